@@ -0,0 +1,239 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/coloradocollective/go-capstone-starter/internal/embeddings"
+	"github.com/sashabaranov/go-openai"
+)
+
+// Analyzer estimates, checks and stores nutrition for recipes within one analysis run.
+type Analyzer struct {
+	db                    *sql.DB
+	tracker               *UsageTracker
+	prices                PriceTable
+	backends              []ModelBackend
+	disagreementThreshold float64
+	verifyDietTags        bool
+	embedder              embeddings.Embedder
+	embeddingPrices       PriceConfig
+	batchSize             int
+}
+
+// NewAnalyzerFromEnv configures an Analyzer for the given run from the environment.
+func NewAnalyzerFromEnv(db *sql.DB, tracker *UsageTracker, prices PriceTable) *Analyzer {
+	embedder := embeddings.NewFromEnv()
+	batchSize := 1
+	if raw := os.Getenv("ANALYZER_BATCH_SIZE"); raw != "" {
+		size, err := strconv.Atoi(raw)
+		if err != nil || size < 1 {
+			log.Fatalf("Invalid value for ANALYZER_BATCH_SIZE: %q", raw)
+		}
+		batchSize = size
+	}
+	backends := LoadBackends()
+	for _, backend := range backends {
+		if _, ok := prices.ByModel[backend.Model]; !ok && backend.Model != prices.Default.Model {
+			log.Printf("Warning: MODEL_PRICES has no entry for %s; costing it at the MODEL_NAME prices", backend.Model)
+		}
+	}
+	return &Analyzer{
+		db:                    db,
+		tracker:               tracker,
+		prices:                prices,
+		backends:              backends,
+		disagreementThreshold: DisagreementThreshold(),
+		verifyDietTags:        os.Getenv("ANALYZER_DIET_MODEL_CHECK") == "true",
+		embedder:              embedder,
+		embeddingPrices:       LoadEmbeddingPriceConfig(embedder.Model()),
+		batchSize:             batchSize,
+	}
+}
+
+// recipeWork collects what is known about one recipe while it is analyzed.
+type recipeWork struct {
+	Recipe         Recipe
+	Ingredients    []Ingredient
+	Estimates      []NutritionEstimate
+	Estimators     []string
+	PromptVersions []string
+}
+
+func (w *recipeWork) add(backend ModelBackend, estimate NutritionEstimate, promptVersion string) {
+	w.Estimates = append(w.Estimates, estimate)
+	w.Estimators = append(w.Estimators, backend.Name())
+	w.PromptVersions = append(w.PromptVersions, promptVersion)
+}
+
+func (w *recipeWork) hasEstimateFrom(backend ModelBackend) bool {
+	for _, name := range w.Estimators {
+		if name == backend.Name() {
+			return true
+		}
+	}
+	return false
+}
+
+// Run analyzes the recipes in batches of batchSize and returns the final run status.
+func (a *Analyzer) Run(recipes []Recipe) string {
+	for start := 0; start < len(recipes); start += a.batchSize {
+		if reason, exhausted := a.tracker.Exhausted(); exhausted {
+			log.Printf("Stopping run: %s", reason)
+			return RunStatusBudgetExhausted
+		}
+
+		end := min(start+a.batchSize, len(recipes))
+		var works []*recipeWork
+		for _, recipe := range recipes[start:end] {
+			if work, ok := a.prepare(recipe); ok {
+				works = append(works, work)
+			}
+		}
+
+		a.estimate(works)
+		for _, work := range works {
+			// finish logs its own failures; the batch run moves on to the next recipe.
+			_ = a.finish(work)
+		}
+	}
+	return RunStatusCompleted
+}
+
+func (a *Analyzer) prepare(recipe Recipe) (*recipeWork, bool) {
+	if recipe.NutritionLocked {
+		log.Printf("Skipping recipe ID %d: nutrition was locked by a reviewer", recipe.ID)
+		return nil, false
+	}
+
+	ingredients, err := GetIngredientsForRecipe(a.db, recipe.ID)
+	if err != nil {
+		log.Printf("Skipping recipe ID %d: %v", recipe.ID, err)
+		return nil, false
+	}
+	return &recipeWork{Recipe: recipe, Ingredients: ingredients}, true
+}
+
+// estimate asks every backend about every recipe. With batching enabled each backend
+// first gets one combined request; recipes the batch answer did not cover fall back
+// to single-recipe calls.
+func (a *Analyzer) estimate(works []*recipeWork) {
+	for _, backend := range a.backends {
+		if a.batchSize > 1 && len(works) > 1 {
+			a.estimateBatch(backend, works)
+		}
+		for _, work := range works {
+			if !work.hasEstimateFrom(backend) {
+				a.estimateSingle(backend, work)
+			}
+		}
+	}
+}
+
+func (a *Analyzer) estimateSingle(backend ModelBackend, work *recipeWork) {
+	if _, exhausted := a.tracker.Exhausted(); exhausted {
+		return
+	}
+	recipeID := work.Recipe.ID
+
+	result := BackendEstimate{Backend: backend}
+	var usage openai.Usage
+	result.Raw, usage, result.Err = backend.Query(NutritionPrompt(work.Ingredients))
+	if result.Err == nil {
+		if err := a.tracker.Record(recipeID, a.pricesFor(backend), usage); err != nil {
+			log.Printf("Failed to record usage for recipe ID %d: %v", recipeID, err)
+		}
+		if err := json.Unmarshal([]byte(result.Raw), &result.Estimate); err != nil {
+			result.Err = fmt.Errorf("invalid JSON response: %w", err)
+		}
+	}
+
+	if result.Err != nil {
+		log.Printf("Backend %s failed for recipe ID %d: %v", backend.Name(), recipeID, result.Err)
+	} else {
+		work.add(backend, result.Estimate, nutritionPromptVersion)
+	}
+	if err := SaveEstimate(a.db, a.tracker.RunID, recipeID, result); err != nil {
+		log.Printf("Failed to save estimate for recipe ID %d: %v", recipeID, err)
+	}
+}
+
+func (a *Analyzer) pricesFor(backend ModelBackend) PriceConfig {
+	return a.prices.For(backend.Model)
+}
+
+// finish combines the collected estimates, flags suspect results and stores nutrition,
+// diet tags and the recipe embedding. It returns an error when no nutrition was stored;
+// diet tag and embedding failures are only logged.
+func (a *Analyzer) finish(work *recipeWork) error {
+	recipe := work.Recipe
+	if len(work.Estimates) == 0 {
+		log.Printf("No usable estimate for recipe ID %d", recipe.ID)
+		return fmt.Errorf("no usable estimate for recipe ID %d", recipe.ID)
+	}
+
+	nutrition := MedianEstimate(work.Estimates)
+	reasons := SuspectReasons(nutrition)
+	if disagreeing := Disagreements(work.Estimates, a.disagreementThreshold); len(disagreeing) > 0 {
+		reasons = append(reasons, fmt.Sprintf("backends disagree on %s", strings.Join(disagreeing, ", ")))
+	}
+	if reason, low := LowConfidence(len(work.Estimates), len(a.backends)); low {
+		reasons = append(reasons, reason)
+	}
+	if len(reasons) > 0 {
+		reason := strings.Join(reasons, "; ")
+		log.Printf("Flagging recipe ID %d for review: %s", recipe.ID, reason)
+		if err := FlagForReview(a.db, a.tracker.RunID, recipe.ID, reason); err != nil {
+			log.Printf("Failed to flag recipe ID %d: %v", recipe.ID, err)
+		}
+	}
+
+	provenance := ModelProvenance(work.Estimators, work.PromptVersions, work.Ingredients)
+	if err := UpsertNutrition(a.db, recipe.ID, nutrition, provenance); err != nil {
+		log.Printf("Failed to save nutrition for recipe ID %d: %v", recipe.ID, err)
+		return err
+	}
+
+	fmt.Printf("Saved nutrition for \"%s\"\n", recipe.Name)
+
+	tags := ClassifyRecipe(work.Ingredients, &nutrition)
+	if a.verifyDietTags {
+		if _, exhausted := a.tracker.Exhausted(); !exhausted {
+			var usage openai.Usage
+			var err error
+			tags, usage, err = VerifyDietTags(tags, work.Ingredients)
+			if usage.TotalTokens > 0 {
+				// The tag check goes to the MODEL_NAME backend.
+				if err := a.tracker.Record(recipe.ID, a.pricesFor(DefaultBackend()), usage); err != nil {
+					log.Printf("Failed to record usage for recipe ID %d: %v", recipe.ID, err)
+				}
+			}
+			if err != nil {
+				log.Printf("Diet tag check failed for recipe ID %d: %v", recipe.ID, err)
+			}
+		}
+	}
+	if err := ReplaceDietTags(a.db, recipe.ID, tags); err != nil {
+		log.Printf("Failed to save diet tags for recipe ID %d: %v", recipe.ID, err)
+	}
+
+	if _, exhausted := a.tracker.Exhausted(); exhausted {
+		return nil
+	}
+	embedding, err := EmbedRecipe(a.db, a.embedder, recipe.ID, EmbeddingInput(recipe.Name, work.Ingredients))
+	if err != nil {
+		log.Printf("Failed to embed recipe ID %d: %v", recipe.ID, err)
+		return nil
+	}
+	if embedding != nil && embedding.Usage.TotalTokens > 0 {
+		if err := a.tracker.Record(recipe.ID, a.embeddingPrices, embedding.Usage); err != nil {
+			log.Printf("Failed to record usage for recipe ID %d: %v", recipe.ID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// batchPromptVersion identifies BatchNutritionPrompt in stored provenance.
+const batchPromptVersion = "nutrition-batch-v1"
+
+func batchKey(recipeID int) string {
+	return fmt.Sprintf("recipe-%d", recipeID)
+}
+
+// BatchNutritionPrompt asks for estimates for several recipes at once, each identified by its key.
+func BatchNutritionPrompt(works []*recipeWork) string {
+	var sb strings.Builder
+	sb.WriteString("Estimate the total nutrition for each of the following recipes. Each recipe is identified by its key.\n\n")
+	for _, work := range works {
+		fmt.Fprintf(&sb, "Recipe %q:\n%s\n", batchKey(work.Recipe.ID), formatIngredientsForPrompt(work.Ingredients))
+	}
+	sb.WriteString(`Return only a JSON object with the key "estimates" holding an array with one object per recipe.
+Each object must have the keys "key", "calories", "protein", "fat", "carbohydrates", where "key" is the recipe key given above.
+Use these units: kcal for calories, grams for protein, fat, and carbohydrates. Use integers only. Do not include units in the keys or values.
+`)
+	return sb.String()
+}
+
+type batchEntry struct {
+	Key           string `json:"key"`
+	Calories      *int   `json:"calories"`
+	Protein       *int   `json:"protein"`
+	Fat           *int   `json:"fat"`
+	Carbohydrates *int   `json:"carbohydrates"`
+}
+
+// ParseBatchResponse returns the complete estimates in a batch answer keyed by recipe key.
+// Entries with unknown keys or missing values are dropped so the caller falls back to
+// single-recipe calls for them.
+func ParseBatchResponse(raw string, keys map[string]bool) (map[string]NutritionEstimate, map[string]string, error) {
+	var resp struct {
+		Estimates []json.RawMessage `json:"estimates"`
+	}
+	if err := json.Unmarshal([]byte(raw), &resp); err != nil {
+		return nil, nil, err
+	}
+
+	estimates := make(map[string]NutritionEstimate)
+	rawEntries := make(map[string]string)
+	for _, rawEntry := range resp.Estimates {
+		var entry batchEntry
+		if err := json.Unmarshal(rawEntry, &entry); err != nil {
+			continue
+		}
+		if !keys[entry.Key] || entry.Calories == nil || entry.Protein == nil || entry.Fat == nil || entry.Carbohydrates == nil {
+			continue
+		}
+		estimates[entry.Key] = NutritionEstimate{
+			Calories:      *entry.Calories,
+			Protein:       *entry.Protein,
+			Fat:           *entry.Fat,
+			Carbohydrates: *entry.Carbohydrates,
+		}
+		rawEntries[entry.Key] = string(rawEntry)
+	}
+	return estimates, rawEntries, nil
+}
+
+// estimateBatch sends one combined request for works to backend and adds every
+// estimate the answer covers. A failed batch is logged and left to the single-recipe fallback.
+func (a *Analyzer) estimateBatch(backend ModelBackend, works []*recipeWork) {
+	if _, exhausted := a.tracker.Exhausted(); exhausted {
+		return
+	}
+
+	keys := make(map[string]bool, len(works))
+	recipeIDs := make([]int, len(works))
+	for i, work := range works {
+		keys[batchKey(work.Recipe.ID)] = true
+		recipeIDs[i] = work.Recipe.ID
+	}
+
+	raw, usage, err := backend.Query(BatchNutritionPrompt(works))
+	if err != nil {
+		log.Printf("Batch request to %s failed for %d recipes: %v", backend.Name(), len(works), err)
+		return
+	}
+	if err := a.tracker.RecordBatch(recipeIDs, a.pricesFor(backend), usage); err != nil {
+		log.Printf("Failed to record batch usage: %v", err)
+	}
+
+	estimates, rawEntries, err := ParseBatchResponse(raw, keys)
+	if err != nil {
+		log.Printf("Invalid batch response from %s: %v", backend.Name(), err)
+		return
+	}
+
+	for _, work := range works {
+		key := batchKey(work.Recipe.ID)
+		estimate, ok := estimates[key]
+		if !ok {
+			log.Printf("Batch response from %s did not cover recipe ID %d; falling back to a single call", backend.Name(), work.Recipe.ID)
+			continue
+		}
+		work.add(backend, estimate, batchPromptVersion)
+		result := BackendEstimate{Backend: backend, Estimate: estimate, Raw: rawEntries[key]}
+		if err := SaveEstimate(a.db, a.tracker.RunID, work.Recipe.ID, result); err != nil {
+			log.Printf("Failed to save estimate for recipe ID %d: %v", work.Recipe.ID, err)
+		}
+	}
+	log.Printf("Batch request to %s covered %d of %d recipes (%d tokens)", backend.Name(), len(estimates), len(works), usage.TotalTokens)
+}
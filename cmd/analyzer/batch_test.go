@@ -0,0 +1,52 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBatchNutritionPrompt_KeysEveryRecipe(t *testing.T) {
+	works := []*recipeWork{
+		{Recipe: Recipe{ID: 7}, Ingredients: []Ingredient{{Name: "oats", Amount: "1", Unit: "cup"}}},
+		{Recipe: Recipe{ID: 9}, Ingredients: []Ingredient{{Name: "eggs", Amount: "2"}}},
+	}
+
+	prompt := BatchNutritionPrompt(works)
+
+	for _, want := range []string{`Recipe "recipe-7":`, `Recipe "recipe-9":`, "- oats: 1 cup", `"estimates"`} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("expected prompt to contain %q:\n%s", want, prompt)
+		}
+	}
+}
+
+func TestParseBatchResponse_PartialAnswer(t *testing.T) {
+	keys := map[string]bool{"recipe-1": true, "recipe-2": true, "recipe-3": true}
+	raw := `{"estimates": [
+		{"key": "recipe-1", "calories": 500, "protein": 30, "fat": 20, "carbohydrates": 40},
+		{"key": "recipe-2", "calories": 300, "protein": 10},
+		{"key": "recipe-99", "calories": 1, "protein": 1, "fat": 1, "carbohydrates": 1},
+		"not an object"
+	]}`
+
+	estimates, rawEntries, err := ParseBatchResponse(raw, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(estimates) != 1 {
+		t.Fatalf("expected only the complete, known entry, got %+v", estimates)
+	}
+	if got := estimates["recipe-1"]; got != (NutritionEstimate{Calories: 500, Protein: 30, Fat: 20, Carbohydrates: 40}) {
+		t.Fatalf("unexpected estimate %+v", got)
+	}
+	if !strings.Contains(rawEntries["recipe-1"], `"recipe-1"`) {
+		t.Fatalf("expected raw entry to be kept, got %q", rawEntries["recipe-1"])
+	}
+}
+
+func TestParseBatchResponse_InvalidJSON(t *testing.T) {
+	if _, _, err := ParseBatchResponse("not json", map[string]bool{}); err == nil {
+		t.Fatal("expected an error for invalid JSON")
+	}
+}
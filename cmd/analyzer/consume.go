@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/coloradocollective/go-capstone-starter/internal/events"
+	"github.com/coloradocollective/go-capstone-starter/internal/messaging"
+)
+
+// Recipe event types published by the collector.
+const (
+	RecipeIngestedEvent = "recipe.ingested"
+	RecipeChangedEvent  = "recipe.changed"
+)
+
+// recipeEventVersion is the version of the recipe event schemas the analyzer understands.
+const recipeEventVersion = 1
+
+// RecipeEventData is the data of a recipe event envelope.
+type RecipeEventData struct {
+	RecipeID int    `json:"recipe_id"`
+	Slug     string `json:"slug,omitempty"`
+}
+
+// RecipeEvent announces that a recipe was stored or its ingredients changed.
+type RecipeEvent struct {
+	Type       string
+	RecipeID   int
+	Slug       string
+	OccurredAt time.Time
+}
+
+// DecodeRecipeEvent validates an event envelope against its schema and extracts the recipe event.
+func DecodeRecipeEvent(raw []byte) (RecipeEvent, error) {
+	env, err := events.Decode(raw)
+	if err != nil {
+		return RecipeEvent{}, err
+	}
+	if env.Type != RecipeIngestedEvent && env.Type != RecipeChangedEvent {
+		return RecipeEvent{}, fmt.Errorf("unexpected event type %q", env.Type)
+	}
+	if env.Version != recipeEventVersion {
+		return RecipeEvent{}, fmt.Errorf("unsupported %s version %d", env.Type, env.Version)
+	}
+	var data RecipeEventData
+	if err := env.DecodeData(&data); err != nil {
+		return RecipeEvent{}, err
+	}
+	return RecipeEvent{Type: env.Type, RecipeID: data.RecipeID, Slug: data.Slug, OccurredAt: env.Timestamp}, nil
+}
+
+// recipeEventHandler adapts handle to a messaging.Handler. Payloads that cannot be
+// decoded are logged and acknowledged, as redelivering them cannot help; errors from
+// handle nack the message so it is redelivered. Before handling, hold may keep the
+// message, unacknowledged and without using up a delivery attempt, until handling
+// can go ahead; an error from hold nacks it. Events are handled one at a time
+// because the usage tracker is not safe for concurrent use.
+func recipeEventHandler(handle func(RecipeEvent) error, hold func(context.Context) error) messaging.Handler {
+	var mu sync.Mutex
+	return func(ctx context.Context, msg messaging.Message) error {
+		event, err := DecodeRecipeEvent(msg.Data)
+		if err != nil {
+			log.Printf("Dropping invalid recipe event %s: %v", msg.ID, err)
+			return nil
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if err := hold(ctx); err != nil {
+			return err
+		}
+		if err := handle(event); err != nil {
+			log.Printf("Failed to handle %s for recipe ID %d (attempt %d): %v", event.Type, event.RecipeID, msg.Attempt, err)
+			return err
+		}
+		return nil
+	}
+}
+
+// budgetPollInterval is how often a held event rechecks the budget.
+var budgetPollInterval = time.Minute
+
+// errRunBudgetSpent is returned by waitForBudget once the run's own cap is reached.
+var errRunBudgetSpent = errors.New("run budget spent")
+
+// waitForBudget holds the caller while the daily budget is exhausted, so that
+// events wait for the next UTC day instead of being retried into the dead
+// letters. Nothing frees a spent run budget, so that returns errRunBudgetSpent.
+func (a *Analyzer) waitForBudget(ctx context.Context) error {
+	held := false
+	for {
+		reason, exhausted := a.tracker.Exhausted()
+		if !exhausted {
+			if held {
+				log.Printf("Budget available again; resuming recipe events")
+			}
+			return nil
+		}
+		if a.tracker.RunExhausted() {
+			return fmt.Errorf("%w: %s", errRunBudgetSpent, reason)
+		}
+		if !held {
+			log.Printf("Holding recipe events: %s", reason)
+			held = true
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(budgetPollInterval):
+		}
+	}
+}
+
+// HandleRecipeEvent analyzes the recipe an event refers to. Deliveries are
+// idempotent: recipes whose stored nutrition was estimated from the same
+// ingredients with a current prompt are skipped, as are locked recipes. The
+// consumer checks the budget before calling it.
+func (a *Analyzer) HandleRecipeEvent(event RecipeEvent) error {
+	recipe, fingerprint, promptVersion, err := GetRecipeForAnalysis(a.db, event.RecipeID)
+	if errors.Is(err, sql.ErrNoRows) {
+		log.Printf("Ignoring %s for unknown recipe ID %d", event.Type, event.RecipeID)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if recipe.NutritionLocked {
+		log.Printf("Skipping recipe ID %d: nutrition was locked by a reviewer", recipe.ID)
+		return nil
+	}
+
+	ingredients, err := GetIngredientsForRecipe(a.db, recipe.ID)
+	if err != nil {
+		return err
+	}
+	if fingerprint == ingredientFingerprint(ingredients) && isCurrentPromptVersion(promptVersion) {
+		log.Printf("Skipping recipe ID %d: nutrition is up to date", recipe.ID)
+		return nil
+	}
+
+	work := &recipeWork{Recipe: recipe, Ingredients: ingredients}
+	a.estimate([]*recipeWork{work})
+	return a.finish(work)
+}
+
+// GetRecipeForAnalysis loads a recipe with the fingerprint and prompt version of its stored nutrition.
+func GetRecipeForAnalysis(db *sql.DB, recipeID int) (Recipe, string, string, error) {
+	var r Recipe
+	var fingerprint, promptVersion string
+	err := db.QueryRow(`
+	SELECT r.id, r.name, COALESCE(n.locked, FALSE), COALESCE(n.input_fingerprint, ''), COALESCE(n.prompt_version, '')
+	FROM recipes r
+	LEFT JOIN recipe_nutrition n ON n.recipe_id = r.id
+	WHERE r.id = $1;
+	`, recipeID).Scan(&r.ID, &r.Name, &r.NutritionLocked, &fingerprint, &promptVersion)
+	return r, fingerprint, promptVersion, err
+}
+
+func envOrDefault(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// Consume handles recipe events until ctx is cancelled and returns the final run
+// status. With the in-process broker there is no collector to publish events, so
+// an ingested event is queued for every recipe; up-to-date recipes are skipped.
+func Consume(ctx context.Context, analyzer *Analyzer, recipes []Recipe) (string, error) {
+	topic := envOrDefault("RECIPE_EVENTS_TOPIC", "recipe-events")
+	subscription := envOrDefault("RECIPE_EVENTS_SUBSCRIPTION", "recipe-analyzer")
+
+	broker, err := messaging.NewFromEnv(ctx)
+	if err != nil {
+		return RunStatusCompleted, err
+	}
+	defer broker.Close()
+
+	if _, local := broker.(*messaging.MemoryBroker); local {
+		for _, recipe := range recipes {
+			env, err := events.New(RecipeIngestedEvent, recipeEventVersion, 0, RecipeEventData{RecipeID: recipe.ID})
+			if err != nil {
+				return RunStatusCompleted, err
+			}
+			if err := events.Publish(ctx, broker, topic, env); err != nil {
+				return RunStatusCompleted, err
+			}
+		}
+	}
+
+	// A spent run budget stops the consumer; the held event is nacked and waits
+	// for the next run.
+	consumeCtx, stopConsuming := context.WithCancel(ctx)
+	defer stopConsuming()
+	hold := func(ctx context.Context) error {
+		err := analyzer.waitForBudget(ctx)
+		if errors.Is(err, errRunBudgetSpent) {
+			log.Printf("Stopping consumer: %v", err)
+			stopConsuming()
+		}
+		return err
+	}
+
+	log.Printf("Consuming %s from %s (%T)", subscription, topic, broker)
+	err = broker.Subscribe(consumeCtx, topic, subscription, recipeEventHandler(analyzer.HandleRecipeEvent, hold))
+	if _, exhausted := analyzer.tracker.Exhausted(); exhausted {
+		return RunStatusBudgetExhausted, err
+	}
+	return RunStatusCompleted, err
+}
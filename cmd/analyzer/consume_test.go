@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coloradocollective/go-capstone-starter/internal/events"
+	"github.com/coloradocollective/go-capstone-starter/internal/messaging"
+)
+
+// collectorEvent is a recipe.ingested envelope exactly as cmd/collector publishes it.
+const collectorEvent = `{"type": "recipe.ingested", "version": 1, "id": "9f2c", "timestamp": "2024-05-01T08:30:00Z",
+	"data": {"recipe_id": 12, "slug": "oat-bars"}}`
+
+func recipeEnvelope(t *testing.T, eventType string, recipeID int) []byte {
+	t.Helper()
+	env, err := events.New(eventType, recipeEventVersion, 0, RecipeEventData{RecipeID: recipeID})
+	if err != nil {
+		t.Fatalf("failed to build event: %v", err)
+	}
+	raw, err := events.Encode(env)
+	if err != nil {
+		t.Fatalf("failed to encode event: %v", err)
+	}
+	return raw
+}
+
+func TestDecodeRecipeEvent(t *testing.T) {
+	event, err := DecodeRecipeEvent([]byte(collectorEvent))
+	if err != nil || event.Type != RecipeIngestedEvent || event.RecipeID != 12 || event.Slug != "oat-bars" {
+		t.Fatalf("unexpected result: %+v, %v", event, err)
+	}
+
+	invalid := []string{
+		`not json`,
+		`{"type": "recipe.ingested", "recipe_id": 1}`,
+		`{"type": "recipe.deleted", "version": 1, "id": "1", "timestamp": "2024-05-01T08:30:00Z", "data": {"recipe_id": 1}}`,
+		`{"type": "recipe.changed", "version": 1, "id": "1", "timestamp": "2024-05-01T08:30:00Z", "data": {}}`,
+		`{"type": "recipe.changed", "version": 2, "id": "1", "timestamp": "2024-05-01T08:30:00Z", "data": {"recipe_id": 1}}`,
+		`{"type": "food_entry.deleted", "version": 1, "id": "1", "timestamp": "2024-05-01T08:30:00Z", "data": {"entry_id": 1}}`,
+	}
+	for _, payload := range invalid {
+		if _, err := DecodeRecipeEvent([]byte(payload)); err == nil {
+			t.Errorf("expected %s to be rejected", payload)
+		}
+	}
+}
+
+func TestRecipeEventHandler_RedeliversFailuresAndDropsInvalidEvents(t *testing.T) {
+	broker := messaging.NewMemoryBroker()
+	broker.RetryDelay = time.Millisecond
+	broker.MaxAttempts = 3
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	attempts := map[int]int{}
+	handler := recipeEventHandler(func(event RecipeEvent) error {
+		attempts[event.RecipeID]++
+		if event.RecipeID == 1 && attempts[1] == 1 {
+			return errors.New("backend unavailable")
+		}
+		return nil
+	}, func(context.Context) error { return nil })
+
+	go broker.Subscribe(ctx, "recipe-events", "recipe-analyzer", handler)
+	time.Sleep(10 * time.Millisecond)
+
+	_ = broker.Publish(ctx, "recipe-events", messaging.Message{Data: recipeEnvelope(t, RecipeIngestedEvent, 1)})
+	_ = broker.Publish(ctx, "recipe-events", messaging.Message{Data: recipeEnvelope(t, RecipeChangedEvent, 2)})
+	_ = broker.Publish(ctx, "recipe-events", messaging.Message{Data: []byte(`garbage`)})
+	broker.Drain()
+	cancel()
+
+	if attempts[1] != 2 || attempts[2] != 1 {
+		t.Fatalf("expected recipe 1 to be retried once and recipe 2 handled once, got %v", attempts)
+	}
+	if dead := broker.DeadLetters(); len(dead) != 0 {
+		t.Fatalf("expected no dead letters, got %d", len(dead))
+	}
+}
+
+func TestRecipeEventHandler_HoldsWithoutUsingAttempts(t *testing.T) {
+	broker := messaging.NewMemoryBroker()
+	broker.RetryDelay = time.Millisecond
+	broker.MaxAttempts = 1
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	held := make(chan struct{}, 1)
+	release := make(chan struct{})
+	var mu sync.Mutex
+	var handled []int
+	handler := recipeEventHandler(func(event RecipeEvent) error {
+		mu.Lock()
+		defer mu.Unlock()
+		handled = append(handled, event.RecipeID)
+		return nil
+	}, func(ctx context.Context) error {
+		select {
+		case held <- struct{}{}:
+		default:
+		}
+		select {
+		case <-release:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+
+	go broker.Subscribe(ctx, "recipe-events", "recipe-analyzer", handler)
+	_ = broker.Publish(ctx, "recipe-events", messaging.Message{Data: recipeEnvelope(t, RecipeChangedEvent, 7)})
+	select {
+	case <-held:
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the event to be held")
+	}
+	mu.Lock()
+	early := len(handled)
+	mu.Unlock()
+	if early != 0 {
+		t.Fatalf("expected the event to be held, got %d handled", early)
+	}
+	close(release)
+	broker.Drain()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(handled) != 1 || handled[0] != 7 || len(broker.DeadLetters()) != 0 {
+		t.Fatalf("expected the held event to be handled once after release, got %v and %d dead letters", handled, len(broker.DeadLetters()))
+	}
+}
+
+func TestWaitForBudget(t *testing.T) {
+	budgetPollInterval = time.Millisecond
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+
+	daily := &Analyzer{tracker: &UsageTracker{budget: Budget{PerDay: 1}, day: today, spentToday: 2}}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := daily.waitForBudget(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected a spent daily budget to hold until the context ends, got %v", err)
+	}
+
+	run := &Analyzer{tracker: &UsageTracker{budget: Budget{PerRun: 1, PerDay: 1}, day: today, Cost: 1, spentToday: 1}}
+	if err := run.waitForBudget(context.Background()); !errors.Is(err, errRunBudgetSpent) {
+		t.Fatalf("expected a spent run budget to stop the consumer, got %v", err)
+	}
+
+	free := &Analyzer{tracker: &UsageTracker{budget: Budget{PerDay: 1}, day: today}}
+	if err := free.waitForBudget(context.Background()); err != nil {
+		t.Fatalf("expected no wait with budget left, got %v", err)
+	}
+}
+
+func TestIsCurrentPromptVersion(t *testing.T) {
+	cases := map[string]bool{
+		nutritionPromptVersion:                            true,
+		batchPromptVersion + "," + nutritionPromptVersion: true,
+		"":              false,
+		"nutrition-v0":  false,
+		"nutrition-v1,": false,
+	}
+	for stored, want := range cases {
+		if got := isCurrentPromptVersion(stored); got != want {
+			t.Errorf("isCurrentPromptVersion(%q) = %v, want %v", stored, got, want)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// DietTag is a dietary classification assigned to a recipe together with the rule that fired.
+type DietTag struct {
+	Tag           string
+	Rule          string
+	Detail        string
+	ModelVerified *bool
+}
+
+// ingredientRule tags a recipe unless one of its ingredients mentions an excluded keyword.
+// Phrases in allow are removed from the ingredient text before matching, so that
+// e.g. "almond milk" does not count as dairy.
+type ingredientRule struct {
+	tag      string
+	name     string
+	excludes []string
+	allow    []string
+}
+
+var (
+	meatAndFish = []string{
+		"beef", "pork", "chicken", "turkey", "bacon", "ham", "sausage", "pepperoni", "salami",
+		"prosciutto", "chorizo", "lamb", "veal", "duck", "steak", "meatball", "meat", "gelatin",
+		"fish", "salmon", "tuna", "cod", "tilapia", "halibut", "anchovy", "anchovies", "sardine",
+		"shrimp", "prawn", "crab", "lobster", "scallop", "clam", "mussel", "oyster", "worcestershire",
+	}
+	dairy = []string{
+		"milk", "cheese", "butter", "cream", "yogurt", "yoghurt", "whey", "casein", "ghee",
+		"buttermilk", "parmesan", "mozzarella", "cheddar", "ricotta", "feta", "half-and-half",
+		"half and half", "alfredo",
+	}
+	dairyAllow = []string{
+		"almond milk", "coconut milk", "soy milk", "oat milk", "rice milk", "cashew milk",
+		"peanut butter", "almond butter", "cashew butter", "apple butter", "cocoa butter",
+		"coconut cream", "cream of tartar", "dairy-free", "dairy free", "vegan",
+	}
+	eggsAndHoney = []string{"egg", "mayonnaise", "mayo", "honey"}
+)
+
+var ingredientRules = []ingredientRule{
+	{
+		tag:      "vegetarian",
+		name:     "no_meat_or_fish_keywords",
+		excludes: meatAndFish,
+		allow:    []string{"vegetable broth", "vegetarian", "meatless", "plant-based"},
+	},
+	{
+		tag:      "vegan",
+		name:     "no_animal_product_keywords",
+		excludes: append(append(append([]string{}, meatAndFish...), dairy...), eggsAndHoney...),
+		allow:    append([]string{"vegetable broth", "egg-free", "plant-based"}, dairyAllow...),
+	},
+	{
+		tag:  "gluten-free",
+		name: "no_gluten_keywords",
+		excludes: []string{
+			"flour", "wheat", "bread", "breadcrumbs", "panko", "pasta", "spaghetti", "macaroni",
+			"noodles", "lasagna", "orzo", "couscous", "barley", "rye", "biscuit", "cracker",
+			"crouton", "soy sauce", "seitan", "dough", "crust", "cake mix", "graham", "bun",
+		},
+		allow: []string{
+			"gluten-free", "gluten free", "almond flour", "coconut flour", "rice flour",
+			"rice noodles", "corn tortilla", "tamari",
+		},
+	},
+	{
+		tag:      "dairy-free",
+		name:     "no_dairy_keywords",
+		excludes: dairy,
+		allow:    dairyAllow,
+	},
+	{
+		tag:  "nut-free",
+		name: "no_nut_keywords",
+		excludes: []string{
+			"nut", "almond", "walnut", "pecan", "cashew", "pistachio", "hazelnut", "macadamia",
+			"peanut", "pine nut", "praline", "nutella", "marzipan",
+		},
+		allow: []string{"nut-free", "nut free"},
+	},
+}
+
+const (
+	// lowCarbMaxEnergyShare is the share of calories from carbohydrates below which a recipe is low-carb.
+	lowCarbMaxEnergyShare = 0.26
+	// highProteinMinEnergyShare is the share of calories from protein at or above which a recipe is high-protein.
+	highProteinMinEnergyShare = 0.25
+)
+
+// ClassifyRecipe applies the deterministic rule set to a recipe's ingredients and,
+// when available, its nutrition estimate.
+func ClassifyRecipe(ingredients []Ingredient, nutrition *NutritionEstimate) []DietTag {
+	var tags []DietTag
+	if len(ingredients) > 0 {
+		for _, rule := range ingredientRules {
+			if hasExcludedIngredient(ingredients, rule) {
+				continue
+			}
+			tags = append(tags, DietTag{
+				Tag:    rule.tag,
+				Rule:   rule.name,
+				Detail: fmt.Sprintf("none of %d ingredients matched an excluded keyword", len(ingredients)),
+			})
+		}
+	}
+
+	if nutrition != nil && nutrition.Calories > 0 {
+		carbShare := float64(nutrition.Carbohydrates*4) / float64(nutrition.Calories)
+		if carbShare < lowCarbMaxEnergyShare {
+			tags = append(tags, DietTag{
+				Tag:    "low-carb",
+				Rule:   "carb_energy_share_below_26pct",
+				Detail: fmt.Sprintf("%.0f%% of calories from carbohydrates", carbShare*100),
+			})
+		}
+		proteinShare := float64(nutrition.Protein*4) / float64(nutrition.Calories)
+		if proteinShare >= highProteinMinEnergyShare {
+			tags = append(tags, DietTag{
+				Tag:    "high-protein",
+				Rule:   "protein_energy_share_at_least_25pct",
+				Detail: fmt.Sprintf("%.0f%% of calories from protein", proteinShare*100),
+			})
+		}
+	}
+	return tags
+}
+
+func hasExcludedIngredient(ingredients []Ingredient, rule ingredientRule) bool {
+	for _, ing := range ingredients {
+		text := normalizeIngredientText(ing.Name + " " + ing.Notes)
+		for _, phrase := range rule.allow {
+			text = strings.ReplaceAll(text, normalizeIngredientText(phrase), " ")
+		}
+		for _, keyword := range rule.excludes {
+			if containsKeyword(text, normalizeIngredientText(keyword)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+var nonWord = regexp.MustCompile(`[^a-z0-9]+`)
+
+// normalizeIngredientText lowercases text and collapses punctuation to single spaces,
+// padding both ends so that whole words can be matched with " word ".
+func normalizeIngredientText(text string) string {
+	words := strings.Fields(nonWord.ReplaceAllString(strings.ToLower(text), " "))
+	return " " + strings.Join(words, " ") + " "
+}
+
+// containsKeyword matches a normalized keyword as whole words, allowing simple plurals.
+func containsKeyword(text, keyword string) bool {
+	keyword = strings.TrimSpace(keyword)
+	for _, form := range []string{keyword, keyword + "s", keyword + "es"} {
+		if strings.Contains(text, " "+form+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+// VerifyDietTags asks the model which of the rule-based tags it agrees with and
+// marks each tag accordingly. Tags are kept either way; the rules stay the source of truth.
+func VerifyDietTags(tags []DietTag, ingredients []Ingredient) ([]DietTag, openai.Usage, error) {
+	if len(tags) == 0 {
+		return tags, openai.Usage{}, nil
+	}
+
+	names := make([]string, len(tags))
+	for i, tag := range tags {
+		names[i] = tag.Tag
+	}
+	prompt := fmt.Sprintf(`Given the following ingredients:
+
+%s
+Which of these dietary tags correctly describe the dish: %s?
+
+Return only a JSON object with the key "tags" holding the list of tags that apply.
+`, formatIngredientsForPrompt(ingredients), strings.Join(names, ", "))
+
+	resp, usage, err := QueryModel(prompt)
+	if err != nil {
+		return tags, usage, err
+	}
+
+	var verdict struct {
+		Tags []string `json:"tags"`
+	}
+	if err := json.Unmarshal([]byte(resp), &verdict); err != nil {
+		return tags, usage, err
+	}
+
+	confirmed := make(map[string]bool, len(verdict.Tags))
+	for _, tag := range verdict.Tags {
+		confirmed[strings.ToLower(strings.TrimSpace(tag))] = true
+	}
+	for i := range tags {
+		agrees := confirmed[tags[i].Tag]
+		tags[i].ModelVerified = &agrees
+	}
+	return tags, usage, nil
+}
+
+// ReplaceDietTags stores the tags for a recipe, replacing any earlier classification.
+func ReplaceDietTags(db *sql.DB, recipeID int, tags []DietTag) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM recipe_diet_tags WHERE recipe_id = $1`, recipeID); err != nil {
+		return err
+	}
+	for _, tag := range tags {
+		_, err := tx.Exec(`
+		INSERT INTO recipe_diet_tags (recipe_id, tag, rule, detail, model_verified)
+		VALUES ($1, $2, $3, $4, $5);
+		`, recipeID, tag.Tag, tag.Rule, tag.Detail, tag.ModelVerified)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"testing"
+)
+
+func tagSet(tags []DietTag) map[string]bool {
+	set := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		set[tag.Tag] = true
+	}
+	return set
+}
+
+func TestClassifyRecipe_IngredientRules(t *testing.T) {
+	tests := []struct {
+		name        string
+		ingredients []Ingredient
+		want        []string
+		notWant     []string
+	}{
+		{
+			name: "chicken casserole",
+			ingredients: []Ingredient{
+				{Name: "can Campbell’s 98% Fat Free Cream of Chicken Soup"},
+				{Name: "refrigerated biscuits"},
+			},
+			want:    []string{"nut-free"},
+			notWant: []string{"vegetarian", "vegan", "gluten-free", "dairy-free"},
+		},
+		{
+			name: "vegan curry with coconut milk",
+			ingredients: []Ingredient{
+				{Name: "chickpeas"},
+				{Name: "light coconut milk"},
+				{Name: "butternut squash", Notes: "cubed"},
+				{Name: "nutmeg"},
+			},
+			want: []string{"vegetarian", "vegan", "gluten-free", "dairy-free", "nut-free"},
+		},
+		{
+			name: "eggplant parmesan",
+			ingredients: []Ingredient{
+				{Name: "eggplant"},
+				{Name: "grated Parmesan cheese"},
+				{Name: "panko breadcrumbs"},
+			},
+			want:    []string{"vegetarian", "nut-free"},
+			notWant: []string{"vegan", "dairy-free", "gluten-free"},
+		},
+		{
+			name:        "peanut butter",
+			ingredients: []Ingredient{{Name: "creamy peanut butter"}, {Name: "honey"}},
+			want:        []string{"vegetarian", "dairy-free"},
+			notWant:     []string{"vegan", "nut-free"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tagSet(ClassifyRecipe(tt.ingredients, nil))
+			for _, tag := range tt.want {
+				if !got[tag] {
+					t.Errorf("expected tag %q, got %v", tag, got)
+				}
+			}
+			for _, tag := range tt.notWant {
+				if got[tag] {
+					t.Errorf("did not expect tag %q", tag)
+				}
+			}
+		})
+	}
+}
+
+func TestClassifyRecipe_NutritionRules(t *testing.T) {
+	got := tagSet(ClassifyRecipe(nil, &NutritionEstimate{Calories: 400, Protein: 40, Fat: 20, Carbohydrates: 15}))
+	if !got["low-carb"] || !got["high-protein"] {
+		t.Fatalf("expected low-carb and high-protein, got %v", got)
+	}
+
+	got = tagSet(ClassifyRecipe(nil, &NutritionEstimate{Calories: 400, Protein: 10, Fat: 10, Carbohydrates: 65}))
+	if got["low-carb"] || got["high-protein"] {
+		t.Fatalf("expected no macro tags, got %v", got)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	"github.com/coloradocollective/go-capstone-starter/internal/embeddings"
+	"github.com/lib/pq"
+)
+
+// EmbeddingInput builds the text that represents a recipe for similarity search.
+func EmbeddingInput(name string, ingredients []Ingredient) string {
+	var sb strings.Builder
+	sb.WriteString(name)
+	sb.WriteString("\n")
+	for _, ing := range ingredients {
+		sb.WriteString(ing.Name)
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+func hashInput(input string) string {
+	sum := sha256.Sum256([]byte(input))
+	return hex.EncodeToString(sum[:])
+}
+
+// EmbedRecipe computes and stores the embedding for a recipe. It returns nil without
+// calling the model when the stored vector was built from the same input by the same model.
+func EmbedRecipe(db *sql.DB, embedder embeddings.Embedder, recipeID int, input string) (*embeddings.Result, error) {
+	inputHash := hashInput(input)
+
+	var storedHash, storedModel string
+	err := db.QueryRow(`SELECT input_hash, model FROM recipe_embeddings WHERE recipe_id = $1`, recipeID).Scan(&storedHash, &storedModel)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if storedHash == inputHash && storedModel == embedder.Model() {
+		return nil, nil
+	}
+
+	result, err := embedder.Embed(context.Background(), input)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(`
+	INSERT INTO recipe_embeddings (recipe_id, model, dimensions, embedding, input_hash, updated_at)
+	VALUES ($1, $2, $3, $4, $5, NOW())
+	ON CONFLICT (recipe_id) DO UPDATE SET
+		model = EXCLUDED.model,
+		dimensions = EXCLUDED.dimensions,
+		embedding = EXCLUDED.embedding,
+		input_hash = EXCLUDED.input_hash,
+		updated_at = NOW();
+	`, recipeID, result.Model, len(result.Vector), pq.Float64Array(result.Vector), inputHash)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
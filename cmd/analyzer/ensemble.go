@@ -0,0 +1,241 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// ModelBackend is one OpenAI-compatible endpoint and model used for estimation.
+type ModelBackend struct {
+	Model   string
+	BaseURL string
+	APIKey  string
+}
+
+// Name identifies the backend in logs and in stored estimates.
+func (b ModelBackend) Name() string {
+	if b.BaseURL == "" {
+		return b.Model
+	}
+	return b.Model + "@" + b.BaseURL
+}
+
+// DefaultBackend is the single backend configured by MODEL_NAME, MODEL_BASE_URL and OPENAI_API_KEY.
+func DefaultBackend() ModelBackend {
+	return ModelBackend{
+		Model:   os.Getenv("MODEL_NAME"),
+		BaseURL: os.Getenv("MODEL_BASE_URL"),
+		APIKey:  os.Getenv("OPENAI_API_KEY"),
+	}
+}
+
+// LoadBackends reads the ensemble from MODEL_ENSEMBLE, a comma-separated list of
+// model or model@base_url entries sharing OPENAI_API_KEY. Entries without a base URL
+// use MODEL_BASE_URL. When MODEL_ENSEMBLE is unset only the default backend is used.
+func LoadBackends() []ModelBackend {
+	return parseBackends(os.Getenv("MODEL_ENSEMBLE"), DefaultBackend())
+}
+
+func parseBackends(spec string, fallback ModelBackend) []ModelBackend {
+	var backends []ModelBackend
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		backend := ModelBackend{Model: entry, BaseURL: fallback.BaseURL, APIKey: fallback.APIKey}
+		if model, baseURL, found := strings.Cut(entry, "@"); found {
+			backend.Model = model
+			backend.BaseURL = baseURL
+		}
+		backends = append(backends, backend)
+	}
+	if len(backends) == 0 {
+		return []ModelBackend{fallback}
+	}
+	return backends
+}
+
+// Query sends a single prompt to the backend and returns the (pretty-printed) JSON reply.
+func (b ModelBackend) Query(prompt string) (string, openai.Usage, error) {
+	cfg := openai.DefaultConfig(b.APIKey)
+	cfg.BaseURL = b.BaseURL
+
+	client := openai.NewClientWithConfig(cfg)
+	resp, err := client.CreateChatCompletion(
+		context.Background(),
+		openai.ChatCompletionRequest{
+			Model: b.Model,
+			Messages: []openai.ChatCompletionMessage{
+				{Role: openai.ChatMessageRoleSystem, Content: "You are a nutritionist."},
+				{Role: openai.ChatMessageRoleUser, Content: prompt},
+			},
+			ResponseFormat: &openai.ChatCompletionResponseFormat{Type: openai.ChatCompletionResponseFormatTypeJSONObject},
+		},
+	)
+	if err != nil {
+		return "", openai.Usage{}, err
+	}
+
+	text := resp.Choices[0].Message.Content
+	var pretty bytes.Buffer
+	if err := json.Indent(&pretty, []byte(text), "", "  "); err != nil {
+		// If not valid JSON, return raw text
+		return text, resp.Usage, nil
+	}
+	return pretty.String(), resp.Usage, nil
+}
+
+// BackendEstimate is the outcome of asking one backend about one recipe.
+type BackendEstimate struct {
+	Backend  ModelBackend
+	Estimate NutritionEstimate
+	Raw      string
+	Err      error
+}
+
+// MedianEstimate combines estimates nutrient by nutrient using the median.
+func MedianEstimate(estimates []NutritionEstimate) NutritionEstimate {
+	pick := func(get func(NutritionEstimate) int) int {
+		values := make([]int, len(estimates))
+		for i, e := range estimates {
+			values[i] = get(e)
+		}
+		return median(values)
+	}
+	return NutritionEstimate{
+		Calories:      pick(func(e NutritionEstimate) int { return e.Calories }),
+		Protein:       pick(func(e NutritionEstimate) int { return e.Protein }),
+		Fat:           pick(func(e NutritionEstimate) int { return e.Fat }),
+		Carbohydrates: pick(func(e NutritionEstimate) int { return e.Carbohydrates }),
+	}
+}
+
+func median(values []int) int {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]int(nil), values...)
+	sort.Ints(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 1 {
+		return sorted[mid]
+	}
+	return (sorted[mid-1] + sorted[mid]) / 2
+}
+
+// minDisagreementSpread ignores spreads too small to matter, e.g. 2g vs 4g of fat.
+const minDisagreementSpread = 10
+
+// Disagreements lists the nutrients whose spread (max - min) exceeds threshold
+// times the median across the given estimates.
+func Disagreements(estimates []NutritionEstimate, threshold float64) []string {
+	if len(estimates) < 2 {
+		return nil
+	}
+	nutrients := []struct {
+		name string
+		get  func(NutritionEstimate) int
+	}{
+		{"calories", func(e NutritionEstimate) int { return e.Calories }},
+		{"protein", func(e NutritionEstimate) int { return e.Protein }},
+		{"fat", func(e NutritionEstimate) int { return e.Fat }},
+		{"carbohydrates", func(e NutritionEstimate) int { return e.Carbohydrates }},
+	}
+
+	var disagreeing []string
+	for _, n := range nutrients {
+		values := make([]int, len(estimates))
+		for i, e := range estimates {
+			values[i] = n.get(e)
+		}
+		sort.Ints(values)
+		spread := values[len(values)-1] - values[0]
+		if spread > minDisagreementSpread && float64(spread) > threshold*float64(median(values)) {
+			disagreeing = append(disagreeing, n.name)
+		}
+	}
+	return disagreeing
+}
+
+// LowConfidence reports an ensemble result that could not be cross-checked because
+// only one of several backends produced an estimate.
+func LowConfidence(estimates, backends int) (string, bool) {
+	if backends < 2 || estimates != 1 {
+		return "", false
+	}
+	return fmt.Sprintf("low confidence: only 1 of %d backends answered", backends), true
+}
+
+// DisagreementThreshold reads ENSEMBLE_DISAGREEMENT_THRESHOLD, defaulting to 0.25 (25% of the median).
+func DisagreementThreshold() float64 {
+	if threshold := envFloat("ENSEMBLE_DISAGREEMENT_THRESHOLD"); threshold > 0 {
+		return threshold
+	}
+	return 0.25
+}
+
+// SaveEstimate keeps an individual backend answer for auditing.
+func SaveEstimate(db *sql.DB, runID, recipeID int, result BackendEstimate) error {
+	// Failed calls are kept too, with the error and no values.
+	values := []any{nil, nil, nil, nil}
+	var errText sql.NullString
+	if result.Err != nil {
+		errText = sql.NullString{String: result.Err.Error(), Valid: true}
+	} else {
+		e := result.Estimate
+		values = []any{e.Calories, e.Protein, e.Fat, e.Carbohydrates}
+	}
+	_, err := db.Exec(`
+	INSERT INTO recipe_nutrition_estimates (run_id, recipe_id, backend, model, calories, protein, fat, carbohydrates, raw_response, error)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10);
+	`, runID, recipeID, result.Backend.Name(), result.Backend.Model,
+		values[0], values[1], values[2], values[3], result.Raw, errText)
+	return err
+}
+
+const (
+	// maxPlausibleCalories is far above any single recipe in the catalog.
+	maxPlausibleCalories = 10000
+	// maxEnergyMismatch is the tolerated relative gap between stated calories and
+	// the calories implied by the macros (4/4/9 kcal per gram).
+	maxEnergyMismatch = 0.25
+)
+
+// SuspectReasons lists sanity checks a single estimate fails.
+func SuspectReasons(n NutritionEstimate) []string {
+	var reasons []string
+	if n.Calories <= 0 || n.Protein < 0 || n.Fat < 0 || n.Carbohydrates < 0 {
+		reasons = append(reasons, "missing or negative values")
+		return reasons
+	}
+	if n.Calories > maxPlausibleCalories {
+		reasons = append(reasons, fmt.Sprintf("implausible calories (%d kcal)", n.Calories))
+	}
+	implied := 4*n.Protein + 4*n.Carbohydrates + 9*n.Fat
+	gap := float64(implied-n.Calories) / float64(n.Calories)
+	if gap > maxEnergyMismatch || gap < -maxEnergyMismatch {
+		reasons = append(reasons, fmt.Sprintf("macros imply %d kcal but estimate says %d kcal", implied, n.Calories))
+	}
+	return reasons
+}
+
+// FlagForReview marks a recipe's nutrition as suspect so it shows up in the review
+// queue. A recipe that already has an open review keeps it rather than queueing
+// another one on every run.
+func FlagForReview(db *sql.DB, runID, recipeID int, reason string) error {
+	_, err := db.Exec(`
+	INSERT INTO recipe_nutrition_flags (recipe_id, run_id, reason)
+	VALUES ($1, $2, $3)
+	ON CONFLICT (recipe_id) WHERE status = 'open' DO NOTHING;
+	`, recipeID, runID, reason)
+	return err
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMedianEstimate(t *testing.T) {
+	odd := MedianEstimate([]NutritionEstimate{
+		{Calories: 500, Protein: 30, Fat: 20, Carbohydrates: 40},
+		{Calories: 450, Protein: 35, Fat: 25, Carbohydrates: 50},
+		{Calories: 900, Protein: 10, Fat: 22, Carbohydrates: 45},
+	})
+	if want := (NutritionEstimate{Calories: 500, Protein: 30, Fat: 22, Carbohydrates: 45}); odd != want {
+		t.Fatalf("expected %+v, got %+v", want, odd)
+	}
+
+	even := MedianEstimate([]NutritionEstimate{
+		{Calories: 400, Protein: 20, Fat: 10, Carbohydrates: 30},
+		{Calories: 500, Protein: 30, Fat: 20, Carbohydrates: 40},
+	})
+	if want := (NutritionEstimate{Calories: 450, Protein: 25, Fat: 15, Carbohydrates: 35}); even != want {
+		t.Fatalf("expected %+v, got %+v", want, even)
+	}
+}
+
+func TestDisagreements(t *testing.T) {
+	estimates := []NutritionEstimate{
+		{Calories: 500, Protein: 30, Fat: 4, Carbohydrates: 40},
+		{Calories: 520, Protein: 32, Fat: 8, Carbohydrates: 90},
+		{Calories: 510, Protein: 31, Fat: 6, Carbohydrates: 45},
+	}
+
+	got := Disagreements(estimates, 0.25)
+
+	if want := []string{"carbohydrates"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	if got := Disagreements(estimates[:1], 0.25); got != nil {
+		t.Fatalf("expected no disagreement for a single estimate, got %v", got)
+	}
+}
+
+func TestLowConfidence(t *testing.T) {
+	if _, low := LowConfidence(1, 3); !low {
+		t.Fatal("expected a single answer from an ensemble to be low confidence")
+	}
+	if _, low := LowConfidence(2, 3); low {
+		t.Fatal("expected two answers to be cross-checked")
+	}
+	if _, low := LowConfidence(1, 1); low {
+		t.Fatal("expected a single-backend setup not to be flagged")
+	}
+}
+
+func TestParseBackends(t *testing.T) {
+	fallback := ModelBackend{Model: "gpt-4o-mini", BaseURL: "https://api.openai.com/v1", APIKey: "key"}
+
+	got := parseBackends("gpt-4o-mini, llama3@http://localhost:8000/v1", fallback)
+
+	want := []ModelBackend{
+		{Model: "gpt-4o-mini", BaseURL: "https://api.openai.com/v1", APIKey: "key"},
+		{Model: "llama3", BaseURL: "http://localhost:8000/v1", APIKey: "key"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+	if got := parseBackends("", fallback); !reflect.DeepEqual(got, []ModelBackend{fallback}) {
+		t.Fatalf("expected fallback backend, got %+v", got)
+	}
+}
+
+func TestSuspectReasons(t *testing.T) {
+	if got := SuspectReasons(NutritionEstimate{Calories: 500, Protein: 30, Fat: 20, Carbohydrates: 50}); got != nil {
+		t.Fatalf("expected a consistent estimate to pass, got %v", got)
+	}
+	if got := SuspectReasons(NutritionEstimate{Calories: 200, Protein: 30, Fat: 20, Carbohydrates: 50}); len(got) != 1 {
+		t.Fatalf("expected macro/calorie mismatch to be flagged, got %v", got)
+	}
+	if got := SuspectReasons(NutritionEstimate{}); len(got) != 1 {
+		t.Fatalf("expected empty estimate to be flagged, got %v", got)
+	}
+}
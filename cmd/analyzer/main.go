@@ -1,14 +1,15 @@
 package main
 
 import (
-	"bytes"
 	"context"
 	"database/sql"
-	"encoding/json"
+	"flag"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 
 	_ "github.com/lib/pq"
 	"github.com/sashabaranov/go-openai"
@@ -30,6 +31,9 @@ type NutritionEstimate struct {
 }
 
 func main() {
+	consume := flag.Bool("consume", false, "run as a long-lived consumer of recipe events instead of analyzing every recipe once")
+	flag.Parse()
+
 	connStr := os.Getenv("DATABASE_URL")
 	db, err := sql.Open("postgres", connStr)
 	if err != nil {
@@ -42,41 +46,29 @@ func main() {
 		log.Fatalf("Failed to get recipes: %v", err)
 	}
 
-	for _, recipe := range recipes {
-		ingredients, err := GetIngredientsForRecipe(db, recipe.ID)
-		if err != nil {
-			log.Printf("Skipping recipe ID %d: %v", recipe.ID, err)
-			continue
-		}
-
-		prompt := fmt.Sprintf(`Estimate the total nutrition for the following ingredients:
-
-%s
-
-Return only a JSON object with the following keys: "calories", "protein", "fat", "carbohydrates". 
-Use these units: kcal for calories, grams for protein, fat, and carbohydrates. Use integers only. Do not include units in the keys or values.
-`, formatIngredientsForPrompt(ingredients))
-
-		resp, err := QueryModel(prompt)
-		if err != nil {
-			log.Printf("Model query failed for recipe ID %d: %v", recipe.ID, err)
-			continue
-		}
-
-		var nutrition NutritionEstimate
-		if err := json.Unmarshal([]byte(resp), &nutrition); err != nil {
-			log.Printf("Invalid JSON response for recipe ID %d: %v\nRaw: %s", recipe.ID, err, resp)
-			continue
-		}
+	prices := LoadPriceTable()
+	tracker, err := StartRun(db, prices.Default, LoadBudget())
+	if err != nil {
+		log.Fatalf("Failed to start analysis run: %v", err)
+	}
 
-		err = UpsertNutrition(db, recipe.ID, nutrition)
+	analyzer := NewAnalyzerFromEnv(db, tracker, prices)
+	var status string
+	if *consume {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		status, err = Consume(ctx, analyzer, recipes)
+		stop()
 		if err != nil {
-			log.Printf("Failed to save nutrition for recipe ID %d: %v", recipe.ID, err)
-			continue
+			log.Printf("Consumer stopped: %v", err)
 		}
+	} else {
+		status = analyzer.Run(recipes)
+	}
 
-		fmt.Printf("Saved nutrition for \"%s\"\n", recipe.Name)
+	if err := tracker.Finish(status); err != nil {
+		log.Printf("Failed to finish analysis run: %v", err)
 	}
+	fmt.Println(tracker.Summary())
 }
 
 func GetRecipeWithIngredients(db *sql.DB, recipeID int) (string, []Ingredient, error) {
@@ -123,46 +115,23 @@ func formatIngredientsForPrompt(ingredients []Ingredient) string {
 	return sb.String()
 }
 
-func QueryModel(prompt string) (string, error) {
-	apiKey := os.Getenv("OPENAI_API_KEY")
-	baseURL := os.Getenv("MODEL_BASE_URL") // e.g. "https://api.openai.com/v1" or "http://localhost:8000/v1"
-	model := os.Getenv("MODEL_NAME")
-
-	cfg := openai.DefaultConfig(apiKey)
-	cfg.BaseURL = baseURL
-
-	client := openai.NewClientWithConfig(cfg)
-	resp, err := client.CreateChatCompletion(
-		context.Background(),
-		openai.ChatCompletionRequest{
-			Model: model,
-			Messages: []openai.ChatCompletionMessage{
-				{Role: openai.ChatMessageRoleSystem, Content: "You are a nutritionist."},
-				{Role: openai.ChatMessageRoleUser, Content: prompt},
-			},
-			ResponseFormat: &openai.ChatCompletionResponseFormat{Type: openai.ChatCompletionResponseFormatTypeJSONObject},
-		},
-	)
-	if err != nil {
-		return "", err
-	}
-
-	text := resp.Choices[0].Message.Content
-	var pretty bytes.Buffer
-	if err := json.Indent(&pretty, []byte(text), "", "  "); err != nil {
-		// If not valid JSON, return raw text
-		return text, nil
-	}
-	return pretty.String(), nil
+// QueryModel sends a prompt to the default backend.
+func QueryModel(prompt string) (string, openai.Usage, error) {
+	return DefaultBackend().Query(prompt)
 }
 
 type Recipe struct {
-	ID   int
-	Name string
+	ID              int
+	Name            string
+	NutritionLocked bool
 }
 
 func GetAllRecipes(db *sql.DB) ([]Recipe, error) {
-	rows, err := db.Query(`SELECT id, name FROM recipes`)
+	rows, err := db.Query(`
+	SELECT r.id, r.name, COALESCE(n.locked, FALSE)
+	FROM recipes r
+	LEFT JOIN recipe_nutrition n ON n.recipe_id = r.id;
+	`)
 	if err != nil {
 		return nil, err
 	}
@@ -171,7 +140,7 @@ func GetAllRecipes(db *sql.DB) ([]Recipe, error) {
 	var recipes []Recipe
 	for rows.Next() {
 		var r Recipe
-		if err := rows.Scan(&r.ID, &r.Name); err != nil {
+		if err := rows.Scan(&r.ID, &r.Name, &r.NutritionLocked); err != nil {
 			return nil, err
 		}
 		recipes = append(recipes, r)
@@ -204,16 +173,23 @@ func GetIngredientsForRecipe(db *sql.DB, recipeID int) ([]Ingredient, error) {
 	return ingredients, nil
 }
 
-func UpsertNutrition(db *sql.DB, recipeID int, n NutritionEstimate) error {
+func UpsertNutrition(db *sql.DB, recipeID int, n NutritionEstimate, p Provenance) error {
 	query := `
-	INSERT INTO recipe_nutrition (recipe_id, calories, protein, fat, carbohydrates)
-	VALUES ($1, $2, $3, $4, $5)
+	INSERT INTO recipe_nutrition (recipe_id, calories, protein, fat, carbohydrates, source, estimator, prompt_version, input_fingerprint, estimated_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 	ON CONFLICT (recipe_id) DO UPDATE SET
 		calories = EXCLUDED.calories,
 		protein = EXCLUDED.protein,
 		fat = EXCLUDED.fat,
-		carbohydrates = EXCLUDED.carbohydrates;
+		carbohydrates = EXCLUDED.carbohydrates,
+		source = EXCLUDED.source,
+		estimator = EXCLUDED.estimator,
+		prompt_version = EXCLUDED.prompt_version,
+		input_fingerprint = EXCLUDED.input_fingerprint,
+		estimated_at = EXCLUDED.estimated_at
+	WHERE NOT recipe_nutrition.locked;
 	`
-	_, err := db.Exec(query, recipeID, n.Calories, n.Protein, n.Fat, n.Carbohydrates)
+	_, err := db.Exec(query, recipeID, n.Calories, n.Protein, n.Fat, n.Carbohydrates,
+		p.Source, p.Estimator, p.PromptVersion, p.InputFingerprint, p.EstimatedAt)
 	return err
 }
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Nutrition sources the analyzer records with the recipe_nutrition rows it writes.
+// Reviewer edits are recorded as "manual" by the review queue.
+const (
+	SourceModel    = "model"
+	SourceEnsemble = "ensemble"
+)
+
+// nutritionPromptVersion must be bumped whenever NutritionPrompt changes meaningfully,
+// so stored values can be traced back to the prompt that produced them.
+const nutritionPromptVersion = "nutrition-v1"
+
+// Provenance describes where a stored nutrition value came from.
+type Provenance struct {
+	Source           string
+	Estimator        string
+	PromptVersion    string
+	InputFingerprint string
+	EstimatedAt      time.Time
+}
+
+// NutritionPrompt builds the estimation prompt for a recipe's ingredients.
+func NutritionPrompt(ingredients []Ingredient) string {
+	return fmt.Sprintf(`Estimate the total nutrition for the following ingredients:
+
+%s
+
+Return only a JSON object with the following keys: "calories", "protein", "fat", "carbohydrates". 
+Use these units: kcal for calories, grams for protein, fat, and carbohydrates. Use integers only. Do not include units in the keys or values.
+`, formatIngredientsForPrompt(ingredients))
+}
+
+// ModelProvenance records which backends and prompts produced an estimate from which input.
+func ModelProvenance(estimators, promptVersions []string, ingredients []Ingredient) Provenance {
+	source := SourceModel
+	if len(estimators) > 1 {
+		source = SourceEnsemble
+	}
+	return Provenance{
+		Source:           source,
+		Estimator:        strings.Join(estimators, ","),
+		PromptVersion:    strings.Join(uniqueStrings(promptVersions), ","),
+		InputFingerprint: ingredientFingerprint(ingredients),
+		EstimatedAt:      time.Now().UTC(),
+	}
+}
+
+// ingredientFingerprint identifies the estimation input, so unchanged recipes can be skipped.
+func ingredientFingerprint(ingredients []Ingredient) string {
+	return hashInput(formatIngredientsForPrompt(ingredients))
+}
+
+// isCurrentPromptVersion reports whether a stored prompt_version only names prompts still in use.
+func isCurrentPromptVersion(stored string) bool {
+	if stored == "" {
+		return false
+	}
+	for _, version := range strings.Split(stored, ",") {
+		if version != nutritionPromptVersion && version != batchPromptVersion {
+			return false
+		}
+	}
+	return true
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	var unique []string
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			unique = append(unique, v)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,29 @@
+package main
+
+import "testing"
+
+func TestModelProvenance(t *testing.T) {
+	ingredients := []Ingredient{{Name: "ground turkey", Amount: "1", Unit: "lb"}, {Name: "kidney beans", Amount: "2", Unit: "cans"}}
+
+	single := ModelProvenance([]string{"gpt-4o-mini"}, []string{nutritionPromptVersion}, ingredients)
+	if single.Source != SourceModel || single.Estimator != "gpt-4o-mini" || single.PromptVersion != nutritionPromptVersion {
+		t.Fatalf("unexpected single-model provenance %+v", single)
+	}
+
+	ensemble := ModelProvenance([]string{"gpt-4o-mini", "llama3"}, []string{nutritionPromptVersion, batchPromptVersion, nutritionPromptVersion}, ingredients)
+	if ensemble.Source != SourceEnsemble || ensemble.Estimator != "gpt-4o-mini,llama3" {
+		t.Fatalf("unexpected ensemble provenance %+v", ensemble)
+	}
+	if ensemble.PromptVersion != nutritionPromptVersion+","+batchPromptVersion || !isCurrentPromptVersion(ensemble.PromptVersion) {
+		t.Fatalf("expected each prompt version once, got %q", ensemble.PromptVersion)
+	}
+	if ensemble.InputFingerprint != single.InputFingerprint || ensemble.InputFingerprint == "" {
+		t.Fatal("expected the fingerprint to depend only on the ingredients")
+	}
+	if changed := ModelProvenance([]string{"gpt-4o-mini"}, nil, ingredients[:1]); changed.InputFingerprint == single.InputFingerprint {
+		t.Fatal("expected a different ingredient list to change the fingerprint")
+	}
+	if isCurrentPromptVersion("nutrition-v0") || isCurrentPromptVersion("") {
+		t.Fatal("expected retired or missing prompt versions to be stale")
+	}
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/sashabaranov/go-openai"
+)
+
+const (
+	RunStatusRunning         = "running"
+	RunStatusCompleted       = "completed"
+	RunStatusBudgetExhausted = "budget_exhausted"
+)
+
+// PriceConfig holds the USD prices used to cost a model call.
+type PriceConfig struct {
+	Model                string
+	PromptPricePer1K     float64
+	CompletionPricePer1K float64
+}
+
+// Cost returns the USD cost of a single call with the given token usage.
+func (p PriceConfig) Cost(usage openai.Usage) float64 {
+	return float64(usage.PromptTokens)/1000*p.PromptPricePer1K +
+		float64(usage.CompletionTokens)/1000*p.CompletionPricePer1K
+}
+
+// Budget caps spend per run and per UTC day. A zero value means unlimited.
+type Budget struct {
+	PerRun float64
+	PerDay float64
+}
+
+// Exceeded reports whether either cap has been reached and which one.
+func (b Budget) Exceeded(runCost, dayCost float64) (string, bool) {
+	if b.PerRun > 0 && runCost >= b.PerRun {
+		return fmt.Sprintf("run budget of $%.4f reached", b.PerRun), true
+	}
+	if b.PerDay > 0 && dayCost >= b.PerDay {
+		return fmt.Sprintf("daily budget of $%.4f reached", b.PerDay), true
+	}
+	return "", false
+}
+
+// LoadPriceConfig reads the model name and token prices from the environment.
+func LoadPriceConfig() PriceConfig {
+	return PriceConfig{
+		Model:                os.Getenv("MODEL_NAME"),
+		PromptPricePer1K:     envFloat("MODEL_PROMPT_PRICE_PER_1K"),
+		CompletionPricePer1K: envFloat("MODEL_COMPLETION_PRICE_PER_1K"),
+	}
+}
+
+// PriceTable prices model calls by model. Models without an entry of their own
+// are charged the Default prices.
+type PriceTable struct {
+	Default PriceConfig
+	ByModel map[string]PriceConfig
+}
+
+// For returns the prices of model.
+func (t PriceTable) For(model string) PriceConfig {
+	if prices, ok := t.ByModel[model]; ok {
+		return prices
+	}
+	prices := t.Default
+	prices.Model = model
+	return prices
+}
+
+// ParseModelPrices reads a comma-separated list of model:prompt:completion
+// entries, with prices in USD per 1K tokens. The prices are taken from the end
+// of each entry, so model names may contain colons themselves, as in
+// "llama3:8b:0:0".
+func ParseModelPrices(spec string) (map[string]PriceConfig, error) {
+	prices := make(map[string]PriceConfig)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.Split(entry, ":")
+		if len(parts) < 3 {
+			return nil, fmt.Errorf("price entry %q is not model:prompt:completion", entry)
+		}
+		model := strings.Join(parts[:len(parts)-2], ":")
+		prompt, err1 := strconv.ParseFloat(parts[len(parts)-2], 64)
+		completion, err2 := strconv.ParseFloat(parts[len(parts)-1], 64)
+		if model == "" || err1 != nil || err2 != nil || prompt < 0 || completion < 0 {
+			return nil, fmt.Errorf("price entry %q is not model:prompt:completion", entry)
+		}
+		prices[model] = PriceConfig{Model: model, PromptPricePer1K: prompt, CompletionPricePer1K: completion}
+	}
+	return prices, nil
+}
+
+// LoadPriceTable reads per-model prices from MODEL_PRICES, falling back to the
+// MODEL_NAME prices of LoadPriceConfig for models it does not list.
+func LoadPriceTable() PriceTable {
+	byModel, err := ParseModelPrices(os.Getenv("MODEL_PRICES"))
+	if err != nil {
+		log.Fatalf("Invalid value for MODEL_PRICES: %v", err)
+	}
+	table := PriceTable{Default: LoadPriceConfig(), ByModel: byModel}
+	if prices, ok := byModel[table.Default.Model]; ok {
+		table.Default = prices
+	}
+	return table
+}
+
+// LoadEmbeddingPriceConfig reads the price of embedding input tokens from the environment.
+func LoadEmbeddingPriceConfig(model string) PriceConfig {
+	return PriceConfig{
+		Model:            model,
+		PromptPricePer1K: envFloat("EMBEDDING_PRICE_PER_1K"),
+	}
+}
+
+// LoadBudget reads the run and daily spend caps from the environment.
+func LoadBudget() Budget {
+	return Budget{
+		PerRun: envFloat("ANALYZER_RUN_BUDGET_USD"),
+		PerDay: envFloat("ANALYZER_DAILY_BUDGET_USD"),
+	}
+}
+
+func envFloat(name string) float64 {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return 0
+	}
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		log.Fatalf("Invalid value for %s: %v", name, err)
+	}
+	return value
+}
+
+// UsageTracker records token usage for one analyzer run and enforces its budget.
+type UsageTracker struct {
+	db               *sql.DB
+	RunID            int
+	budget           Budget
+	day              time.Time
+	spentToday       float64
+	Calls            int
+	PromptTokens     int
+	CompletionTokens int
+	Cost             float64
+}
+
+// StartRun inserts a new analysis_runs row and loads what has already been spent today.
+func StartRun(db *sql.DB, prices PriceConfig, budget Budget) (*UsageTracker, error) {
+	t := &UsageTracker{db: db, budget: budget}
+
+	err := db.QueryRow(`
+	INSERT INTO analysis_runs (model, prompt_price_per_1k, completion_price_per_1k, run_budget_usd, daily_budget_usd, status)
+	VALUES ($1, $2, $3, NULLIF($4, 0), NULLIF($5, 0), $6)
+	RETURNING id;
+	`, prices.Model, prices.PromptPricePer1K, prices.CompletionPricePer1K, budget.PerRun, budget.PerDay, RunStatusRunning).Scan(&t.RunID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := t.loadSpentToday(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// loadSpentToday reads what all runs have spent since the start of the current UTC day.
+func (t *UsageTracker) loadSpentToday() error {
+	t.day = time.Now().UTC().Truncate(24 * time.Hour)
+	return t.db.QueryRow(`
+	SELECT COALESCE(SUM(cost_usd), 0)::float8
+	FROM analysis_run_calls
+	WHERE created_at >= $1;
+	`, t.day).Scan(&t.spentToday)
+}
+
+// Exhausted reports whether the run should stop before making another call.
+// A run that outlives the day, such as the event consumer, switches to the new
+// day's spending once the date changes.
+func (t *UsageTracker) Exhausted() (string, bool) {
+	if time.Now().UTC().Truncate(24 * time.Hour).After(t.day) {
+		if err := t.loadSpentToday(); err != nil {
+			log.Printf("Failed to load today's spending: %v", err)
+		}
+	}
+	return t.budget.Exceeded(t.Cost, t.spentToday)
+}
+
+// RunExhausted reports whether the run's own cap, rather than the daily one, is reached.
+func (t *UsageTracker) RunExhausted() bool {
+	return t.budget.PerRun > 0 && t.Cost >= t.budget.PerRun
+}
+
+// Record stores the usage of one model call made for a recipe, costed with the given prices.
+func (t *UsageTracker) Record(recipeID int, prices PriceConfig, usage openai.Usage) error {
+	return t.record(recipeID, nil, prices, usage)
+}
+
+// RecordBatch stores the usage of one model call that covered several recipes.
+func (t *UsageTracker) RecordBatch(recipeIDs []int, prices PriceConfig, usage openai.Usage) error {
+	return t.record(nil, pq.Array(recipeIDs), prices, usage)
+}
+
+func (t *UsageTracker) record(recipeID, recipeIDs any, prices PriceConfig, usage openai.Usage) error {
+	cost := prices.Cost(usage)
+	t.Calls++
+	t.PromptTokens += usage.PromptTokens
+	t.CompletionTokens += usage.CompletionTokens
+	t.Cost += cost
+	t.spentToday += cost
+
+	_, err := t.db.Exec(`
+	INSERT INTO analysis_run_calls (run_id, recipe_id, recipe_ids, model, prompt_tokens, completion_tokens, cost_usd)
+	VALUES ($1, $2, $3, $4, $5, $6, $7);
+	`, t.RunID, recipeID, recipeIDs, prices.Model, usage.PromptTokens, usage.CompletionTokens, cost)
+	return err
+}
+
+// Finish writes the run totals and final status.
+func (t *UsageTracker) Finish(status string) error {
+	_, err := t.db.Exec(`
+	UPDATE analysis_runs SET
+		status = $2,
+		calls = $3,
+		prompt_tokens = $4,
+		completion_tokens = $5,
+		cost_usd = $6,
+		finished_at = NOW()
+	WHERE id = $1;
+	`, t.RunID, status, t.Calls, t.PromptTokens, t.CompletionTokens, t.Cost)
+	return err
+}
+
+// Summary returns a human-readable report of the run's usage.
+func (t *UsageTracker) Summary() string {
+	return fmt.Sprintf(
+		"Run %d: %d calls, %d prompt tokens, %d completion tokens, $%.4f spent (today: $%.4f)",
+		t.RunID, t.Calls, t.PromptTokens, t.CompletionTokens, t.Cost, t.spentToday,
+	)
+}
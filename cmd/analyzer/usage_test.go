@@ -0,0 +1,65 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestPriceConfig_Cost(t *testing.T) {
+	prices := PriceConfig{PromptPricePer1K: 0.5, CompletionPricePer1K: 1.5}
+
+	cost := prices.Cost(openai.Usage{PromptTokens: 2000, CompletionTokens: 500})
+
+	if math.Abs(cost-1.75) > 1e-9 {
+		t.Fatalf("expected cost 1.75, got %f", cost)
+	}
+}
+
+func TestParseModelPrices(t *testing.T) {
+	prices, err := ParseModelPrices("gpt-4o-mini:0.15:0.6, llama3:8b:0:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	table := PriceTable{Default: PriceConfig{Model: "gpt-4o-mini", PromptPricePer1K: 1, CompletionPricePer1K: 1}, ByModel: prices}
+
+	if got := table.For("gpt-4o-mini"); got.PromptPricePer1K != 0.15 || got.CompletionPricePer1K != 0.6 {
+		t.Fatalf("expected the listed gpt-4o-mini prices, got %+v", got)
+	}
+	if got := table.For("llama3:8b"); got.Model != "llama3:8b" || got.PromptPricePer1K != 0 {
+		t.Fatalf("expected free llama3:8b, got %+v", got)
+	}
+	if got := table.For("mistral"); got.Model != "mistral" || got.PromptPricePer1K != 1 {
+		t.Fatalf("expected the default prices under the model's name, got %+v", got)
+	}
+	for _, spec := range []string{"gpt-4o-mini", "gpt-4o-mini:0.15", ":1:1", "gpt-4o-mini:cheap:0.6", "gpt-4o-mini:-1:0"} {
+		if _, err := ParseModelPrices(spec); err == nil {
+			t.Errorf("expected %q to be rejected", spec)
+		}
+	}
+}
+
+func TestBudget_Exceeded(t *testing.T) {
+	tests := []struct {
+		name     string
+		budget   Budget
+		runCost  float64
+		dayCost  float64
+		exceeded bool
+	}{
+		{"unlimited", Budget{}, 100, 100, false},
+		{"under both caps", Budget{PerRun: 1, PerDay: 5}, 0.5, 2, false},
+		{"run cap reached", Budget{PerRun: 1, PerDay: 5}, 1, 2, true},
+		{"day cap reached", Budget{PerRun: 1, PerDay: 5}, 0.5, 5, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, exceeded := tt.budget.Exceeded(tt.runCost, tt.dayCost)
+			if exceeded != tt.exceeded {
+				t.Fatalf("expected exceeded=%v, got %v", tt.exceeded, exceeded)
+			}
+		})
+	}
+}
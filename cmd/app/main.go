@@ -4,12 +4,23 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"os"
 	"time"
 	"net/http"
-	"cloud.google.com/go/pubsub"
-	"github.com/alicebob/miniredis/v2"
 	"github.com/coloradocollective/go-capstone-starter/internal/app"
+	"github.com/coloradocollective/go-capstone-starter/internal/auth"
+	"github.com/coloradocollective/go-capstone-starter/internal/cache"
+	"github.com/coloradocollective/go-capstone-starter/internal/embeddings"
+	"github.com/coloradocollective/go-capstone-starter/internal/foodentries"
+	"github.com/coloradocollective/go-capstone-starter/internal/foodimport"
+	"github.com/coloradocollective/go-capstone-starter/internal/foodlog"
+	"github.com/coloradocollective/go-capstone-starter/internal/foods"
+	"github.com/coloradocollective/go-capstone-starter/internal/goals"
+	"github.com/coloradocollective/go-capstone-starter/internal/health"
+	"github.com/coloradocollective/go-capstone-starter/internal/measurements"
+	"github.com/coloradocollective/go-capstone-starter/internal/messaging"
+	"github.com/coloradocollective/go-capstone-starter/internal/outbox"
+	"github.com/coloradocollective/go-capstone-starter/internal/profile"
+	"github.com/coloradocollective/go-capstone-starter/internal/recipes"
 	"github.com/coloradocollective/go-capstone-starter/internal/utils"
 	"github.com/coloradocollective/go-capstone-starter/pkg/dbsupport"
 	"github.com/coloradocollective/go-capstone-starter/pkg/websupport"
@@ -17,10 +28,10 @@ import (
 	"github.com/joho/godotenv"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
-)
 
-var pubsubClient *pubsub.Client
-var pubsubTopic *pubsub.Topic
+	// The runtime image has no zoneinfo; users' time zones are resolved from this copy.
+	_ "time/tzdata"
+)
 
 
 var httpRequestsTotal = prometheus.NewCounter(
@@ -47,26 +58,37 @@ func main() {
 		log.Printf("Warning: .env file not found")
 	}
 
-	mr, err := miniredis.Run()
+	redisCache, err := cache.Open(cache.ConfigFromEnv())
 	if err != nil {
-		log.Fatalf("Failed to start Redis server: %v", err)
+		log.Fatalf("Failed to configure Redis: %v", err)
+	}
+	defer redisCache.Close()
+
+	// utils only takes an address, so it reaches the configured server without its
+	// password, DB or TLS settings; code that needs those uses redisCache.Client.
+	utils.InitRedisWithAddr(redisCache.Addr)
+	if err := redisCache.Ping(context.Background()); err != nil {
+		log.Printf("Warning: Redis is not reachable (%s): %v", redisCache.Describe(), err)
+	} else {
+		log.Printf("Using %s", redisCache.Describe())
 	}
-	defer mr.Close()
-
-	utils.InitRedisWithAddr(mr.Addr())
-	log.Printf("Redis server started at %s", mr.Addr())
 
-	projectID := os.Getenv("GCP_PROJECT_ID")
-	pubsubClient, err = pubsub.NewClient(context.Background(), projectID)
+	// NewFromEnv only picks the in-memory broker when no backend is configured; a
+	// configured backend that cannot start must not silently drop every event.
+	broker, err := messaging.NewFromEnv(context.Background())
 	if err != nil {
-		log.Fatalf("Failed to create Pub/Sub client: %v", err)
+		log.Fatalf("Failed to start messaging backend: %v", err)
+	}
+	defer broker.Close()
+	log.Printf("Messaging broker: %T", broker)
+
+	// utils still publishes through a Pub/Sub client, so it is only wired up on GCP.
+	if gcp, ok := broker.(*messaging.GCPBroker); ok {
+		topicName := "nutrition-helper-topic"
+		if err := utils.InitPubSub(gcp.Client(), topicName); err != nil {
+			log.Printf("Warning: failed to initialize Pub/Sub topic %s: %v", topicName, err)
+		}
 	}
-	defer pubsubClient.Close()
-
-	topicName := "nutrition-helper-topic"
-if err := utils.InitPubSub(pubsubClient, topicName); err != nil {
-	log.Fatalf("Failed to initialize Pub/Sub: %v", err)
-}
 
 	host := websupport.EnvironmentVariable("HOST", "")
 	port := websupport.EnvironmentVariable("BACKEND_PORT", 8778)
@@ -77,7 +99,28 @@ if err := utils.InitPubSub(pubsubClient, topicName); err != nil {
 	db := dbsupport.CreateConnection(databaseUrl)
 	mainMux := http.NewServeMux()
 
+	relayCtx, stopRelay := context.WithCancel(context.Background())
+	defer stopRelay()
+	go outbox.NewRelay(outbox.NewPostgresStore(db), broker).Run(relayCtx)
+
 	app.Handlers(db)(mainMux)
+	health.Handlers(map[string]health.Check{
+		"database": db.PingContext,
+		"cache":    redisCache.Ping,
+	})(mainMux)
+	foodentries.Handlers(db, auth.FromEnv())(mainMux)
+	profile.Handlers(db, auth.FromEnv())(mainMux)
+	goals.Handlers(db, auth.FromEnv())(mainMux)
+	measurements.Handlers(db, auth.FromEnv())(mainMux)
+	foodimport.Handlers(db, auth.FromEnv())(mainMux)
+	foodLookup, err := foods.LookupFromEnv(foods.NewPostgresStore(db), redisCache.Client)
+	if err != nil {
+		log.Fatalf("Failed to configure food lookup: %v", err)
+	}
+	foods.Handlers(db, foodLookup, auth.FromEnv())(mainMux)
+	foodlog.Handlers(db, foodLookup, auth.FromEnv())(mainMux)
+	recipes.Handlers(db, embeddings.NewFromEnv(), auth.FromEnv())(mainMux)
+	recipes.AdminHandlers(db, websupport.EnvironmentVariable("ADMIN_API_TOKEN", ""))(mainMux)
 
 	metricsMux := http.NewServeMux()
 	prometheus.MustRegister(httpRequestsTotal)
@@ -92,7 +135,7 @@ if err := utils.InitPubSub(pubsubClient, topicName); err != nil {
 	corsHandler := handlers.CORS(
 		handlers.AllowedOrigins([]string{"http://localhost:5173", "http://localhost:8080", "https://s25-team-3-capstone-450859268851.us-central1.run.app"}), 
 		handlers.AllowedMethods([]string{"GET", "POST", "PUT", "DELETE"}), 
-		handlers.AllowedHeaders([]string{"Content-Type", "Authorization"}), 
+		handlers.AllowedHeaders([]string{"Content-Type", "Authorization", "X-Admin-Token"}), 
 	)(loggingMiddleware(finalMux))
 
 	server := &http.Server{
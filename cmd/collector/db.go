@@ -3,9 +3,10 @@ package main
 
 import (
 	"database/sql"
+	"errors"
 	"os"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 // InitializeDB initializes the database connection.
@@ -24,18 +25,18 @@ func InsertRecipeIngredient(db *sql.DB, recipeIngredient RecipeIngredient) error
 	return err
 }
 
-// InsertRecipe inserts a new recipe into the database.
-func InsertRecipe(db *sql.DB, recipe Recipe) error {
-	query := `INSERT INTO recipes (slug, name, image_url, calories, number_of_ingredients) VALUES ($1, $2, $3, $4, $5) RETURNING id`
-	err := db.QueryRow(query, recipe.Slug, recipe.Name, recipe.ImageURL, recipe.Calories, recipe.NumberOfIngredients).Scan(&recipe.ID)
+// InsertRecipe inserts a new recipe into the database and returns its ID.
+func InsertRecipe(db *sql.DB, recipe Recipe) (int, error) {
+	query := `INSERT INTO recipes (slug, name, image_url, calories, number_of_ingredients, servings) VALUES ($1, $2, $3, $4, $5, NULLIF($6, 0)) RETURNING id`
+	err := db.QueryRow(query, recipe.Slug, recipe.Name, recipe.ImageURL, recipe.Calories, recipe.NumberOfIngredients, recipe.Servings).Scan(&recipe.ID)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	for _, ingredient := range recipe.Ingredients {
 		ingredientID, err := InsertIngredient(db, ingredient)
 		if err != nil {
-			return err
+			return 0, err
 		}
 		recipeIngredient := RecipeIngredient{
 			RecipeID:     recipe.ID,
@@ -47,11 +48,11 @@ func InsertRecipe(db *sql.DB, recipe Recipe) error {
 		}
 		err = InsertRecipeIngredient(db, recipeIngredient)
 		if err != nil {
-			return err
+			return 0, err
 		}
 	}
 
-	return nil
+	return recipe.ID, nil
 }
 
 // InsertIngredient inserts a new ingredient into the database.
@@ -62,12 +63,102 @@ func InsertIngredient(db *sql.DB, ingredient Ingredient) (int, error) {
 	return ingredientID, err
 }
 
-func RecipeExists(db *sql.DB, recipe Recipe) (bool, error) {
-	var exists bool
-	query := `SELECT EXISTS(SELECT 1 FROM recipes WHERE name = $1)`
-	err := db.QueryRow(query, recipe.Name).Scan(&exists)
+// FindRecipeID returns the ID of the stored recipe with the same name, if any.
+func FindRecipeID(db *sql.DB, recipe Recipe) (int, bool, error) {
+	var id int
+	err := db.QueryRow(`SELECT id FROM recipes WHERE name = $1 ORDER BY id LIMIT 1`, recipe.Name).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return id, true, nil
+}
+
+// UpdateServings stores the yield scraped for an existing recipe. A page that
+// no longer says leaves the stored yield alone.
+func UpdateServings(db *sql.DB, recipe Recipe) error {
+	if recipe.Servings <= 0 {
+		return nil
+	}
+	_, err := db.Exec(`UPDATE recipes SET servings = $2 WHERE id = $1 AND servings IS DISTINCT FROM $2`, recipe.ID, recipe.Servings)
+	return err
+}
+
+// ReplaceIngredientsIfChanged compares the stored ingredients of recipe.ID with
+// recipe.Ingredients and, when they differ, replaces them in one transaction.
+// It reports whether anything changed.
+func ReplaceIngredientsIfChanged(db *sql.DB, recipe Recipe) (bool, error) {
+	tx, err := db.Begin()
 	if err != nil {
 		return false, err
 	}
-	return exists, nil
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`
+	SELECT i.id, i.name, COALESCE(ri.amount, ''), COALESCE(ri.unit, ''), COALESCE(ri.notes, ''), COALESCE(ri.position, 0)
+	FROM recipe_ingredients ri
+	JOIN ingredients i ON i.id = ri.ingredient_id
+	WHERE ri.recipe_id = $1
+	ORDER BY ri.position, ri.id`, recipe.ID)
+	if err != nil {
+		return false, err
+	}
+	var stored []Ingredient
+	var ingredientIDs []int64
+	for rows.Next() {
+		var i Ingredient
+		if err := rows.Scan(&i.ID, &i.Name, &i.Amount, &i.Unit, &i.Notes, &i.Position); err != nil {
+			rows.Close()
+			return false, err
+		}
+		stored = append(stored, i)
+		ingredientIDs = append(ingredientIDs, int64(i.ID))
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return false, err
+	}
+	if sameIngredients(stored, recipe.Ingredients) {
+		return false, nil
+	}
+
+	// Ingredient rows are created per recipe, so the old ones can go with their links.
+	if _, err := tx.Exec(`DELETE FROM recipe_ingredients WHERE recipe_id = $1`, recipe.ID); err != nil {
+		return false, err
+	}
+	if _, err := tx.Exec(`DELETE FROM ingredients WHERE id = ANY($1)`, pq.Array(ingredientIDs)); err != nil {
+		return false, err
+	}
+	for _, ingredient := range recipe.Ingredients {
+		var ingredientID int
+		err := tx.QueryRow(`INSERT INTO ingredients (name, uid) VALUES ($1, $2) RETURNING id`, ingredient.Name, ingredient.UID).Scan(&ingredientID)
+		if err != nil {
+			return false, err
+		}
+		_, err = tx.Exec(`INSERT INTO recipe_ingredients (recipe_id, ingredient_id, amount, unit, notes, position) VALUES ($1, $2, $3, $4, $5, $6)`,
+			recipe.ID, ingredientID, ingredient.Amount, ingredient.Unit, ingredient.Notes, ingredient.Position)
+		if err != nil {
+			return false, err
+		}
+	}
+	if _, err := tx.Exec(`UPDATE recipes SET number_of_ingredients = $2 WHERE id = $1`, recipe.ID, len(recipe.Ingredients)); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// sameIngredients compares ingredient lists by what the analyzer reads from them.
+func sameIngredients(stored, scraped []Ingredient) bool {
+	if len(stored) != len(scraped) {
+		return false
+	}
+	for i := range stored {
+		a, b := stored[i], scraped[i]
+		if a.Name != b.Name || a.Amount != b.Amount || a.Unit != b.Unit || a.Notes != b.Notes {
+			return false
+		}
+	}
+	return true
 }
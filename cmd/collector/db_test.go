@@ -51,3 +51,34 @@ func TestDB_InsertRecipeAndIngredients(t *testing.T) {
 	_, _ = db.Exec(`DELETE FROM ingredients WHERE recipe_id = $1`, recipeID)
 	_, _ = db.Exec(`DELETE FROM recipes WHERE id = $1`, recipeID)
 }
+
+func TestSameIngredients(t *testing.T) {
+	stored := []Ingredient{{ID: 4, Name: "oats", Amount: "2", Unit: "cups"}, {ID: 5, Name: "honey", Amount: "1/4", Unit: "cup"}}
+	if !sameIngredients(stored, []Ingredient{{Name: "oats", Amount: "2", Unit: "cups"}, {Name: "honey", Amount: "1/4", Unit: "cup"}}) {
+		t.Fatal("expected the same list scraped again to be unchanged")
+	}
+	if sameIngredients(stored, []Ingredient{{Name: "oats", Amount: "3", Unit: "cups"}, {Name: "honey", Amount: "1/4", Unit: "cup"}}) {
+		t.Fatal("expected a changed amount to count as a change")
+	}
+	if sameIngredients(stored, stored[:1]) {
+		t.Fatal("expected a removed ingredient to count as a change")
+	}
+}
+
+func TestRecipeServings(t *testing.T) {
+	cases := []struct {
+		recipe map[string]interface{}
+		want   int
+	}{
+		{map[string]interface{}{"originalServingsParsed": 6.0, "originalServings": "6"}, 6},
+		{map[string]interface{}{"originalServings": " 4 "}, 4},
+		{map[string]interface{}{"originalServingsParsed": 2.5, "originalServings": "2-3"}, 0},
+		{map[string]interface{}{"originalServingsParsed": 0.0}, 0},
+		{map[string]interface{}{}, 0},
+	}
+	for _, c := range cases {
+		if got := recipeServings(c.recipe); got != c.want {
+			t.Errorf("recipeServings(%v) = %d, want %d", c.recipe, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+
+	"github.com/coloradocollective/go-capstone-starter/internal/events"
+	"github.com/coloradocollective/go-capstone-starter/internal/messaging"
+)
+
+// Recipe event types the analyzer consumes: new recipes, and stored recipes whose
+// ingredients changed.
+const (
+	RecipeIngestedEvent = "recipe.ingested"
+	RecipeChangedEvent  = "recipe.changed"
+	recipeEventVersion  = 1
+)
+
+// RecipeEventData is published whenever the collector stores or updates a recipe.
+type RecipeEventData struct {
+	RecipeID int    `json:"recipe_id"`
+	Slug     string `json:"slug"`
+}
+
+// recipeEvents is nil when no messaging backend could be started; events are then
+// only logged.
+var (
+	recipeEvents messaging.Publisher
+	recipeTopic  string
+)
+
+// initEventPublisher picks the backend the way the app and the analyzer do (see
+// messaging.NewFromEnv) and publishes to RECIPE_EVENTS_TOPIC.
+func initEventPublisher() {
+	recipeTopic = os.Getenv("RECIPE_EVENTS_TOPIC")
+	if recipeTopic == "" {
+		recipeTopic = "recipe-events"
+	}
+	broker, err := messaging.NewFromEnv(context.Background())
+	if err != nil {
+		log.Printf("Failed to start messaging backend; recipe events will only be logged: %v", err)
+		return
+	}
+	log.Printf("Publishing recipe events to %s with %T", recipeTopic, broker)
+	recipeEvents = broker
+}
+
+// publishRecipeEvent tells the analyzer about a newly stored or changed recipe. A
+// failed publish is logged but does not fail ingestion; the analyzer's batch mode
+// still picks the recipe up.
+func publishRecipeEvent(eventType string, recipe Recipe) {
+	env, err := events.New(eventType, recipeEventVersion, 0, RecipeEventData{RecipeID: recipe.ID, Slug: recipe.Slug})
+	if err != nil {
+		log.Printf("Failed to build recipe event for recipe ID %d: %v", recipe.ID, err)
+		return
+	}
+	if recipeEvents == nil {
+		log.Printf("Recipe event: %s for recipe ID %d", env.Type, recipe.ID)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := events.Publish(ctx, recipeEvents, recipeTopic, env); err != nil {
+		log.Printf("Failed to publish recipe event for recipe ID %d: %v", recipe.ID, err)
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/coloradocollective/go-capstone-starter/internal/events"
+	"github.com/coloradocollective/go-capstone-starter/internal/messaging"
+)
+
+func TestPublishRecipeEvent(t *testing.T) {
+	broker := messaging.NewMemoryBroker()
+	recipeEvents, recipeTopic = broker, "recipe-events"
+	defer func() { recipeEvents = nil }()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	got := make(chan messaging.Message, 1)
+	go broker.Subscribe(ctx, "recipe-events", "recipe-analyzer", func(_ context.Context, msg messaging.Message) error {
+		got <- msg
+		return nil
+	})
+	publishRecipeEvent(RecipeChangedEvent, Recipe{ID: 12, Slug: "oat-bars"})
+
+	select {
+	case msg := <-got:
+		env, err := events.Decode(msg.Data)
+		if err != nil {
+			t.Fatalf("published event does not match its schema: %v", err)
+		}
+		var data RecipeEventData
+		if err := env.DecodeData(&data); err != nil || env.Type != RecipeChangedEvent || data.RecipeID != 12 || data.Slug != "oat-bars" {
+			t.Fatalf("unexpected event %+v (%v)", env, err)
+		}
+		if msg.Attributes["event_id"] != env.ID || msg.Attributes["version"] != "1" {
+			t.Fatalf("unexpected attributes %v", msg.Attributes)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("recipe event was not published")
+	}
+}
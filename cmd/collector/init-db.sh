@@ -16,9 +16,12 @@ CREATE TABLE IF NOT EXISTS recipes (
     name TEXT NOT NULL,
     image_url TEXT,
     calories FLOAT,  -- Add calories
-    number_of_ingredients INT
+    number_of_ingredients INT,
+    servings INT CHECK (servings > 0)  -- NULL when the recipe does not say
 );
 
+ALTER TABLE recipes ADD COLUMN IF NOT EXISTS servings INT CHECK (servings > 0);
+
 CREATE TABLE IF NOT EXISTS ingredients (
     id SERIAL PRIMARY KEY,
     name TEXT NOT NULL,
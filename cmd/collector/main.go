@@ -1,6 +1,7 @@
 package main
 
 func main() {
+	initEventPublisher()
 	scrapeData()
 	startServer()
 }
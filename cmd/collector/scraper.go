@@ -7,8 +7,10 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"math"
 	"net/http"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -93,6 +95,22 @@ func generateSlug(name string) string {
 	return strings.ToLower(strings.ReplaceAll(name, " ", "-"))
 }
 
+// recipeServings returns how many servings a WPRM recipe makes, or 0 when it
+// does not say.
+func recipeServings(recipeMap map[string]interface{}) int {
+	if parsed, ok := recipeMap["originalServingsParsed"].(float64); ok && parsed >= 1 && parsed == math.Trunc(parsed) {
+		return int(parsed)
+	}
+	for _, key := range []string{"originalServings", "servings"} {
+		if text, ok := recipeMap[key].(string); ok {
+			if n, err := strconv.Atoi(strings.TrimSpace(text)); err == nil && n > 0 {
+				return n
+			}
+		}
+	}
+	return 0
+}
+
 func recipeJSONToDB(recipeJSON string) error {
 	// parse json
 	var recipeData map[string]interface{}
@@ -126,6 +144,7 @@ func recipeJSONToDB(recipeJSON string) error {
 			Name:                name,
 			ImageURL:            imageURL,
 			NumberOfIngredients: numberOfIngredients,
+			Servings:            recipeServings(recipeMap),
 		}
 
 		// insert into database
@@ -135,15 +154,6 @@ func recipeJSONToDB(recipeJSON string) error {
 		}
 		defer db.Close()
 
-		exists, err := RecipeExists(db, recipe)
-		if err != nil {
-			return err
-		}
-		if exists {
-			log.Printf("Recipe %s already exists in the database. Skipping insertion.\n", name)
-			continue
-		}
-
 		// process ingredients
 		for _, ingredient := range ingredients {
 			if ingredientMap, ok := ingredient.(map[string]interface{}); ok {
@@ -163,10 +173,33 @@ func recipeJSONToDB(recipeJSON string) error {
 			}
 		}
 
-		err = InsertRecipe(db, recipe)
+		existingID, exists, err := FindRecipeID(db, recipe)
+		if err != nil {
+			return err
+		}
+		if exists {
+			recipe.ID = existingID
+			if err := UpdateServings(db, recipe); err != nil {
+				return err
+			}
+			changed, err := ReplaceIngredientsIfChanged(db, recipe)
+			if err != nil {
+				return err
+			}
+			if !changed {
+				log.Printf("Recipe %s already exists in the database. Skipping insertion.\n", name)
+				continue
+			}
+			log.Printf("Ingredients of recipe %s changed; updated them.\n", name)
+			publishRecipeEvent(RecipeChangedEvent, recipe)
+			break
+		}
+
+		recipe.ID, err = InsertRecipe(db, recipe)
 		if err != nil {
 			return err
 		}
+		publishRecipeEvent(RecipeIngestedEvent, recipe)
 
 		break // exit after processing the first recipe
 	}
@@ -9,6 +9,7 @@ type Recipe struct {
 	ImageURL            string       `db:"image_url"`
 	Calories            float64      `db:"calories"`
 	NumberOfIngredients int          `db:"number_of_ingredients"`
+	Servings            int          `db:"servings"` // 0 when the recipe does not say
 	Ingredients         []Ingredient // Associated ingredients
 }
 
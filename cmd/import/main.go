@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/coloradocollective/go-capstone-starter/internal/foodentries"
+	"github.com/coloradocollective/go-capstone-starter/internal/foodimport"
+	"github.com/coloradocollective/go-capstone-starter/internal/profile"
+	"github.com/joho/godotenv"
+	_ "github.com/lib/pq"
+)
+
+func main() {
+	file := flag.String("file", "", "MyFitnessPal or Cronometer CSV export to import")
+	userID := flag.Int("user", 0, "user ID to import the entries for")
+	format := flag.String("format", "", `"myfitnesspal" or "cronometer"; detected from the header when empty`)
+	timeZone := flag.String("tz", "", "IANA time zone of the export's dates; defaults to the user's")
+	commit := flag.Bool("commit", false, "create the entries; without it the import is only previewed")
+	flag.Parse()
+
+	if *file == "" || *userID == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	_ = godotenv.Load()
+
+	f, err := os.Open(*file)
+	if err != nil {
+		log.Fatalf("Failed to open %s: %v", *file, err)
+	}
+	defer f.Close()
+
+	db, err := sql.Open("postgres", os.Getenv("DATABASE_URL"))
+	if err != nil {
+		log.Fatal("Failed to connect to DB:", err)
+	}
+	defer db.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	loc, err := location(ctx, profile.NewPostgresStore(db), *userID, *timeZone)
+	if err != nil {
+		log.Fatalf("Failed to determine the time zone: %v", err)
+	}
+	entries := foodentries.NewPostgresStore(db, foodentries.EventsTopic())
+	result, err := Import(ctx, entries, f, *format, *userID, loc, *commit)
+	printResult(os.Stdout, result)
+	if err != nil {
+		log.Fatalf("Import stopped: %v", err)
+	}
+}
+
+func location(ctx context.Context, locations profile.Locations, userID int, name string) (*time.Location, error) {
+	if name != "" {
+		return profile.LoadTimeZone(name)
+	}
+	return locations.Location(ctx, userID)
+}
+
+// Import parses an export, marks duplicates and, when commit is set, creates the
+// remaining valid entries. The result is returned even when the commit stops
+// part way.
+func Import(ctx context.Context, entries foodimport.Entries, r io.Reader, format string, userID int, loc *time.Location, commit bool) (foodimport.Result, error) {
+	format, rows, err := foodimport.Parse(r, format, loc)
+	if err != nil {
+		return foodimport.Result{}, err
+	}
+	if err := foodimport.Prepare(ctx, entries, userID, rows); err != nil {
+		return foodimport.Result{}, err
+	}
+	result := foodimport.Summarize(format, rows)
+	if commit {
+		result.Committed = true
+		result.Imported, err = foodimport.Commit(ctx, entries, rows)
+	}
+	return result, err
+}
+
+func printResult(out io.Writer, r foodimport.Result) {
+	if r.Format == "" {
+		return
+	}
+	fmt.Fprintf(out, "%s export: %d rows, %d valid, %d invalid, %d duplicates\n", r.Format, r.Rows, r.Valid, r.Invalid, r.Duplicates)
+	for _, row := range r.Preview {
+		e := row.Entry
+		fmt.Fprintf(out, "  line %d: %s %s %q %d kcal\n", row.Line, e.ConsumedAt.Format("2006-01-02 15:04"), e.MealType, e.Name, e.Calories)
+	}
+	if r.Valid > len(r.Preview) {
+		fmt.Fprintf(out, "  ... and %d more\n", r.Valid-len(r.Preview))
+	}
+	for _, row := range r.Problems {
+		if row.Duplicate {
+			fmt.Fprintf(out, "  line %d: duplicate, skipped\n", row.Line)
+		} else {
+			fmt.Fprintf(out, "  line %d: %s\n", row.Line, row.Error)
+		}
+	}
+	if r.Committed {
+		fmt.Fprintf(out, "Imported %d entries\n", r.Imported)
+	} else {
+		fmt.Fprintln(out, "Preview only; run again with -commit to import")
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coloradocollective/go-capstone-starter/internal/foodentries"
+)
+
+type memoryEntries struct {
+	created []foodentries.Entry
+}
+
+func (m *memoryEntries) Create(_ context.Context, e foodentries.Entry) (foodentries.Entry, error) {
+	m.created = append(m.created, e)
+	return e, nil
+}
+
+func (m *memoryEntries) Each(context.Context, foodentries.ExportQuery, func(foodentries.Entry) error) error {
+	return nil
+}
+
+const export = `Date,Meal,Calories,Fat (g),Carbohydrates (g),Fiber,Sugar,Protein (g),Note
+2024-05-01,Lunch,600,20,70,6,8,35,
+2024-05-01,Lunch,600,20,70,6,8,35,
+2024-05-02,Dinner,-5,0,0,0,0,0,
+`
+
+func TestImport(t *testing.T) {
+	store := &memoryEntries{}
+	result, err := Import(context.Background(), store, strings.NewReader(export), "", 4, time.UTC, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Valid != 1 || result.Duplicates != 1 || result.Invalid != 1 || len(store.created) != 0 {
+		t.Fatalf("unexpected preview %+v", result)
+	}
+	var out bytes.Buffer
+	printResult(&out, result)
+	for _, want := range []string{"line 3: duplicate", "line 4: portion and nutrient values must not be negative", "run again with -commit"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected %q in:\n%s", want, out.String())
+		}
+	}
+
+	result, err = Import(context.Background(), store, strings.NewReader(export), "", 4, time.UTC, true)
+	if err != nil || result.Imported != 1 || len(store.created) != 1 {
+		t.Errorf("expected one entry to be imported, got %+v, %v", result, err)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"flag"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/coloradocollective/go-capstone-starter/internal/foods"
+	"github.com/joho/godotenv"
+	_ "github.com/lib/pq"
+)
+
+func main() {
+	file := flag.String("file", "", "Open Food Facts CSV or JSONL dump, optionally gzipped")
+	format := flag.String("format", "", `"csv" or "jsonl"; detected from the file name when empty`)
+	batch := flag.Int("batch", foods.DefaultBatchSize, "products to upsert per statement")
+	flag.Parse()
+
+	if *file == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	_ = godotenv.Load()
+
+	f, err := os.Open(*file)
+	if err != nil {
+		log.Fatalf("Failed to open %s: %v", *file, err)
+	}
+	defer f.Close()
+
+	r, dumpFormat, err := Open(f, *file, *format)
+	if err != nil {
+		log.Fatalf("Failed to read %s: %v", *file, err)
+	}
+
+	db, err := sql.Open("postgres", os.Getenv("DATABASE_URL"))
+	if err != nil {
+		log.Fatal("Failed to connect to DB:", err)
+	}
+	defer db.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	stats, err := foods.LoadOpenFoodFacts(ctx, r, dumpFormat, foods.NewPostgresStore(db), *batch, func(s foods.LoadStats) {
+		log.Printf("Read %d products, loaded %d, left out %d older versions, skipped %d", s.Read, s.Loaded, s.Stale, s.Skipped)
+	})
+	log.Printf("Done: read %d products, loaded %d, left out %d older versions, skipped %d", stats.Read, stats.Loaded, stats.Stale, stats.Skipped)
+	if err != nil {
+		log.Fatalf("Import stopped: %v", err)
+	}
+}
+
+// Open unwraps a gzipped dump and works out its format from the file name when
+// none is given.
+func Open(r io.Reader, name, format string) (io.Reader, string, error) {
+	name = strings.ToLower(name)
+	if strings.HasSuffix(name, ".gz") {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, "", err
+		}
+		r = gz
+		name = strings.TrimSuffix(name, ".gz")
+	}
+	if format == "" {
+		format = foods.DumpCSV
+		if strings.HasSuffix(name, ".jsonl") || strings.HasSuffix(name, ".json") {
+			format = foods.DumpJSONL
+		}
+	}
+	return r, format, nil
+}
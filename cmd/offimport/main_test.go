@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"io"
+	"testing"
+
+	"github.com/coloradocollective/go-capstone-starter/internal/foods"
+)
+
+type products map[string]foods.Product
+
+func (p products) Product(_ context.Context, barcode string) (foods.Product, error) {
+	if product, ok := p[barcode]; ok {
+		return product, nil
+	}
+	return foods.Product{}, sql.ErrNoRows
+}
+
+func (p products) Match(context.Context, string) (foods.Product, error) {
+	return foods.Product{}, sql.ErrNoRows
+}
+
+func (p products) Upsert(_ context.Context, batch []foods.Product) (int, error) {
+	for _, product := range batch {
+		p[product.Barcode] = product
+	}
+	return len(batch), nil
+}
+
+func TestOpen_GzippedJSONL(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	io.WriteString(gz, `{"code":"3017620422003","product_name":"Nutella","nutriments":{"energy-kcal_100g":539}}`+"\n")
+	gz.Close()
+
+	r, format, err := Open(&buf, "openfoodfacts-products.JSONL.gz", "")
+	if err != nil || format != foods.DumpJSONL {
+		t.Fatalf("expected a jsonl reader, got %q, %v", format, err)
+	}
+	store := products{}
+	stats, err := foods.LoadOpenFoodFacts(context.Background(), r, format, store, 10, nil)
+	if err != nil || stats.Loaded != 1 || store["3017620422003"].Name != "Nutella" {
+		t.Errorf("unexpected load %+v, %v", stats, err)
+	}
+}
+
+func TestOpen_DefaultsToCSV(t *testing.T) {
+	if _, format, _ := Open(bytes.NewReader(nil), "en.openfoodfacts.org.products.csv", ""); format != foods.DumpCSV {
+		t.Errorf("expected csv, got %q", format)
+	}
+	if _, format, _ := Open(bytes.NewReader(nil), "dump.txt", foods.DumpJSONL); format != foods.DumpJSONL {
+		t.Errorf("expected the explicit format to win, got %q", format)
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"sort"
+	"time"
+
+	"github.com/coloradocollective/go-capstone-starter/internal/events"
+	"github.com/coloradocollective/go-capstone-starter/internal/foodentries"
+	"github.com/coloradocollective/go-capstone-starter/internal/messaging"
+	"github.com/joho/godotenv"
+	_ "github.com/lib/pq"
+)
+
+func main() {
+	source := flag.String("source", "outbox", `where events come from: "outbox" re-emits stored events, "synthesize" builds them from food_entries and recipes`)
+	from := flag.String("from", "", "only events at or after this date or RFC 3339 time")
+	to := flag.String("to", "", "only events before this date or RFC 3339 time")
+	userID := flag.Int("user", 0, "only events about this user ID")
+	types := flag.String("types", "", "comma-separated event types to include, e.g. food_entry.created")
+	topic := flag.String("topic", "", "publish everything to this topic instead of each event's own, e.g. to bootstrap one consumer")
+	dryRun := flag.Bool("dry-run", false, "list the events that would be published without publishing them")
+	flag.Parse()
+
+	_ = godotenv.Load()
+
+	filter := Filter{UserID: *userID, Types: parseTypes(*types)}
+	var err error
+	if filter.From, err = parseTime(*from); err != nil {
+		log.Fatalf("Invalid -from: %v", err)
+	}
+	if filter.To, err = parseTime(*to); err != nil {
+		log.Fatalf("Invalid -to: %v", err)
+	}
+
+	db, err := sql.Open("postgres", os.Getenv("DATABASE_URL"))
+	if err != nil {
+		log.Fatal("Failed to connect to DB:", err)
+	}
+	defer db.Close()
+
+	var src Source
+	switch *source {
+	case "outbox":
+		src = OutboxSource{db: db}
+	case "synthesize":
+		src = SynthesizedSource{
+			db:          db,
+			entryTopic:  envOrDefault("FOOD_ENTRY_EVENTS_TOPIC", foodentries.DefaultEventsTopic),
+			recipeTopic: envOrDefault("RECIPE_EVENTS_TOPIC", "recipe-events"),
+		}
+	default:
+		log.Fatalf("Unknown -source %q", *source)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var publisher messaging.Publisher
+	if !*dryRun {
+		broker, err := messaging.NewFromEnv(ctx)
+		if err != nil {
+			log.Fatalf("Failed to connect to the message broker: %v", err)
+		}
+		defer broker.Close()
+		if _, local := broker.(*messaging.MemoryBroker); local {
+			log.Fatal("Replaying into the in-memory broker would discard every event; set MESSAGING_BACKEND or use -dry-run")
+		}
+		publisher = broker
+	}
+
+	counts, err := Replay(ctx, src, filter, *topic, publisher, os.Stdout)
+	printSummary(os.Stdout, counts, *dryRun)
+	if err != nil {
+		log.Fatalf("Replay stopped: %v", err)
+	}
+}
+
+// Replay emits every event from src that matches filter, in source order, so
+// per-user ordering is kept. topic overrides each event's own topic when set.
+// With a nil publisher it only lists the events. It returns the number of events
+// per type.
+func Replay(ctx context.Context, src Source, filter Filter, topic string, publisher messaging.Publisher, out io.Writer) (map[string]int, error) {
+	counts := make(map[string]int)
+	err := src.Events(ctx, filter, func(item Item) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if topic != "" {
+			item.Topic = topic
+		}
+
+		env := item.Envelope
+		if publisher == nil {
+			user := "-"
+			if env.User != nil {
+				user = fmt.Sprint(*env.User)
+			}
+			fmt.Fprintf(out, "%s %s %s v%d user=%s id=%s\n", env.Timestamp.Format(time.RFC3339), item.Topic, env.Type, env.Version, user, env.ID)
+		} else if err := events.Publish(ctx, publisher, item.Topic, env); err != nil {
+			return fmt.Errorf("failed to publish %s: %w", env.ID, err)
+		}
+		counts[env.Type]++
+		return nil
+	})
+	return counts, err
+}
+
+func printSummary(out io.Writer, counts map[string]int, dryRun bool) {
+	verb := "Published"
+	if dryRun {
+		verb = "Would publish"
+	}
+	types := make([]string, 0, len(counts))
+	total := 0
+	for t, n := range counts {
+		types = append(types, t)
+		total += n
+	}
+	sort.Strings(types)
+	fmt.Fprintf(out, "%s %d events\n", verb, total)
+	for _, t := range types {
+		fmt.Fprintf(out, "  %s: %d\n", t, counts[t])
+	}
+}
+
+// parseTime accepts a date (taken as midnight UTC) or an RFC 3339 time; empty means unbounded.
+func parseTime(raw string) (time.Time, error) {
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.DateOnly, raw); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, raw)
+}
+
+func envOrDefault(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coloradocollective/go-capstone-starter/internal/events"
+	"github.com/coloradocollective/go-capstone-starter/internal/foodentries"
+	"github.com/coloradocollective/go-capstone-starter/internal/messaging"
+)
+
+type fakeSource struct {
+	items []Item
+}
+
+func (s fakeSource) Events(_ context.Context, filter Filter, emit func(Item) error) error {
+	for _, item := range s.items {
+		if !filter.wantsType(item.Envelope.Type) {
+			continue
+		}
+		if err := emit(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func testEntry(id, userID int) foodentries.Entry {
+	created := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC).Add(time.Duration(id) * time.Hour)
+	return foodentries.Entry{ID: id, UserID: userID, Name: "Oatmeal", Portion: 1, Unit: "bowl", MealType: "breakfast",
+		ConsumedAt: created, CreatedAt: created, UpdatedAt: created}
+}
+
+func newTestSource(t *testing.T) fakeSource {
+	t.Helper()
+	var src fakeSource
+	for _, entry := range []foodentries.Entry{testEntry(1, 4), testEntry(2, 4), testEntry(3, 5)} {
+		env, err := SynthesizeEntryEvent(entry)
+		if err != nil {
+			t.Fatalf("failed to synthesize: %v", err)
+		}
+		src.items = append(src.items, Item{Topic: "food-entry-events", Envelope: env})
+	}
+	recipe, err := SynthesizeRecipeEvent(12, "oat-bars")
+	if err != nil {
+		t.Fatalf("failed to synthesize: %v", err)
+	}
+	src.items = append(src.items, Item{Topic: "recipe-events", Envelope: recipe})
+	return src
+}
+
+func TestSynthesizeEntryEvent(t *testing.T) {
+	env, err := SynthesizeEntryEvent(testEntry(7, 4))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	again, _ := SynthesizeEntryEvent(testEntry(7, 4))
+	if env.ID != "backfill-food_entry.created-7" || again.ID != env.ID {
+		t.Errorf("expected a stable ID, got %q and %q", env.ID, again.ID)
+	}
+	if !env.Timestamp.Equal(testEntry(7, 4).CreatedAt) || env.User == nil || *env.User != 4 {
+		t.Errorf("unexpected envelope: %+v", env)
+	}
+	raw, err := events.Encode(env)
+	if err != nil {
+		t.Fatalf("synthesized event does not match its schema: %v", err)
+	}
+	if _, err := events.Decode(raw); err != nil {
+		t.Fatalf("synthesized event does not decode: %v", err)
+	}
+}
+
+func TestSynthesizeEntryEvent_KeepsTheRecipe(t *testing.T) {
+	entry := testEntry(7, 4)
+	recipeID := 9
+	entry.RecipeID = &recipeID
+	env, err := SynthesizeEntryEvent(entry)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	raw, err := events.Encode(env)
+	if err != nil {
+		t.Fatalf("synthesized event does not match its schema: %v", err)
+	}
+	if !bytes.Contains(raw, []byte(`"recipe_id":9`)) {
+		t.Errorf("expected the entry to keep its recipe, got %s", raw)
+	}
+}
+
+func TestReplay_DryRunListsWithoutPublishing(t *testing.T) {
+	var out bytes.Buffer
+	counts, err := Replay(context.Background(), newTestSource(t), Filter{Types: []string{foodentries.EntryCreated}}, "", nil, &out)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if counts[foodentries.EntryCreated] != 3 || len(counts) != 1 {
+		t.Fatalf("unexpected counts: %v", counts)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[0], "food-entry-events food_entry.created v1 user=4 id=backfill-food_entry.created-1") {
+		t.Fatalf("unexpected listing:\n%s", out.String())
+	}
+}
+
+func TestReplay_PublishesInOrderToTheOverrideTopic(t *testing.T) {
+	broker := messaging.NewMemoryBroker()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mu sync.Mutex
+	var received []string
+	go broker.Subscribe(ctx, "bootstrap", "new-consumer", events.Handler(func(_ context.Context, env events.Envelope) error {
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, env.ID)
+		return nil
+	}))
+	time.Sleep(10 * time.Millisecond)
+
+	counts, err := Replay(ctx, newTestSource(t), Filter{}, "bootstrap", broker, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	broker.Drain()
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{"backfill-food_entry.created-1", "backfill-food_entry.created-2", "backfill-food_entry.created-3", "backfill-recipe.ingested-12"}
+	if len(received) != len(want) || counts[foodentries.EntryCreated] != 3 || counts[recipeIngestedEvent] != 1 {
+		t.Fatalf("expected %v, got %v (counts %v)", want, received, counts)
+	}
+	for i := range want {
+		if received[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, received)
+		}
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	if got, err := parseTime("2024-05-01"); err != nil || !got.Equal(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected date: %v, %v", got, err)
+	}
+	if got, err := parseTime("2024-05-01T08:30:00+02:00"); err != nil || got.Hour() != 8 {
+		t.Errorf("unexpected time: %v, %v", got, err)
+	}
+	if got, err := parseTime(""); err != nil || !got.IsZero() {
+		t.Errorf("expected no bound, got %v, %v", got, err)
+	}
+	if _, err := parseTime("last week"); err == nil {
+		t.Error("expected an error")
+	}
+}
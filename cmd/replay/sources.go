@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/coloradocollective/go-capstone-starter/internal/events"
+	"github.com/coloradocollective/go-capstone-starter/internal/foodentries"
+	"github.com/coloradocollective/go-capstone-starter/internal/outbox"
+)
+
+// Filter narrows the events to replay. Zero values do not filter.
+type Filter struct {
+	From   time.Time
+	To     time.Time
+	UserID int
+	Types  []string
+}
+
+func (f Filter) wantsType(eventType string) bool {
+	if len(f.Types) == 0 {
+		return true
+	}
+	for _, t := range f.Types {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+func (f Filter) hasTimeRange() bool {
+	return !f.From.IsZero() || !f.To.IsZero()
+}
+
+// Item is one event to re-emit.
+type Item struct {
+	Topic    string
+	Envelope events.Envelope
+}
+
+// Source produces historical events in the order they should be published.
+type Source interface {
+	Events(ctx context.Context, filter Filter, emit func(Item) error) error
+}
+
+// timeBounds turns the filter's range into query arguments; an unset end is unbounded.
+func timeBounds(filter Filter) (any, any) {
+	var from, to any
+	if !filter.From.IsZero() {
+		from = filter.From
+	}
+	if !filter.To.IsZero() {
+		to = filter.To
+	}
+	return from, to
+}
+
+// OutboxSource re-emits the events stored in the outbox, delivered or not, in
+// the order they were written. Payloads are republished unchanged, keeping their
+// event IDs, so consumers that deduplicate see each event once.
+type OutboxSource struct {
+	db *sql.DB
+}
+
+func (s OutboxSource) Events(ctx context.Context, filter Filter, emit func(Item) error) error {
+	from, to := timeBounds(filter)
+	var orderingKey any
+	if filter.UserID != 0 {
+		orderingKey = outbox.UserKey(filter.UserID)
+	}
+	rows, err := s.db.QueryContext(ctx, `
+	SELECT id, topic, payload
+	FROM outbox
+	WHERE ($1::timestamptz IS NULL OR created_at >= $1)
+		AND ($2::timestamptz IS NULL OR created_at < $2)
+		AND ($3::text IS NULL OR ordering_key = $3)
+	ORDER BY id;
+	`, from, to, orderingKey)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var topic string
+		var payload []byte
+		if err := rows.Scan(&id, &topic, &payload); err != nil {
+			return err
+		}
+		env, err := events.Decode(payload)
+		if err != nil {
+			return fmt.Errorf("outbox row %d: %w", id, err)
+		}
+		if !filter.wantsType(env.Type) {
+			continue
+		}
+		if err := emit(Item{Topic: topic, Envelope: env}); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// SynthesizedSource builds events from the current contents of food_entries and
+// recipes, for history written before the outbox existed. Each entry becomes a
+// food_entry.created event stamped with its created_at, and each recipe a
+// recipe.ingested event. Event IDs are derived from the row, so running a
+// backfill twice produces the same IDs.
+type SynthesizedSource struct {
+	db          *sql.DB
+	entryTopic  string
+	recipeTopic string
+}
+
+func (s SynthesizedSource) Events(ctx context.Context, filter Filter, emit func(Item) error) error {
+	if filter.wantsType(foodentries.EntryCreated) {
+		if err := s.entryEvents(ctx, filter, emit); err != nil {
+			return err
+		}
+	}
+	// Recipes belong to no user and have no ingestion time, so user and time filters exclude them.
+	if filter.wantsType(recipeIngestedEvent) && filter.UserID == 0 && !filter.hasTimeRange() {
+		return s.recipeEvents(ctx, emit)
+	}
+	return nil
+}
+
+func (s SynthesizedSource) entryEvents(ctx context.Context, filter Filter, emit func(Item) error) error {
+	from, to := timeBounds(filter)
+	var userID any
+	if filter.UserID != 0 {
+		userID = filter.UserID
+	}
+	rows, err := s.db.QueryContext(ctx, `
+	SELECT id, user_id, name, portion, unit, calories, protein, carbohydrates, fat, fiber, sugar, meal_type,
+		consumed_at, recipe_id, COALESCE(created_at, consumed_at), COALESCE(updated_at, created_at, consumed_at)
+	FROM food_entries
+	WHERE ($1::timestamptz IS NULL OR COALESCE(created_at, consumed_at) >= $1)
+		AND ($2::timestamptz IS NULL OR COALESCE(created_at, consumed_at) < $2)
+		AND ($3::int IS NULL OR user_id = $3)
+	ORDER BY user_id, COALESCE(created_at, consumed_at), id;
+	`, from, to, userID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e foodentries.Entry
+		var recipeID sql.NullInt64
+		err := rows.Scan(&e.ID, &e.UserID, &e.Name, &e.Portion, &e.Unit, &e.Calories, &e.Protein, &e.Carbohydrates,
+			&e.Fat, &e.Fiber, &e.Sugar, &e.MealType, &e.ConsumedAt, &recipeID, &e.CreatedAt, &e.UpdatedAt)
+		if err != nil {
+			return err
+		}
+		if recipeID.Valid {
+			id := int(recipeID.Int64)
+			e.RecipeID = &id
+		}
+		env, err := SynthesizeEntryEvent(e)
+		if err != nil {
+			return fmt.Errorf("food entry %d: %w", e.ID, err)
+		}
+		if err := emit(Item{Topic: s.entryTopic, Envelope: env}); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (s SynthesizedSource) recipeEvents(ctx context.Context, emit func(Item) error) error {
+	rows, err := s.db.QueryContext(ctx, `SELECT id, slug FROM recipes ORDER BY id`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		var slug string
+		if err := rows.Scan(&id, &slug); err != nil {
+			return err
+		}
+		env, err := SynthesizeRecipeEvent(id, slug)
+		if err != nil {
+			return fmt.Errorf("recipe %d: %w", id, err)
+		}
+		if err := emit(Item{Topic: s.recipeTopic, Envelope: env}); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+const recipeIngestedEvent = "recipe.ingested"
+
+// SynthesizeEntryEvent describes an existing entry as the event that created it.
+func SynthesizeEntryEvent(entry foodentries.Entry) (events.Envelope, error) {
+	env, err := events.New(foodentries.EntryCreated, foodentries.EventVersion, entry.UserID,
+		foodentries.EventData{EntryID: entry.ID, Entry: &entry})
+	if err != nil {
+		return events.Envelope{}, err
+	}
+	env.ID = "backfill-" + foodentries.EntryCreated + "-" + strconv.Itoa(entry.ID)
+	env.Timestamp = entry.CreatedAt.UTC()
+	return env, nil
+}
+
+// SynthesizeRecipeEvent describes an existing recipe as the event that ingested it.
+func SynthesizeRecipeEvent(recipeID int, slug string) (events.Envelope, error) {
+	env, err := events.New(recipeIngestedEvent, 1, 0, map[string]any{"recipe_id": recipeID, "slug": slug})
+	if err != nil {
+		return events.Envelope{}, err
+	}
+	env.ID = "backfill-" + recipeIngestedEvent + "-" + strconv.Itoa(recipeID)
+	return env, nil
+}
+
+// parseTypes splits a comma-separated -types flag.
+func parseTypes(raw string) []string {
+	var types []string
+	for _, t := range strings.Split(raw, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			types = append(types, t)
+		}
+	}
+	return types
+}
@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS analysis_runs (
+    id SERIAL PRIMARY KEY,
+    model TEXT NOT NULL,
+    prompt_price_per_1k NUMERIC(12, 6) NOT NULL DEFAULT 0,     -- USD per 1K prompt tokens
+    completion_price_per_1k NUMERIC(12, 6) NOT NULL DEFAULT 0, -- USD per 1K completion tokens
+    run_budget_usd NUMERIC(12, 6),                              -- NULL means unlimited
+    daily_budget_usd NUMERIC(12, 6),                            -- NULL means unlimited
+    status TEXT NOT NULL DEFAULT 'running',                     -- running, completed, budget_exhausted
+    calls INT NOT NULL DEFAULT 0,
+    prompt_tokens INT NOT NULL DEFAULT 0,
+    completion_tokens INT NOT NULL DEFAULT 0,
+    cost_usd NUMERIC(12, 6) NOT NULL DEFAULT 0,
+    started_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    finished_at TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS analysis_run_calls (
+    id SERIAL PRIMARY KEY,
+    run_id INT NOT NULL REFERENCES analysis_runs(id) ON DELETE CASCADE,
+    recipe_id INT NOT NULL,
+    model TEXT NOT NULL,
+    prompt_tokens INT NOT NULL,
+    completion_tokens INT NOT NULL,
+    cost_usd NUMERIC(12, 6) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS analysis_run_calls_created_at_idx ON analysis_run_calls (created_at);
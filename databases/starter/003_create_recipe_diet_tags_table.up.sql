@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS recipe_diet_tags (
+    recipe_id INT NOT NULL,
+    tag TEXT NOT NULL,            -- e.g., vegetarian, vegan, gluten-free, low-carb
+    rule TEXT NOT NULL,           -- Name of the rule that assigned the tag
+    detail TEXT,                  -- Why the rule fired
+    model_verified BOOLEAN,       -- NULL when the model check is disabled
+    classified_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (recipe_id, tag)
+);
+
+CREATE INDEX IF NOT EXISTS recipe_diet_tags_tag_idx ON recipe_diet_tags (tag);
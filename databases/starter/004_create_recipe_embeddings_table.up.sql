@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS recipe_embeddings (
+    recipe_id INT PRIMARY KEY,
+    model TEXT NOT NULL,          -- Embedding model; only vectors from the same model are compared
+    dimensions INT NOT NULL,
+    embedding FLOAT8[] NOT NULL,
+    input_hash TEXT NOT NULL,     -- SHA-256 of the embedded text, used to skip unchanged recipes
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS recipe_nutrition_estimates (
+    id SERIAL PRIMARY KEY,
+    run_id INT REFERENCES analysis_runs(id) ON DELETE SET NULL,
+    recipe_id INT NOT NULL,
+    backend TEXT NOT NULL,        -- model@base_url that produced the estimate
+    model TEXT NOT NULL,
+    calories INT,                 -- NULL when the backend call failed
+    protein INT,
+    fat INT,
+    carbohydrates INT,
+    raw_response TEXT,
+    error TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS recipe_nutrition_estimates_recipe_id_idx ON recipe_nutrition_estimates (recipe_id);
+
+CREATE TABLE IF NOT EXISTS recipe_nutrition_flags (
+    id SERIAL PRIMARY KEY,
+    recipe_id INT NOT NULL,
+    run_id INT REFERENCES analysis_runs(id) ON DELETE SET NULL,
+    reason TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'open', -- open, resolved
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS recipe_nutrition_flags_status_idx ON recipe_nutrition_flags (status);
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS recipe_nutrition (
+    recipe_id INT PRIMARY KEY,
+    calories INT,
+    protein INT,
+    fat INT,
+    carbohydrates INT
+);
+
+ALTER TABLE recipe_nutrition
+    ADD COLUMN IF NOT EXISTS source TEXT NOT NULL DEFAULT 'unknown', -- blog, usda, model, ensemble
+    ADD COLUMN IF NOT EXISTS estimator TEXT,                         -- Model(s) or lookup that produced the values
+    ADD COLUMN IF NOT EXISTS prompt_version TEXT,                    -- NULL for non-model sources
+    ADD COLUMN IF NOT EXISTS input_fingerprint TEXT,                 -- SHA-256 of the ingredient list used
+    ADD COLUMN IF NOT EXISTS estimated_at TIMESTAMPTZ;
@@ -0,0 +1,10 @@
+ALTER TABLE recipe_nutrition
+    ADD COLUMN IF NOT EXISTS locked BOOLEAN NOT NULL DEFAULT FALSE; -- Locked values are never overwritten by the analyzer
+
+ALTER TABLE recipe_nutrition_flags
+    ADD COLUMN IF NOT EXISTS reviewed_by TEXT,
+    ADD COLUMN IF NOT EXISTS review_note TEXT,
+    ADD COLUMN IF NOT EXISTS reviewed_at TIMESTAMPTZ;
+
+-- Flags are now review queue items: open, approved, edited, rejected
+UPDATE recipe_nutrition_flags SET status = 'approved' WHERE status = 'resolved';
@@ -0,0 +1,4 @@
+-- Batched model calls cover several recipes; they record recipe_ids instead of recipe_id.
+ALTER TABLE analysis_run_calls
+    ALTER COLUMN recipe_id DROP NOT NULL,
+    ADD COLUMN IF NOT EXISTS recipe_ids INT[];
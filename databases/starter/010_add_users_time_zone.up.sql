@@ -0,0 +1,2 @@
+-- IANA time zone, e.g. "America/Denver", in which a user's days start and end.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS time_zone TEXT NOT NULL DEFAULT 'UTC';
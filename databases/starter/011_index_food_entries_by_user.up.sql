@@ -0,0 +1,3 @@
+-- Serves the default listing order (newest first) and keyset pagination on it,
+-- as well as the date-range scans of rollups.
+CREATE INDEX IF NOT EXISTS food_entries_user_consumed_at_idx ON food_entries (user_id, consumed_at DESC, id DESC);
@@ -0,0 +1,15 @@
+-- Daily targets, or per-meal targets when meal_type is set. A goal applies from
+-- effective_from until the next goal for the same user and meal type.
+CREATE TABLE IF NOT EXISTS nutrition_goals (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    meal_type VARCHAR(20) NOT NULL DEFAULT '', -- '' for the whole day
+    effective_from DATE NOT NULL,
+    calories INT NOT NULL,
+    protein INT,                               -- NULL when the user has no target for it
+    carbohydrates INT,
+    fat INT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, meal_type, effective_from)
+);
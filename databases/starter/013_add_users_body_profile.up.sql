@@ -0,0 +1,8 @@
+-- Body data for energy estimates; every column is optional.
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS sex TEXT,                          -- female or male, as used by the BMR equations
+    ADD COLUMN IF NOT EXISTS birth_date DATE,
+    ADD COLUMN IF NOT EXISTS height_cm DOUBLE PRECISION,
+    ADD COLUMN IF NOT EXISTS weight_kg DOUBLE PRECISION,
+    ADD COLUMN IF NOT EXISTS body_fat_percent DOUBLE PRECISION,
+    ADD COLUMN IF NOT EXISTS activity_level TEXT;              -- sedentary, light, moderate, active or very_active
@@ -0,0 +1,16 @@
+-- At most one set of measurements per user and day, in the user's time zone.
+CREATE TABLE IF NOT EXISTS body_measurements (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    measured_on DATE NOT NULL,
+    weight_kg DOUBLE PRECISION,
+    body_fat_percent DOUBLE PRECISION,
+    waist_cm DOUBLE PRECISION,
+    hips_cm DOUBLE PRECISION,
+    chest_cm DOUBLE PRECISION,
+    neck_cm DOUBLE PRECISION,
+    note TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, measured_on)
+);
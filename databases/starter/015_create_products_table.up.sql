@@ -0,0 +1,18 @@
+-- Packaged foods loaded from Open Food Facts dumps by cmd/offimport. Nutrients
+-- are per 100 g and NULL when the source does not give them.
+CREATE TABLE IF NOT EXISTS products (
+    barcode TEXT PRIMARY KEY,                 -- digits; 12-digit UPC-A codes are stored as EAN-13
+    name TEXT NOT NULL,
+    brand TEXT NOT NULL DEFAULT '',
+    serving_size TEXT NOT NULL DEFAULT '',    -- as labelled, e.g. "1 bar (40 g)"
+    serving_quantity_g DOUBLE PRECISION,
+    calories_100g DOUBLE PRECISION,
+    protein_100g DOUBLE PRECISION,
+    carbohydrates_100g DOUBLE PRECISION,
+    fat_100g DOUBLE PRECISION,
+    fiber_100g DOUBLE PRECISION,
+    sugar_100g DOUBLE PRECISION,
+    source TEXT NOT NULL DEFAULT 'openfoodfacts',
+    source_modified_at TIMESTAMPTZ,
+    imported_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
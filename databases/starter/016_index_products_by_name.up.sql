@@ -0,0 +1,5 @@
+-- Trigram index for the name searches of the local food lookup provider, which
+-- use ILIKE '%...%' and cannot use a b-tree.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS products_name_trgm_idx ON products USING gin (name gin_trgm_ops);
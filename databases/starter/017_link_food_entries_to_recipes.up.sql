@@ -0,0 +1,7 @@
+-- Entries logged from a recipe keep it, so corrections to its nutrition can be
+-- applied to them. portion is then the number of servings. The recipes table
+-- and its servings column belong to the collector's schema.
+ALTER TABLE food_entries
+    ADD COLUMN IF NOT EXISTS recipe_id INT;
+
+CREATE INDEX IF NOT EXISTS food_entries_recipe_id_idx ON food_entries (recipe_id) WHERE recipe_id IS NOT NULL;
@@ -0,0 +1,4 @@
+-- Similar-recipe requests check MAX(updated_at) per model to decide whether their
+-- in-memory copy of the embeddings is stale.
+CREATE INDEX IF NOT EXISTS recipe_embeddings_model_updated_at_idx
+    ON recipe_embeddings (model, updated_at);
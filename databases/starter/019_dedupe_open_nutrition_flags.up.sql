@@ -0,0 +1,9 @@
+-- Every analyzer run used to queue another open review for a recipe that was
+-- already waiting for one. Keep the oldest open review of each recipe.
+DELETE FROM recipe_nutrition_flags f
+USING recipe_nutrition_flags older
+WHERE f.status = 'open' AND older.status = 'open'
+    AND older.recipe_id = f.recipe_id AND older.id < f.id;
+
+CREATE UNIQUE INDEX IF NOT EXISTS recipe_nutrition_flags_open_recipe_idx
+    ON recipe_nutrition_flags (recipe_id) WHERE status = 'open';
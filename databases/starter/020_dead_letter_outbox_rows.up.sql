@@ -0,0 +1,8 @@
+-- Rows the relay gave up on after too many failed attempts; their key moves on.
+ALTER TABLE outbox
+    ADD COLUMN IF NOT EXISTS dead_lettered_at TIMESTAMPTZ;
+
+-- The relay reads the oldest waiting row of every ordering key.
+DROP INDEX IF EXISTS outbox_pending_idx;
+CREATE INDEX IF NOT EXISTS outbox_pending_key_idx
+    ON outbox (ordering_key, id) WHERE delivered_at IS NULL AND dead_lettered_at IS NULL;
@@ -0,0 +1,20 @@
+-- recipes is created by the collector, and only shares a database with
+-- food_entries in some deployments. Where it does, entries logged from a
+-- deleted recipe keep their values but lose the link.
+DO $$
+BEGIN
+    IF to_regclass('recipes') IS NULL THEN
+        RETURN;
+    END IF;
+
+    UPDATE food_entries e SET recipe_id = NULL
+    WHERE e.recipe_id IS NOT NULL
+      AND NOT EXISTS (SELECT 1 FROM recipes r WHERE r.id = e.recipe_id);
+
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'food_entries_recipe_id_fkey') THEN
+        ALTER TABLE food_entries
+            ADD CONSTRAINT food_entries_recipe_id_fkey
+            FOREIGN KEY (recipe_id) REFERENCES recipes (id) ON DELETE SET NULL;
+    END IF;
+END
+$$;
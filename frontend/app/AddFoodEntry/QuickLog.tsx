@@ -0,0 +1,181 @@
+import React, { useState } from "react";
+
+type Entry = {
+  name: string;
+  portion: number;
+  unit: string;
+  calories: number;
+  protein: number;
+  carbohydrates: number;
+  fat: number;
+  fiber: number;
+  sugar: number;
+  meal_type: string;
+  consumed_at: string;
+};
+
+type Draft = {
+  text: string;
+  food: string;
+  match?: string;
+  provider?: string;
+  note?: string;
+  error?: string;
+  entry?: Entry;
+};
+
+// QuickLog drafts entries from a description such as "2 eggs and toast for
+// breakfast" and creates the ones the user keeps in a single request.
+const QuickLog = ({ token }: { token: string | null }) => {
+  const [text, setText] = useState("");
+  const [drafts, setDrafts] = useState<Draft[]>([]);
+  const [keep, setKeep] = useState<boolean[]>([]);
+  const [loading, setLoading] = useState(false);
+
+  const handleParse = async () => {
+    if (!text.trim()) return;
+    setLoading(true);
+    try {
+      const response = await fetch("/api/food-entries/parse", {
+        method: "POST",
+        headers: {
+          "Content-Type": "application/json",
+          Authorization: `Bearer ${token}`,
+        },
+        body: JSON.stringify({ text }),
+      });
+      if (!response.ok) {
+        alert(await response.text());
+        return;
+      }
+      const data = await response.json();
+      setDrafts(data.items);
+      setKeep(data.items.map((d: Draft) => Boolean(d.entry)));
+    } catch (e) {
+      alert("Error reading your meal. Please try again.");
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  const setPortion = (i: number, grams: number) => {
+    setDrafts((prev) =>
+      prev.map((d, j) => {
+        if (j !== i || !d.entry || d.entry.portion === 0) return d;
+        const scale = grams / d.entry.portion;
+        const e = d.entry;
+        return {
+          ...d,
+          entry: {
+            ...e,
+            portion: grams,
+            calories: Math.round(e.calories * scale),
+            protein: Math.round(e.protein * scale),
+            carbohydrates: Math.round(e.carbohydrates * scale),
+            fat: Math.round(e.fat * scale),
+            fiber: Math.round(e.fiber * scale),
+            sugar: Math.round(e.sugar * scale),
+          },
+        };
+      })
+    );
+  };
+
+  const handleConfirm = async () => {
+    const entries = drafts
+      .filter((d, i) => keep[i] && d.entry)
+      .map((d) => d.entry);
+    if (entries.length === 0) return;
+    try {
+      const response = await fetch("/api/food-entries/batch", {
+        method: "POST",
+        headers: {
+          "Content-Type": "application/json",
+          Authorization: `Bearer ${token}`,
+        },
+        body: JSON.stringify({ entries }),
+      });
+      if (!response.ok) {
+        alert(await response.text());
+        return;
+      }
+      window.location.href = "/";
+    } catch (e) {
+      alert("Error saving entries. Please try again.");
+    }
+  };
+
+  return (
+    <div className="quick-log">
+      <div className="input-group">
+        <label>Describe your meal:</label>
+        <textarea
+          value={text}
+          placeholder="2 scrambled eggs, a slice of toast with butter and black coffee for breakfast"
+          onChange={(e) => setText(e.target.value)}
+        />
+        <button type="button" onClick={handleParse} disabled={loading}>
+          {loading ? "Reading..." : "Read Meal"}
+        </button>
+      </div>
+      {drafts.length > 0 && (
+        <>
+          <table className="quick-log-items">
+            <thead>
+              <tr>
+                <th>Log</th>
+                <th>Food</th>
+                <th>Grams</th>
+                <th>kcal</th>
+                <th>Meal</th>
+                <th>Notes</th>
+              </tr>
+            </thead>
+            <tbody>
+              {drafts.map((d, i) => (
+                <tr key={i}>
+                  <td>
+                    <input
+                      type="checkbox"
+                      checked={keep[i] ?? false}
+                      disabled={!d.entry}
+                      onChange={(e) =>
+                        setKeep((prev) =>
+                          prev.map((k, j) => (j === i ? e.target.checked : k))
+                        )
+                      }
+                    />
+                  </td>
+                  <td>
+                    {d.entry?.name ?? d.food}
+                    {d.match && <small> ({d.match})</small>}
+                  </td>
+                  <td>
+                    {d.entry && (
+                      <input
+                        type="number"
+                        min={0}
+                        value={d.entry.portion}
+                        onChange={(e) => setPortion(i, Number(e.target.value))}
+                      />
+                    )}
+                  </td>
+                  <td>{d.entry?.calories}</td>
+                  <td>{d.entry?.meal_type}</td>
+                  <td>{d.error ?? d.note}</td>
+                </tr>
+              ))}
+            </tbody>
+          </table>
+          <div className="button-group">
+            <button type="button" className="submit-btn" onClick={handleConfirm}>
+              Log Selected
+            </button>
+          </div>
+        </>
+      )}
+    </div>
+  );
+};
+
+export default QuickLog;
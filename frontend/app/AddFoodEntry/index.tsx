@@ -1,6 +1,7 @@
 import React, { useEffect, useState } from "react";
 import { useNavigate } from "react-router";
 import "./AddFoodEntry.css";
+import QuickLog from "./QuickLog";
 
 const getTodayDate = (): string => {
   const today = new Date();
@@ -41,6 +42,42 @@ const AddFoodEntry = () => {
     setFormData((prev) => ({ ...prev, [name]: value }));
   };
 
+  const [barcode, setBarcode] = useState("");
+
+  // Fills the form from a product looked up by barcode. The form logs grams, so
+  // a serving prefill is turned into the serving's weight.
+  const handleBarcodeLookup = async () => {
+    if (!barcode.trim()) return;
+    try {
+      const response = await fetch(
+        `/api/foods/barcode/${encodeURIComponent(barcode.trim())}`
+      );
+      if (!response.ok) {
+        alert(response.status === 404 ? "Product not found." : "Invalid barcode.");
+        return;
+      }
+      const food = await response.json();
+      const { entry } = food;
+      const grams =
+        entry.unit === "serving"
+          ? Math.round(food.serving_quantity_g * entry.portion)
+          : entry.portion;
+      setFormData((prev) => ({
+        ...prev,
+        name: entry.name,
+        portion: String(grams),
+        calories: String(entry.calories),
+        protein: String(entry.protein),
+        carbohydrates: String(entry.carbohydrates),
+        fat: String(entry.fat),
+        fiber: String(entry.fiber),
+        sugar: String(entry.sugar),
+      }));
+    } catch (e) {
+      alert("Error looking up barcode. Please try again.");
+    }
+  };
+
   const handleSubmit = async (e: React.FormEvent) => {
     e.preventDefault();
     const { date, time, ...rest } = formData;
@@ -131,7 +168,20 @@ const AddFoodEntry = () => {
     <div className="food-entry-page">
       <div className="form-container">
         <h2 className="form-title">🍽️ Add Food Entry</h2>
+        <QuickLog token={token} />
         <form onSubmit={handleSubmit} className="food-entry-form">
+          <div className="input-group">
+            <label>Barcode:</label>
+            <input
+              name="barcode"
+              inputMode="numeric"
+              value={barcode}
+              onChange={(e) => setBarcode(e.target.value)}
+            />
+            <button type="button" onClick={handleBarcodeLookup}>
+              Look Up
+            </button>
+          </div>
           <div className="input-group">
             <label>Food Name:</label>
             <input
@@ -1,7 +1,6 @@
 import React, { useEffect, useState } from "react";
 import { useNavigate } from "react-router";
 import dayjs from "dayjs";
-import utc from "dayjs/plugin/utc";
 import "./FoodEntryList.css";
 
 type FoodEntry = {
@@ -18,7 +17,6 @@ type FoodEntry = {
   meal_type: string;
   consumed_at: string;
 };
-dayjs.extend(utc);
 
 const FoodEntryList = () => {
   const [entries, setEntries] = useState<FoodEntry[]>([]);
@@ -78,7 +76,7 @@ const FoodEntryList = () => {
                 </p>
                 <p>
                   <strong>Consumed At:</strong>{" "}
-                  {dayjs(entry.consumed_at).utc().format("M/D/YYYY, h:mm A")}
+                  {dayjs(entry.consumed_at).format("M/D/YYYY, h:mm A")}
                 </p>
               </div>
             </div>
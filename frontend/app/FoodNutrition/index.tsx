@@ -11,17 +11,34 @@ import {
 } from "react-bootstrap";
 import "./FoodNutrition.css";
 
+type Nutrients = {
+  calories: number | null;
+  protein: number | null;
+  carbohydrates: number | null;
+  fat: number | null;
+  fiber: number | null;
+  sugar: number | null;
+};
+
 type FoodInfo = {
-  ProductName: string;
-  Calories: number;
-  Fat: number;
-  Carbohydrates: number;
-  Sugars: number;
-  Protein: number;
-  Fiber: number;
-  Image: string;
+  name: string;
+  brand?: string;
+  serving_size?: string;
+  per_100g: Nutrients;
+  per_serving: Nutrients | null;
+  image?: string;
+  provider: string;
+  cached: boolean;
+};
+
+const providerNames: Record<string, string> = {
+  local: "our food database",
+  openfoodfacts: "Open Food Facts",
+  nutritionix: "Nutritionix",
 };
 
+const amount = (value: number | null) => (value === null ? "–" : value);
+
 const Front = () => {
   const [input, setInput] = useState("");
   const [foodInfo, setFoodInfo] = useState<FoodInfo | null>(null);
@@ -32,15 +49,16 @@ const Front = () => {
     setLoading(true);
     try {
       const token = localStorage.getItem("token");
-      const res = await fetch("/api/food-info-nutritionix", {
-        method: "POST",
-        headers: {
-          "Content-Type": "application/json",
-          Authorization: `Bearer ${token}`,
-        },
-        body: JSON.stringify({ query: input }),
-      });
+      const res = await fetch(
+        `/api/foods/lookup?q=${encodeURIComponent(input.trim())}`,
+        { headers: { Authorization: `Bearer ${token}` } }
+      );
 
+      if (res.status === 404) {
+        setFoodInfo(null);
+        alert("No nutrition found for that food.");
+        return;
+      }
       if (!res.ok) {
         throw new Error("Failed to fetch food information");
       }
@@ -74,25 +92,44 @@ const Front = () => {
           <Col md={6} lg={4}>
             <Card className="food-card">
               <Card.Body>
-                <Card.Title>{foodInfo.ProductName}</Card.Title>
-                <img
-                  src={foodInfo.Image}
-                  alt={foodInfo.ProductName}
-                  className="img-fluid mb-3"
-                />
-                <Card.Text>
-                  <strong>Calories:</strong> {foodInfo.Calories} kcal
-                  <br />
-                  <strong>Protein:</strong> {foodInfo.Protein} g
-                  <br />
-                  <strong>Carbs:</strong> {foodInfo.Carbohydrates} g
-                  <br />
-                  <strong>Fat:</strong> {foodInfo.Fat} g
-                  <br />
-                  <strong>Fiber:</strong> {foodInfo.Fiber} g
-                  <br />
-                  <strong>Sugar:</strong> {foodInfo.Sugars} g
-                </Card.Text>
+                <Card.Title>
+                  {foodInfo.brand ? `${foodInfo.brand} ` : ""}
+                  {foodInfo.name}
+                </Card.Title>
+                {foodInfo.image && (
+                  <img
+                    src={foodInfo.image}
+                    alt={foodInfo.name}
+                    className="img-fluid mb-3"
+                  />
+                )}
+                <Card.Subtitle className="mb-2 text-muted">
+                  {foodInfo.per_serving
+                    ? `Per serving${foodInfo.serving_size ? ` (${foodInfo.serving_size})` : ""}`
+                    : "Per 100 g"}
+                </Card.Subtitle>
+                {(() => {
+                  const n = foodInfo.per_serving ?? foodInfo.per_100g;
+                  return (
+                    <Card.Text>
+                      <strong>Calories:</strong> {amount(n.calories)} kcal
+                      <br />
+                      <strong>Protein:</strong> {amount(n.protein)} g
+                      <br />
+                      <strong>Carbs:</strong> {amount(n.carbohydrates)} g
+                      <br />
+                      <strong>Fat:</strong> {amount(n.fat)} g
+                      <br />
+                      <strong>Fiber:</strong> {amount(n.fiber)} g
+                      <br />
+                      <strong>Sugar:</strong> {amount(n.sugar)} g
+                    </Card.Text>
+                  );
+                })()}
+                <small className="text-muted">
+                  Source: {providerNames[foodInfo.provider] ?? foodInfo.provider}
+                  {foodInfo.cached ? " (cached)" : ""}
+                </small>
               </Card.Body>
             </Card>
           </Col>
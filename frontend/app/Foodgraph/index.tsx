@@ -10,20 +10,18 @@ import {
   ResponsiveContainer,
 } from "recharts";
 import dayjs from "dayjs";
-import utc from "dayjs/plugin/utc";
 import "./FoodGraph.css"; // Importing the new CSS file for styling
 
-dayjs.extend(utc);
-
-interface FoodEntry {
-  food_name: string;
-  calories: number;
-  protein: number;
-  fat: number;
-  carbohydrates: number;
-  fiber: number;
-  sugar: number;
-  consumed_at: string;
+interface RollupBucket {
+  label: string;
+  totals: {
+    calories: number;
+    protein: number;
+    fat: number;
+    carbohydrates: number;
+    fiber: number;
+    sugar: number;
+  };
 }
 
 interface AggregatedData {
@@ -47,67 +45,30 @@ const FoodLineGraph = () => {
       }
 
       try {
-        const response = await fetch("/api/get-food-entry-user-details", {
-          headers: {
-            Authorization: `Bearer ${token}`,
-            "Content-Type": "application/json",
-          },
-        });
+        const now = dayjs();
+        const from = now.startOf("month").format("YYYY-MM-DD");
+        const to = now.endOf("month").format("YYYY-MM-DD");
+        const response = await fetch(
+          `/api/food-entries/rollup?period=day&from=${from}&to=${to}`,
+          {
+            headers: {
+              Authorization: `Bearer ${token}`,
+              "Content-Type": "application/json",
+            },
+          }
+        );
 
         if (!response.ok) {
           console.error("Failed to fetch food entries");
           return;
         }
 
-        const rawData: FoodEntry[] = await response.json();
-        const now = dayjs();
-        const startOfMonth = now.startOf("month").format("YYYY-MM-DD");
-        const endOfMonth = now.endOf("month").format("YYYY-MM-DD");
-
-        const dailyTotals: { [date: string]: AggregatedData } = {};
-
-        rawData.forEach((entry) => {
-          // Use utc() to avoid time zone conversion
-          const dateObj = dayjs.utc(entry.consumed_at).format("YYYY-MM-DD"); // Treat as UTC and only use date
-          if (dateObj < startOfMonth || dateObj > endOfMonth) return;
-
-          if (!dailyTotals[dateObj]) {
-            dailyTotals[dateObj] = {
-              date: dateObj,
-              calories: 0,
-              protein: 0,
-              fat: 0,
-              carbohydrates: 0,
-              fiber: 0,
-              sugar: 0,
-            };
-          }
-
-          dailyTotals[dateObj].calories += entry.calories;
-          dailyTotals[dateObj].protein += entry.protein;
-          dailyTotals[dateObj].fat += entry.fat;
-          dailyTotals[dateObj].carbohydrates += entry.carbohydrates;
-          dailyTotals[dateObj].fiber += entry.fiber;
-          dailyTotals[dateObj].sugar += entry.sugar;
-        });
-
-        // Fill missing days
-        const daysInMonth = dayjs(endOfMonth).date();
-        const fullData: AggregatedData[] = [];
-        for (let day = 1; day <= daysInMonth; day++) {
-          const date = dayjs(startOfMonth).date(day).format("YYYY-MM-DD");
-          fullData.push(
-            dailyTotals[date] || {
-              date,
-              calories: 0,
-              protein: 0,
-              fat: 0,
-              carbohydrates: 0,
-              fiber: 0,
-              sugar: 0,
-            }
-          );
-        }
+        // The server returns one bucket per day of the month, including empty days.
+        const rollup: { buckets: RollupBucket[] } = await response.json();
+        const fullData: AggregatedData[] = rollup.buckets.map((bucket) => ({
+          date: bucket.label,
+          ...bucket.totals,
+        }));
 
         setData(fullData);
       } catch (err) {
@@ -51,6 +51,18 @@ const Login = () => {
 
       const data = await response.json();
       localStorage.setItem("token", data.token);
+
+      // Days are counted in the user's time zone; keep it in step with the browser.
+      await fetch("/api/profile/time-zone", {
+        method: "PUT",
+        headers: {
+          Authorization: `Bearer ${data.token}`,
+          "Content-Type": "application/json",
+        },
+        body: JSON.stringify({
+          time_zone: Intl.DateTimeFormat().resolvedOptions().timeZone,
+        }),
+      }).catch((err) => console.error("Failed to save time zone:", err));
       window.location.href = "/";
     } catch (error) {
       console.error("Login error:", error);
@@ -75,6 +75,28 @@ const RecommendRecipes = () => {
   const [addedRecipes, setAddedRecipes] = useState<Set<string>>(new Set());
   const [isLoading, setIsLoading] = useState(false);
 
+  // Prefill what is left of today's goal, when the user has one.
+  useEffect(() => {
+    const token = localStorage.getItem("token");
+    if (!token) return;
+
+    fetch("/api/goals/progress", {
+      headers: { Authorization: `Bearer ${token}` },
+    })
+      .then((res) => (res.ok ? res.json() : null))
+      .then((progress) => {
+        const targets = progress?.daily?.targets;
+        if (!targets) return;
+        const remaining = (name: string) =>
+          targets[name] ? String(Math.max(targets[name].remaining, 0)) : "";
+        setCalories(remaining("calories"));
+        setProtein(remaining("protein"));
+        setFat(remaining("fat"));
+        setCarbohydrates(remaining("carbohydrates"));
+      })
+      .catch((err) => console.error("Failed to load goals:", err));
+  }, []);
+
   const handleRecommend = async () => {
     const token = localStorage.getItem("token");
     if (!token) {
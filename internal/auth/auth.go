@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ErrUnauthenticated is returned when a request carries no valid bearer token.
+var ErrUnauthenticated = errors.New("unauthenticated")
+
+// Authenticator resolves the user behind the "Authorization: Bearer <jwt>" header
+// the frontend sends. Tokens are HS256-signed with JWT_SECRET and name the user in
+// a "user_id" claim, falling back to "sub".
+type Authenticator struct {
+	secret []byte
+}
+
+func New(secret string) Authenticator {
+	return Authenticator{secret: []byte(secret)}
+}
+
+// FromEnv reads the signing secret from JWT_SECRET. Without a secret every request is rejected.
+func FromEnv() Authenticator {
+	return New(os.Getenv("JWT_SECRET"))
+}
+
+// UserID returns the authenticated user's ID.
+func (a Authenticator) UserID(r *http.Request) (int, error) {
+	raw, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !found || raw == "" || len(a.secret) == 0 {
+		return 0, ErrUnauthenticated
+	}
+
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(raw, claims, func(*jwt.Token) (any, error) {
+		return a.secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Name}))
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrUnauthenticated, err)
+	}
+
+	claim, ok := claims["user_id"]
+	if !ok {
+		claim = claims["sub"]
+	}
+	switch v := claim.(type) {
+	case float64:
+		if v > 0 && v == float64(int(v)) {
+			return int(v), nil
+		}
+	case string:
+		if id, err := strconv.Atoi(v); err == nil && id > 0 {
+			return id, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: token does not name a user", ErrUnauthenticated)
+}
+
+// Require wraps a handler that needs the user's ID and answers 401 without one.
+func (a Authenticator) Require(next func(w http.ResponseWriter, r *http.Request, userID int)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := a.UserID(r)
+		if err != nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r, userID)
+	}
+}
+
+// Token signs a token for userID. It exists for tests and local tooling; users get
+// their tokens from the login endpoint.
+func (a Authenticator) Token(userID int) (string, error) {
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"user_id": userID}).SignedString(a.secret)
+}
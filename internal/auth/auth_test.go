@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func requestWithToken(token string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	return r
+}
+
+func TestUserID(t *testing.T) {
+	a := New("secret")
+	token, _ := a.Token(42)
+	if id, err := a.UserID(requestWithToken(token)); err != nil || id != 42 {
+		t.Fatalf("expected user 42, got %d, %v", id, err)
+	}
+
+	sub, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "7"}).SignedString([]byte("secret"))
+	if id, err := a.UserID(requestWithToken(sub)); err != nil || id != 7 {
+		t.Fatalf("expected user 7 from sub, got %d, %v", id, err)
+	}
+}
+
+func TestUserID_Rejects(t *testing.T) {
+	a := New("secret")
+	forged, _ := New("other").Token(42)
+	anonymous, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"name": "x"}).SignedString([]byte("secret"))
+	unsigned, _ := jwt.NewWithClaims(jwt.SigningMethodNone, jwt.MapClaims{"user_id": 42}).SignedString(jwt.UnsafeAllowNoneSignatureType)
+
+	for name, token := range map[string]string{"missing": "", "forged": forged, "no user": anonymous, "unsigned": unsigned, "garbage": "abc"} {
+		if _, err := a.UserID(requestWithToken(token)); err == nil {
+			t.Errorf("expected %s token to be rejected", name)
+		}
+	}
+
+	valid, _ := New("").Token(42)
+	if _, err := New("").UserID(requestWithToken(valid)); err == nil {
+		t.Error("expected every token to be rejected without a secret")
+	}
+}
@@ -0,0 +1,38 @@
+// Package authtest signs requests for handler tests.
+package authtest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/coloradocollective/go-capstone-starter/internal/auth"
+)
+
+// Auth is the authenticator handler tests register their routes with.
+var Auth = auth.New("test-secret")
+
+// Sign adds a bearer token for userID to req. A zero userID leaves req anonymous.
+func Sign(t testing.TB, req *http.Request, userID int) *http.Request {
+	t.Helper()
+	if userID == 0 {
+		return req
+	}
+	token, err := Auth.Token(userID)
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return req
+}
+
+// Request serves method and path with body on mux as userID, or anonymously when
+// userID is zero, and returns the response.
+func Request(t testing.TB, mux http.Handler, method, path string, userID int, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := Sign(t, httptest.NewRequest(method, path, strings.NewReader(body)), userID)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	return rec
+}
@@ -0,0 +1,105 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// Cache modes reported by Status.
+const (
+	ModeRedis    = "redis"
+	ModeEmbedded = "embedded"
+)
+
+// ErrNotConfigured is returned when neither a Redis URL nor the embedded server was requested.
+var ErrNotConfigured = errors.New("set REDIS_URL, or REDIS_EMBEDDED=true for local development")
+
+// Config selects the Redis server. URL takes the go-redis form
+// redis://[user:password@]host:port[/db]; use rediss:// for TLS. Embedded starts
+// an in-process miniredis, which is lost on restart and not shared between
+// instances, so it is only for local development.
+type Config struct {
+	URL      string
+	Embedded bool
+}
+
+// ConfigFromEnv reads REDIS_URL and REDIS_EMBEDDED.
+func ConfigFromEnv() Config {
+	return Config{
+		URL:      os.Getenv("REDIS_URL"),
+		Embedded: os.Getenv("REDIS_EMBEDDED") == "true",
+	}
+}
+
+// Cache is a connected Redis client.
+type Cache struct {
+	Client *redis.Client
+	Mode   string
+	// Addr is host:port without credentials.
+	Addr string
+
+	embedded *miniredis.Miniredis
+}
+
+// Open creates the client. It does not wait for the server, so a Redis outage at
+// startup shows up in Ping and the health check rather than stopping the app.
+func Open(cfg Config) (*Cache, error) {
+	switch {
+	case cfg.URL != "" && cfg.Embedded:
+		return nil, errors.New("REDIS_URL and REDIS_EMBEDDED=true are mutually exclusive")
+	case cfg.Embedded:
+		mr, err := miniredis.Run()
+		if err != nil {
+			return nil, fmt.Errorf("failed to start embedded Redis: %w", err)
+		}
+		return &Cache{
+			Client:   redis.NewClient(&redis.Options{Addr: mr.Addr()}),
+			Mode:     ModeEmbedded,
+			Addr:     mr.Addr(),
+			embedded: mr,
+		}, nil
+	case cfg.URL != "":
+		opts, err := redis.ParseURL(cfg.URL)
+		if err != nil {
+			// The URL may contain a password, so it is not echoed.
+			return nil, errors.New("invalid REDIS_URL")
+		}
+		return &Cache{Client: redis.NewClient(opts), Mode: ModeRedis, Addr: opts.Addr}, nil
+	default:
+		return nil, ErrNotConfigured
+	}
+}
+
+// TLS reports whether the connection to Redis is encrypted.
+func (c *Cache) TLS() bool {
+	return c.Client.Options().TLSConfig != nil
+}
+
+// Ping checks that Redis answers.
+func (c *Cache) Ping(ctx context.Context) error {
+	return c.Client.Ping(ctx).Err()
+}
+
+// Describe summarizes the connection for logs and health checks.
+func (c *Cache) Describe() string {
+	if c.Mode == ModeEmbedded {
+		return "embedded miniredis at " + c.Addr
+	}
+	if c.TLS() {
+		return "redis at " + c.Addr + " (TLS)"
+	}
+	return "redis at " + c.Addr
+}
+
+func (c *Cache) Close() error {
+	err := c.Client.Close()
+	if c.embedded != nil {
+		c.embedded.Close()
+	}
+	return err
+}
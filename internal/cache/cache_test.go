@@ -0,0 +1,68 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestOpen_Embedded(t *testing.T) {
+	c, err := Open(Config{Embedded: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer c.Close()
+
+	if c.Mode != ModeEmbedded || c.Ping(context.Background()) != nil {
+		t.Fatalf("expected a working embedded cache, got %s", c.Describe())
+	}
+}
+
+func TestOpen_URLWithAuth(t *testing.T) {
+	mr := miniredis.RunT(t)
+	mr.RequireAuth("s3cret")
+
+	c, err := Open(Config{URL: "redis://:s3cret@" + mr.Addr() + "/0"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer c.Close()
+	if err := c.Ping(context.Background()); err != nil {
+		t.Fatalf("expected authenticated ping to succeed: %v", err)
+	}
+	if c.Mode != ModeRedis || c.TLS() || strings.Contains(c.Describe(), "s3cret") {
+		t.Fatalf("unexpected description: %s", c.Describe())
+	}
+
+	wrong, _ := Open(Config{URL: "redis://:wrong@" + mr.Addr()})
+	defer wrong.Close()
+	if err := wrong.Ping(context.Background()); err == nil {
+		t.Fatal("expected ping with the wrong password to fail")
+	}
+}
+
+func TestOpen_TLSURL(t *testing.T) {
+	c, err := Open(Config{URL: "rediss://user:pw@cache.internal:6380"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer c.Close()
+	if !c.TLS() || c.Describe() != "redis at cache.internal:6380 (TLS)" {
+		t.Fatalf("unexpected description: %s", c.Describe())
+	}
+}
+
+func TestOpen_RequiresExplicitConfig(t *testing.T) {
+	if _, err := Open(Config{}); !errors.Is(err, ErrNotConfigured) {
+		t.Fatalf("expected ErrNotConfigured, got %v", err)
+	}
+	if _, err := Open(Config{URL: "redis://localhost", Embedded: true}); err == nil {
+		t.Fatal("expected an error when both are set")
+	}
+	if _, err := Open(Config{URL: "http://:pw@localhost"}); err == nil || strings.Contains(err.Error(), "pw") {
+		t.Fatalf("expected an error that does not leak the URL, got %v", err)
+	}
+}
@@ -0,0 +1,59 @@
+// Package dbtest connects tests to a migrated Postgres database.
+package dbtest
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+
+	_ "github.com/lib/pq"
+)
+
+// DefaultURL is the test database created by databases/create_databases.sql.
+const DefaultURL = "user=starter password=starter database=starter_test host=localhost sslmode=disable"
+
+// Open connects to TEST_DATABASE_URL, or DefaultURL when it is unset, and closes
+// the connection when the test ends. The test is skipped when the database cannot
+// be reached; migrate it first with cmd/migrate.
+func Open(t testing.TB) *sql.DB {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		url = DefaultURL
+	}
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		t.Fatalf("failed to open the test database: %v", err)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		t.Skipf("test database is not reachable: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// UserID returns a user id no other test uses, and deletes the rows of the named
+// tables that belong to it when the test ends. The tables must have a user_id column.
+func UserID(t testing.TB, db *sql.DB, tables ...string) int {
+	t.Helper()
+	var id int
+	err := db.QueryRow(`
+	INSERT INTO users (email, name) VALUES ('dbtest-' || gen_random_uuid() || '@example.com', 'dbtest')
+	RETURNING id;
+	`).Scan(&id)
+	if err != nil {
+		t.Fatalf("failed to create a test user: %v", err)
+	}
+	t.Cleanup(func() {
+		for _, table := range tables {
+			if _, err := db.Exec(`DELETE FROM `+table+` WHERE user_id = $1`, id); err != nil {
+				t.Errorf("failed to clean up %s: %v", table, err)
+			}
+		}
+		if _, err := db.Exec(`DELETE FROM users WHERE id = $1`, id); err != nil {
+			t.Errorf("failed to clean up the test user: %v", err)
+		}
+	})
+	return id
+}
@@ -0,0 +1,144 @@
+package embeddings
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// Embedder turns text into a vector that can be compared with cosine similarity.
+type Embedder interface {
+	Model() string
+	Embed(ctx context.Context, text string) (Result, error)
+}
+
+// Result is a single embedding together with the model that produced it and its token usage.
+type Result struct {
+	Vector []float64
+	Model  string
+	Usage  openai.Usage
+}
+
+// NewFromEnv builds an Embedder from EMBEDDING_BACKEND ("openai" or "local").
+// The OpenAI backend reuses OPENAI_API_KEY and MODEL_BASE_URL so that it talks to
+// the same OpenAI-compatible endpoint as the chat model.
+func NewFromEnv() Embedder {
+	switch os.Getenv("EMBEDDING_BACKEND") {
+	case "local":
+		return NewHashEmbedder(DefaultHashDimensions)
+	default:
+		model := os.Getenv("EMBEDDING_MODEL")
+		if model == "" {
+			model = string(openai.SmallEmbedding3)
+		}
+		return NewOpenAIEmbedder(os.Getenv("OPENAI_API_KEY"), os.Getenv("MODEL_BASE_URL"), model)
+	}
+}
+
+// OpenAIEmbedder calls the embeddings endpoint of an OpenAI-compatible API.
+type OpenAIEmbedder struct {
+	client *openai.Client
+	model  string
+}
+
+func NewOpenAIEmbedder(apiKey, baseURL, model string) *OpenAIEmbedder {
+	cfg := openai.DefaultConfig(apiKey)
+	if baseURL != "" {
+		cfg.BaseURL = baseURL
+	}
+	return &OpenAIEmbedder{client: openai.NewClientWithConfig(cfg), model: model}
+}
+
+func (e *OpenAIEmbedder) Model() string { return e.model }
+
+func (e *OpenAIEmbedder) Embed(ctx context.Context, text string) (Result, error) {
+	resp, err := e.client.CreateEmbeddings(ctx, openai.EmbeddingRequestStrings{
+		Input: []string{text},
+		Model: openai.EmbeddingModel(e.model),
+	})
+	if err != nil {
+		return Result{}, err
+	}
+	if len(resp.Data) == 0 {
+		return Result{}, errors.New("embedding response contained no data")
+	}
+
+	vector := make([]float64, len(resp.Data[0].Embedding))
+	for i, v := range resp.Data[0].Embedding {
+		vector[i] = float64(v)
+	}
+	return Result{Vector: vector, Model: e.model, Usage: resp.Usage}, nil
+}
+
+// DefaultHashDimensions is the vector size used by the local stand-in.
+const DefaultHashDimensions = 256
+
+// HashEmbedder is a deterministic, offline stand-in for a real embedding model.
+// It hashes each word into a fixed number of buckets, so texts that share words
+// end up close together. It is meant for local development and tests.
+type HashEmbedder struct {
+	dimensions int
+}
+
+func NewHashEmbedder(dimensions int) *HashEmbedder {
+	return &HashEmbedder{dimensions: dimensions}
+}
+
+var wordPattern = regexp.MustCompile(`[a-z0-9]+`)
+
+func (e *HashEmbedder) Model() string { return fmt.Sprintf("local-hash-%d", e.dimensions) }
+
+func (e *HashEmbedder) Embed(_ context.Context, text string) (Result, error) {
+	vector := make([]float64, e.dimensions)
+	for _, word := range wordPattern.FindAllString(strings.ToLower(text), -1) {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(word))
+		sum := h.Sum64()
+		sign := 1.0
+		if sum&1 == 1 {
+			sign = -1.0
+		}
+		vector[(sum>>1)%uint64(e.dimensions)] += sign
+	}
+	normalize(vector)
+	return Result{Vector: vector, Model: e.Model()}, nil
+}
+
+func normalize(vector []float64) {
+	var norm float64
+	for _, v := range vector {
+		norm += v * v
+	}
+	if norm == 0 {
+		return
+	}
+	norm = math.Sqrt(norm)
+	for i := range vector {
+		vector[i] /= norm
+	}
+}
+
+// CosineSimilarity returns the cosine of the angle between a and b, or 0 when
+// the vectors differ in length or either has zero magnitude.
+func CosineSimilarity(a, b []float64) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
@@ -0,0 +1,46 @@
+package embeddings
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func TestCosineSimilarity(t *testing.T) {
+	if got := CosineSimilarity([]float64{1, 0}, []float64{1, 0}); math.Abs(got-1) > 1e-9 {
+		t.Fatalf("expected 1 for identical vectors, got %f", got)
+	}
+	if got := CosineSimilarity([]float64{1, 0}, []float64{0, 1}); math.Abs(got) > 1e-9 {
+		t.Fatalf("expected 0 for orthogonal vectors, got %f", got)
+	}
+	if got := CosineSimilarity([]float64{1, 0}, []float64{1, 0, 0}); got != 0 {
+		t.Fatalf("expected 0 for mismatched lengths, got %f", got)
+	}
+}
+
+func TestHashEmbedder_SimilarTextsAreCloser(t *testing.T) {
+	embedder := NewHashEmbedder(DefaultHashDimensions)
+	ctx := context.Background()
+
+	chili, _ := embedder.Embed(ctx, "Turkey Chili: ground turkey, kidney beans, tomatoes, chili powder")
+	beefChili, _ := embedder.Embed(ctx, "Beef Chili: ground beef, kidney beans, tomatoes, chili powder")
+	cake, _ := embedder.Embed(ctx, "Chocolate Cake: flour, sugar, cocoa, eggs, butter")
+
+	near := CosineSimilarity(chili.Vector, beefChili.Vector)
+	far := CosineSimilarity(chili.Vector, cake.Vector)
+	if near <= far {
+		t.Fatalf("expected chili recipes to be closer (%f) than chili and cake (%f)", near, far)
+	}
+}
+
+func TestHashEmbedder_IsDeterministic(t *testing.T) {
+	embedder := NewHashEmbedder(32)
+	a, _ := embedder.Embed(context.Background(), "black bean soup")
+	b, _ := embedder.Embed(context.Background(), "black bean soup")
+
+	for i := range a.Vector {
+		if a.Vector[i] != b.Vector[i] {
+			t.Fatalf("expected identical vectors, differ at %d", i)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+// Package energy estimates energy expenditure and suggests calorie and macro targets.
+package energy
+
+import (
+	"errors"
+	"math"
+)
+
+// Sexes used by the BMR equations.
+const (
+	Female = "female"
+	Male   = "male"
+)
+
+// Activity levels and their TDEE multipliers.
+var ActivityFactors = map[string]float64{
+	"sedentary":   1.2,   // desk job, little exercise
+	"light":       1.375, // exercise 1-3 days a week
+	"moderate":    1.55,  // exercise 3-5 days a week
+	"active":      1.725, // exercise 6-7 days a week
+	"very_active": 1.9,   // physical job or training twice a day
+}
+
+// Objectives.
+const (
+	Lose     = "lose"
+	Maintain = "maintain"
+	Gain     = "gain"
+)
+
+// BMR formulas.
+const (
+	MifflinStJeor = "mifflin_st_jeor"
+	KatchMcArdle  = "katch_mcardle"
+)
+
+// Calorie adjustments for each objective: about 0.5 kg a week lost, or a lean gain.
+var objectiveAdjustments = map[string]float64{Lose: -500, Maintain: 0, Gain: 300}
+
+// Protein per kilogram of body weight for each objective; more protein preserves
+// muscle in a deficit.
+var proteinPerKg = map[string]float64{Lose: 2.0, Maintain: 1.6, Gain: 1.8}
+
+// fatShare is the share of calories from fat, and minFatShare the least it is
+// lowered to when protein leaves no room for it.
+const (
+	fatShare    = 0.25
+	minFatShare = 0.15
+)
+
+// Minimum daily calories suggested without medical supervision.
+var minimumCalories = map[string]float64{Female: 1200, Male: 1500}
+
+// Person holds what the equations need. BodyFatPercent is optional.
+type Person struct {
+	Sex            string
+	Age            int
+	HeightCm       float64
+	WeightKg       float64
+	BodyFatPercent *float64
+	ActivityLevel  string
+}
+
+var ErrUnknownObjective = errors.New("objective must be lose, maintain or gain")
+
+// MifflinStJeorBMR is the Mifflin-St Jeor resting energy expenditure in kcal/day.
+func MifflinStJeorBMR(p Person) float64 {
+	bmr := 10*p.WeightKg + 6.25*p.HeightCm - 5*float64(p.Age)
+	if p.Sex == Male {
+		return bmr + 5
+	}
+	return bmr - 161
+}
+
+// KatchMcArdleBMR is the Katch-McArdle resting energy expenditure in kcal/day,
+// based on lean body mass.
+func KatchMcArdleBMR(weightKg, bodyFatPercent float64) float64 {
+	lean := weightKg * (1 - bodyFatPercent/100)
+	return 370 + 21.6*lean
+}
+
+// BMR uses Katch-McArdle when body fat is known, as it is more accurate for lean or
+// muscular people, and Mifflin-St Jeor otherwise.
+func BMR(p Person) (bmr float64, formula string) {
+	if p.BodyFatPercent != nil {
+		return KatchMcArdleBMR(p.WeightKg, *p.BodyFatPercent), KatchMcArdle
+	}
+	return MifflinStJeorBMR(p), MifflinStJeor
+}
+
+// TDEE scales a BMR by the activity level; unknown levels count as sedentary.
+func TDEE(bmr float64, activityLevel string) float64 {
+	factor, ok := ActivityFactors[activityLevel]
+	if !ok {
+		factor = ActivityFactors["sedentary"]
+	}
+	return bmr * factor
+}
+
+// Suggestion is a set of daily targets for an objective.
+type Suggestion struct {
+	Objective     string `json:"objective"`
+	Formula       string `json:"formula"`
+	BMR           int    `json:"bmr"`
+	TDEE          int    `json:"tdee"`
+	Calories      int    `json:"calories"`
+	Protein       int    `json:"protein"`
+	Carbohydrates int    `json:"carbohydrates"`
+	Fat           int    `json:"fat"`
+	// Warning explains macros lowered to fit the calorie target.
+	Warning string `json:"warning,omitempty"`
+}
+
+// Suggest derives calorie and macro targets. Calories are rounded to tens and never
+// below a safe minimum; protein follows body weight, fat takes a fixed share and
+// carbohydrates the rest. When protein and fat alone exceed the calories, fat and
+// then protein are lowered until they fit, and the suggestion says so.
+func Suggest(p Person, objective string) (Suggestion, error) {
+	adjustment, ok := objectiveAdjustments[objective]
+	if !ok {
+		return Suggestion{}, ErrUnknownObjective
+	}
+	bmr, formula := BMR(p)
+	tdee := TDEE(bmr, p.ActivityLevel)
+
+	calories := math.Max(tdee+adjustment, minimumCalories[p.Sex])
+	calories = math.Round(calories/10) * 10
+	protein := math.Round(proteinPerKg[objective] * p.WeightKg)
+	fat := math.Round(calories * fatShare / 9)
+	carbohydrates := math.Round((calories - protein*4 - fat*9) / 4)
+	var warning string
+	if protein*4+fat*9 > calories {
+		carbohydrates = 0
+		fat = math.Max(math.Floor((calories-protein*4)/9), math.Round(calories*minFatShare/9))
+		warning = "fat was lowered to fit the protein target into the calories, leaving no carbohydrates"
+		if protein*4+fat*9 > calories {
+			protein = math.Floor((calories - fat*9) / 4)
+			warning = "protein and fat were lowered to fit the calories, leaving no carbohydrates"
+		}
+	}
+
+	return Suggestion{
+		Objective:     objective,
+		Formula:       formula,
+		BMR:           int(math.Round(bmr)),
+		TDEE:          int(math.Round(tdee)),
+		Calories:      int(calories),
+		Protein:       int(protein),
+		Carbohydrates: int(carbohydrates),
+		Fat:           int(fat),
+		Warning:       warning,
+	}, nil
+}
@@ -0,0 +1,75 @@
+package energy
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBMR(t *testing.T) {
+	man := Person{Sex: Male, Age: 30, HeightCm: 180, WeightKg: 80, ActivityLevel: "moderate"}
+	if bmr, formula := BMR(man); bmr != 1780 || formula != MifflinStJeor {
+		t.Errorf("expected 1780 by Mifflin-St Jeor, got %v by %s", bmr, formula)
+	}
+	woman := Person{Sex: Female, Age: 40, HeightCm: 165, WeightKg: 60}
+	if bmr := MifflinStJeorBMR(woman); bmr != 1270.25 {
+		t.Errorf("expected 1270.25, got %v", bmr)
+	}
+
+	bodyFat := 15.0
+	man.BodyFatPercent = &bodyFat
+	bmr, formula := BMR(man)
+	if formula != KatchMcArdle || math.Abs(bmr-1838.8) > 0.01 {
+		t.Errorf("expected 1838.8 by Katch-McArdle, got %v by %s", bmr, formula)
+	}
+	if tdee := TDEE(1780, "moderate"); math.Abs(tdee-2759) > 0.01 {
+		t.Errorf("expected a TDEE of 2759, got %v", tdee)
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	man := Person{Sex: Male, Age: 30, HeightCm: 180, WeightKg: 80, ActivityLevel: "moderate"}
+	lose, err := Suggest(man, Lose)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Suggestion{Objective: Lose, Formula: MifflinStJeor, BMR: 1780, TDEE: 2759,
+		Calories: 2260, Protein: 160, Fat: 63, Carbohydrates: 263}
+	if lose != want {
+		t.Errorf("expected %+v, got %+v", want, lose)
+	}
+
+	gain, _ := Suggest(man, Gain)
+	if gain.Calories != 3060 {
+		t.Errorf("expected 3060 calories to gain, got %d", gain.Calories)
+	}
+
+	small := Person{Sex: Female, Age: 70, HeightCm: 150, WeightKg: 45, ActivityLevel: "sedentary"}
+	if s, _ := Suggest(small, Lose); s.Calories != 1200 {
+		t.Errorf("expected the 1200 kcal floor, got %d", s.Calories)
+	}
+	if _, err := Suggest(man, "bulk"); err != ErrUnknownObjective {
+		t.Errorf("expected ErrUnknownObjective, got %v", err)
+	}
+}
+
+func TestSuggest_FitsMacrosIntoFewCalories(t *testing.T) {
+	fits := func(s Suggestion) bool {
+		return s.Protein*4+s.Fat*9+s.Carbohydrates*4 <= s.Calories && s.Carbohydrates == 0 && s.Warning != ""
+	}
+
+	bodyFat := 60.0
+	heavy := Person{Sex: Male, Age: 45, HeightCm: 185, WeightKg: 200, BodyFatPercent: &bodyFat, ActivityLevel: "sedentary"}
+	s, err := Suggest(heavy, Lose)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fits(s) || s.Protein != 400 || s.Fat != 46 {
+		t.Errorf("expected fat lowered to fit the protein, got %+v", s)
+	}
+
+	bodyFat = 70
+	s, _ = Suggest(heavy, Lose)
+	if !fits(s) || s.Calories != 1500 || s.Protein != 318 || s.Fat != 25 {
+		t.Errorf("expected protein lowered and fat kept at its floor, got %+v", s)
+	}
+}
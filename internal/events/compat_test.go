@@ -0,0 +1,57 @@
+package events
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// TestCompatibility replays recorded messages for every published event version
+// against the current schemas. A schema change that rejects any of them, such as
+// a new required property or a narrower type, would break consumers of messages
+// already in flight; it needs a new version instead. When adding a version, record
+// at least one message in testdata/<type>.v<version>/.
+func TestCompatibility(t *testing.T) {
+	for key := range dataSchemas {
+		dir := filepath.Join("testdata", fmt.Sprintf("%s.v%d", key.eventType, key.version))
+		fixtures, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+		if len(fixtures) == 0 {
+			t.Errorf("%s v%d has no recorded messages in %s", key.eventType, key.version, dir)
+		}
+
+		for _, path := range fixtures {
+			raw, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			env, err := Decode(raw)
+			if err != nil {
+				t.Errorf("%s no longer validates: %v", path, err)
+				continue
+			}
+			if env.Type != key.eventType || env.Version != key.version {
+				t.Errorf("%s is recorded as %s v%d", path, env.Type, env.Version)
+			}
+		}
+	}
+}
+
+// TestCompatibility_VersionsAreNotRemoved keeps schemas for every version with
+// recorded messages, since consumers may still receive them.
+func TestCompatibility_VersionsAreNotRemoved(t *testing.T) {
+	dirs, _ := filepath.Glob(filepath.Join("testdata", "*.v*"))
+	for _, dir := range dirs {
+		name := filepath.Base(dir)
+		match := schemaFileName.FindStringSubmatch(name + ".json")
+		if match == nil {
+			t.Errorf("unexpected testdata directory %s", dir)
+			continue
+		}
+		version, _ := strconv.Atoi(match[2])
+		if _, ok := dataSchemas[schemaKey{match[1], version}]; !ok {
+			t.Errorf("schemas/%s.json was removed but %s still has recorded messages", name, dir)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package events
+
+import (
+	"context"
+	"crypto/rand"
+	"embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/coloradocollective/go-capstone-starter/internal/messaging"
+)
+
+// Envelope wraps every published event. Version is the version of the data
+// schema for Type; a change that could break an existing consumer gets a new
+// version instead of changing the old one.
+type Envelope struct {
+	Type      string          `json:"type"`
+	Version   int             `json:"version"`
+	ID        string          `json:"id"`
+	Timestamp time.Time       `json:"timestamp"`
+	User      *int            `json:"user,omitempty"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// ErrUnknownEvent is returned for a type and version without a schema.
+var ErrUnknownEvent = errors.New("unknown event type or version")
+
+//go:embed schemas/*.json
+var schemaFiles embed.FS
+
+type schemaKey struct {
+	eventType string
+	version   int
+}
+
+var (
+	envelopeSchema *Schema
+	dataSchemas    = map[schemaKey]*Schema{}
+	schemaFileName = regexp.MustCompile(`^(.+)\.v([0-9]+)\.json$`)
+)
+
+func init() {
+	if err := loadSchemas(schemaFiles); err != nil {
+		panic(err)
+	}
+}
+
+// loadSchemas reads envelope.json and one <type>.v<version>.json per event version.
+func loadSchemas(files fs.FS) error {
+	names, err := fs.Glob(files, "schemas/*.json")
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		raw, err := fs.ReadFile(files, name)
+		if err != nil {
+			return err
+		}
+		schema, err := ParseSchema(raw)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+
+		base := name[len("schemas/"):]
+		if base == "envelope.json" {
+			envelopeSchema = schema
+			continue
+		}
+		match := schemaFileName.FindStringSubmatch(base)
+		if match == nil {
+			return fmt.Errorf("%s: expected a name like <type>.v<version>.json", name)
+		}
+		version, _ := strconv.Atoi(match[2])
+		dataSchemas[schemaKey{match[1], version}] = schema
+	}
+	if envelopeSchema == nil {
+		return errors.New("schemas/envelope.json is missing")
+	}
+	return nil
+}
+
+// New builds a validated envelope. userID 0 means the event is not about a user.
+func New(eventType string, version int, userID int, data any) (Envelope, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return Envelope{}, err
+	}
+	env := Envelope{
+		Type:      eventType,
+		Version:   version,
+		ID:        newID(),
+		Timestamp: time.Now().UTC(),
+		Data:      raw,
+	}
+	if userID != 0 {
+		env.User = &userID
+	}
+	if _, err := Encode(env); err != nil {
+		return Envelope{}, err
+	}
+	return env, nil
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Encode validates env against its schemas and returns its JSON.
+func Encode(env Envelope) ([]byte, error) {
+	raw, err := json.Marshal(env)
+	if err != nil {
+		return nil, err
+	}
+	return raw, validate(env, raw)
+}
+
+// Decode parses and validates a published event.
+func Decode(raw []byte) (Envelope, error) {
+	var env Envelope
+	if err := json.Unmarshal(raw, &env); err != nil {
+		return Envelope{}, err
+	}
+	return env, validate(env, raw)
+}
+
+func validate(env Envelope, raw []byte) error {
+	if err := envelopeSchema.Validate(raw); err != nil {
+		return fmt.Errorf("invalid envelope: %w", err)
+	}
+	schema, ok := dataSchemas[schemaKey{env.Type, env.Version}]
+	if !ok {
+		return fmt.Errorf("%w: %s v%d", ErrUnknownEvent, env.Type, env.Version)
+	}
+	if err := schema.Validate(env.Data); err != nil {
+		return fmt.Errorf("invalid %s v%d data: %w", env.Type, env.Version, err)
+	}
+	return nil
+}
+
+// DecodeData unmarshals the event data into v.
+func (e Envelope) DecodeData(v any) error {
+	return json.Unmarshal(e.Data, v)
+}
+
+// Publish validates and publishes env, with its type, version and ID as attributes
+// so subscribers can filter without parsing the payload.
+func Publish(ctx context.Context, publisher messaging.Publisher, topic string, env Envelope) error {
+	raw, err := Encode(env)
+	if err != nil {
+		return err
+	}
+	return publisher.Publish(ctx, topic, messaging.Message{Data: raw, Attributes: Attributes(env)})
+}
+
+// Attributes are the message attributes published with an envelope.
+func Attributes(env Envelope) map[string]string {
+	return map[string]string{
+		"type":     env.Type,
+		"version":  strconv.Itoa(env.Version),
+		"event_id": env.ID,
+	}
+}
+
+// Handler validates deliveries before passing them to handle. Messages that fail
+// validation are logged and acknowledged, as redelivering them cannot help;
+// errors from handle nack the message.
+func Handler(handle func(ctx context.Context, env Envelope) error) messaging.Handler {
+	return func(ctx context.Context, msg messaging.Message) error {
+		env, err := Decode(msg.Data)
+		if err != nil {
+			log.Printf("Dropping invalid event %s: %v", msg.ID, err)
+			return nil
+		}
+		return handle(ctx, env)
+	}
+}
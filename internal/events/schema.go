@@ -0,0 +1,194 @@
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Schema is the subset of JSON Schema (draft 2020-12) the event schemas use.
+// Loading a schema with any other keyword fails, so a schema never silently
+// relies on a rule that is not enforced.
+type Schema struct {
+	Types      []string
+	Properties map[string]*Schema
+	Required   []string
+	Enum       []any
+	Minimum    *float64
+	MinLength  *int
+	Format     string
+	Items      *Schema
+}
+
+var supportedKeywords = map[string]bool{
+	"$schema": true, "$id": true, "title": true, "description": true,
+	"type": true, "properties": true, "required": true, "enum": true,
+	"minimum": true, "minLength": true, "format": true, "items": true,
+}
+
+// ParseSchema reads a schema document.
+func ParseSchema(raw []byte) (*Schema, error) {
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+
+	s := &Schema{}
+	for keyword, value := range doc {
+		if !supportedKeywords[keyword] {
+			return nil, fmt.Errorf("unsupported keyword %q", keyword)
+		}
+		var err error
+		switch keyword {
+		case "type":
+			if bytes.HasPrefix(bytes.TrimSpace(value), []byte("[")) {
+				err = json.Unmarshal(value, &s.Types)
+			} else {
+				var t string
+				err = json.Unmarshal(value, &t)
+				s.Types = []string{t}
+			}
+		case "properties":
+			var props map[string]json.RawMessage
+			if err = json.Unmarshal(value, &props); err == nil {
+				s.Properties = make(map[string]*Schema, len(props))
+				for name, prop := range props {
+					if s.Properties[name], err = ParseSchema(prop); err != nil {
+						return nil, fmt.Errorf("property %s: %w", name, err)
+					}
+				}
+			}
+		case "required":
+			err = json.Unmarshal(value, &s.Required)
+		case "enum":
+			err = json.Unmarshal(value, &s.Enum)
+		case "minimum":
+			err = json.Unmarshal(value, &s.Minimum)
+		case "minLength":
+			err = json.Unmarshal(value, &s.MinLength)
+		case "format":
+			err = json.Unmarshal(value, &s.Format)
+			if err == nil && s.Format != "date-time" {
+				err = fmt.Errorf("unsupported format %q", s.Format)
+			}
+		case "items":
+			s.Items, err = ParseSchema(value)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", keyword, err)
+		}
+	}
+	return s, nil
+}
+
+// Validate checks a JSON document against the schema.
+func (s *Schema) Validate(raw []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return err
+	}
+	return s.validate(value, "$")
+}
+
+func (s *Schema) validate(value any, path string) error {
+	if len(s.Types) > 0 && !s.matchesType(value) {
+		return fmt.Errorf("%s: expected %s", path, strings.Join(s.Types, " or "))
+	}
+	if len(s.Enum) > 0 && !inEnum(value, s.Enum) {
+		return fmt.Errorf("%s: %v is not one of %v", path, value, s.Enum)
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				return fmt.Errorf("%s: missing required property %q", path, name)
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if prop, ok := s.Properties[name]; ok {
+				if err := prop.validate(v[name], path+"."+name); err != nil {
+					return err
+				}
+			}
+		}
+	case []any:
+		if s.Items != nil {
+			for i, item := range v {
+				if err := s.Items.validate(item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	case string:
+		if s.MinLength != nil && len([]rune(v)) < *s.MinLength {
+			return fmt.Errorf("%s: shorter than %d characters", path, *s.MinLength)
+		}
+		if s.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339, v); err != nil {
+				return fmt.Errorf("%s: not an RFC 3339 date-time", path)
+			}
+		}
+	case json.Number:
+		if s.Minimum != nil {
+			if f, err := v.Float64(); err == nil && f < *s.Minimum {
+				return fmt.Errorf("%s: %s is below the minimum %v", path, v, *s.Minimum)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Schema) matchesType(value any) bool {
+	for _, t := range s.Types {
+		switch v := value.(type) {
+		case nil:
+			if t == "null" {
+				return true
+			}
+		case bool:
+			if t == "boolean" {
+				return true
+			}
+		case string:
+			if t == "string" {
+				return true
+			}
+		case map[string]any:
+			if t == "object" {
+				return true
+			}
+		case []any:
+			if t == "array" {
+				return true
+			}
+		case json.Number:
+			if t == "number" {
+				return true
+			}
+			if _, err := v.Int64(); err == nil && t == "integer" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func inEnum(value any, enum []any) bool {
+	for _, allowed := range enum {
+		if fmt.Sprint(allowed) == fmt.Sprint(value) {
+			return true
+		}
+	}
+	return false
+}
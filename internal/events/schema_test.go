@@ -0,0 +1,91 @@
+package events
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseSchema_RejectsUnsupportedKeywords(t *testing.T) {
+	for _, raw := range []string{
+		`{"type": "object", "additionalProperties": false}`,
+		`{"type": "object", "properties": {"id": {"pattern": "^[0-9]+$"}}}`,
+		`{"type": "string", "format": "email"}`,
+	} {
+		if _, err := ParseSchema([]byte(raw)); err == nil {
+			t.Errorf("expected %s to be rejected", raw)
+		}
+	}
+}
+
+func TestSchema_Validate(t *testing.T) {
+	schema, err := ParseSchema([]byte(`{
+		"type": "object",
+		"required": ["id", "meal_type"],
+		"properties": {
+			"id": {"type": "integer", "minimum": 1},
+			"meal_type": {"type": "string", "enum": ["breakfast", "lunch"]},
+			"at": {"type": "string", "format": "date-time"},
+			"user": {"type": ["integer", "null"]},
+			"tags": {"type": "array", "items": {"type": "string", "minLength": 1}}
+		}
+	}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	valid := []string{
+		`{"id": 1, "meal_type": "lunch"}`,
+		`{"id": 1, "meal_type": "lunch", "at": "2024-05-01T08:30:00+02:00", "user": null, "tags": ["a"], "extra": true}`,
+	}
+	for _, raw := range valid {
+		if err := schema.Validate([]byte(raw)); err != nil {
+			t.Errorf("expected %s to be valid: %v", raw, err)
+		}
+	}
+
+	invalid := []string{
+		`[]`,
+		`{"meal_type": "lunch"}`,
+		`{"id": 0, "meal_type": "lunch"}`,
+		`{"id": 1.5, "meal_type": "lunch"}`,
+		`{"id": 1, "meal_type": "brunch"}`,
+		`{"id": 1, "meal_type": "lunch", "at": "yesterday"}`,
+		`{"id": 1, "meal_type": "lunch", "user": "4"}`,
+		`{"id": 1, "meal_type": "lunch", "tags": [""]}`,
+	}
+	for _, raw := range invalid {
+		if err := schema.Validate([]byte(raw)); err == nil {
+			t.Errorf("expected %s to be invalid", raw)
+		}
+	}
+}
+
+func TestNew_ValidatesData(t *testing.T) {
+	env, err := New("food_entry.deleted", 1, 4, map[string]int{"entry_id": 31})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if env.ID == "" || env.User == nil || *env.User != 4 || env.Timestamp.IsZero() {
+		t.Fatalf("unexpected envelope: %+v", env)
+	}
+	decoded, err := Decode(mustEncode(t, env))
+	if err != nil || decoded.ID != env.ID {
+		t.Fatalf("round trip failed: %+v, %v", decoded, err)
+	}
+
+	if _, err := New("food_entry.deleted", 1, 4, map[string]int{}); err == nil {
+		t.Error("expected data without entry_id to be rejected")
+	}
+	if _, err := New("food_entry.deleted", 9, 4, map[string]int{"entry_id": 31}); !errors.Is(err, ErrUnknownEvent) {
+		t.Errorf("expected ErrUnknownEvent for an unknown version, got %v", err)
+	}
+}
+
+func mustEncode(t *testing.T, env Envelope) []byte {
+	t.Helper()
+	raw, err := Encode(env)
+	if err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+	return raw
+}
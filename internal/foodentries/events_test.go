@@ -0,0 +1,45 @@
+package foodentries
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/coloradocollective/go-capstone-starter/internal/events"
+)
+
+func TestEntryEvent_MatchesSchema(t *testing.T) {
+	entry := Entry{ID: 31, UserID: 4, Name: "Oatmeal", Portion: 1, Unit: "bowl", Calories: 300, MealType: "lunch",
+		ConsumedAt: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC), CreatedAt: time.Now(), UpdatedAt: time.Now()}
+
+	for _, eventType := range []string{EntryCreated, EntryUpdated, EntryDeleted} {
+		env, err := entryEvent(eventType, entry)
+		if err != nil {
+			t.Errorf("%s does not match its schema: %v", eventType, err)
+			continue
+		}
+		if env.User == nil || *env.User != 4 || env.Version != EventVersion {
+			t.Errorf("unexpected envelope for %s: %+v", eventType, env)
+		}
+	}
+}
+
+// TestEventData_DecodesRecordedMessages keeps EventData able to read messages
+// already published in the current version.
+func TestEventData_DecodesRecordedMessages(t *testing.T) {
+	raw, err := os.ReadFile("../events/testdata/food_entry.created.v1/app.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	env, err := events.Decode(raw)
+	if err != nil {
+		t.Fatalf("invalid recorded message: %v", err)
+	}
+	var data EventData
+	if err := env.DecodeData(&data); err != nil {
+		t.Fatalf("failed to decode data: %v", err)
+	}
+	if data.EntryID != 31 || data.Entry == nil || data.Entry.Name != "Oatmeal" || data.Entry.UserID != 4 {
+		t.Fatalf("unexpected data: %+v", data)
+	}
+}
@@ -0,0 +1,203 @@
+package foodentries
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Export formats.
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+)
+
+// ExportQuery selects a user's entries consumed in [From, To); zero values leave
+// the range open.
+type ExportQuery struct {
+	UserID int
+	From   time.Time
+	To     time.Time
+}
+
+// Each streams the selected entries, oldest first, without loading them all.
+func (s *PostgresStore) Each(ctx context.Context, q ExportQuery, fn func(Entry) error) error {
+	rows, err := s.db.QueryContext(ctx, `
+	SELECT `+entryColumns+` FROM food_entries
+	WHERE user_id = $1
+		AND ($2::timestamptz IS NULL OR consumed_at >= $2)
+		AND ($3::timestamptz IS NULL OR consumed_at < $3)
+	ORDER BY consumed_at, id;
+	`, q.UserID, nullTime(q.From), nullTime(q.To))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		e, err := scanEntry(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func nullTime(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return t
+}
+
+// ExportStatusTrailer is the trailer that tells a complete export from one that
+// failed after the response started.
+const ExportStatusTrailer = "Export-Status"
+
+// Values of ExportStatusTrailer.
+const (
+	ExportComplete = "complete"
+	ExportFailed   = "failed"
+)
+
+// exportFailed is the message of the final record of a failed export.
+const exportFailed = "the export failed; records after the last daily_total are missing"
+
+// exportWriter writes one export format.
+type exportWriter interface {
+	entry(day string, e Entry) error
+	total(day string, count int, totals NutrientTotals) error
+	// fail ends an export that stopped early with an error record.
+	fail() error
+	flush() error
+}
+
+var csvHeader = []string{"record", "date", "id", "name", "portion", "unit", "meal_type", "consumed_at",
+	"calories", "protein", "carbohydrates", "fat", "fiber", "sugar", "entry_count"}
+
+// csvText keeps a spreadsheet from reading user text as a formula by quoting
+// values that start like one.
+func csvText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// csvWriter writes entries and daily totals as rows of one table; the record
+// column tells them apart.
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func newCSVWriter(out io.Writer) (*csvWriter, error) {
+	w := csv.NewWriter(out)
+	return &csvWriter{w: w}, w.Write(csvHeader)
+}
+
+func (c *csvWriter) entry(day string, e Entry) error {
+	return c.w.Write([]string{"entry", day, strconv.Itoa(e.ID), csvText(e.Name), strconv.Itoa(e.Portion), csvText(e.Unit), e.MealType,
+		e.ConsumedAt.Format(time.RFC3339), strconv.Itoa(e.Calories), strconv.Itoa(e.Protein), strconv.Itoa(e.Carbohydrates),
+		strconv.Itoa(e.Fat), strconv.Itoa(e.Fiber), strconv.Itoa(e.Sugar), ""})
+}
+
+func (c *csvWriter) total(day string, count int, t NutrientTotals) error {
+	return c.w.Write([]string{"daily_total", day, "", "", "", "", "", "",
+		strconv.Itoa(t.Calories), strconv.Itoa(t.Protein), strconv.Itoa(t.Carbohydrates),
+		strconv.Itoa(t.Fat), strconv.Itoa(t.Fiber), strconv.Itoa(t.Sugar), strconv.Itoa(count)})
+}
+
+// fail writes an error row, with the message in the name column.
+func (c *csvWriter) fail() error {
+	row := make([]string, len(csvHeader))
+	row[0], row[3] = "error", exportFailed
+	return c.w.Write(row)
+}
+
+func (c *csvWriter) flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// exportRecord is one line of the NDJSON export.
+type exportRecord struct {
+	Type       string          `json:"type"`
+	Date       string          `json:"date"`
+	Entry      *Entry          `json:"entry,omitempty"`
+	EntryCount int             `json:"entry_count,omitempty"`
+	Totals     *NutrientTotals `json:"totals,omitempty"`
+	Error      string          `json:"error,omitempty"`
+}
+
+type ndjsonWriter struct {
+	enc *json.Encoder
+}
+
+func (n *ndjsonWriter) entry(day string, e Entry) error {
+	return n.enc.Encode(exportRecord{Type: "entry", Date: day, Entry: &e})
+}
+
+func (n *ndjsonWriter) total(day string, count int, t NutrientTotals) error {
+	return n.enc.Encode(exportRecord{Type: "daily_total", Date: day, EntryCount: count, Totals: &t})
+}
+
+func (n *ndjsonWriter) fail() error {
+	return n.enc.Encode(exportRecord{Type: "error", Error: exportFailed})
+}
+
+func (n *ndjsonWriter) flush() error {
+	return nil
+}
+
+// export writes the entries streamed by each, following each day's entries with
+// that day's totals. Days are those of loc. afterDay runs once a day is complete.
+func export(w exportWriter, loc *time.Location, each func(fn func(Entry) error) error, afterDay func()) error {
+	var day string
+	var count int
+	var totals NutrientTotals
+	closeDay := func() error {
+		if count == 0 {
+			return nil
+		}
+		if err := w.total(day, count, totals); err != nil {
+			return err
+		}
+		if err := w.flush(); err != nil {
+			return err
+		}
+		afterDay()
+		return nil
+	}
+
+	err := each(func(e Entry) error {
+		d := e.ConsumedAt.In(loc).Format(time.DateOnly)
+		if d != day {
+			if err := closeDay(); err != nil {
+				return err
+			}
+			day, count, totals = d, 0, NutrientTotals{}
+		}
+		count++
+		totals.Calories += e.Calories
+		totals.Protein += e.Protein
+		totals.Carbohydrates += e.Carbohydrates
+		totals.Fat += e.Fat
+		totals.Fiber += e.Fiber
+		totals.Sugar += e.Sugar
+		return w.entry(d, e)
+	})
+	if err != nil {
+		return err
+	}
+	if err := closeDay(); err != nil {
+		return err
+	}
+	return w.flush()
+}
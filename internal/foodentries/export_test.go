@@ -0,0 +1,147 @@
+package foodentries
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coloradocollective/go-capstone-starter/internal/auth/authtest"
+)
+
+func seedExport(t *testing.T, mux *http.ServeMux) {
+	t.Helper()
+	for _, at := range []string{"2024-05-01T08:30:00", "2024-05-01T23:30:00", "2024-05-02T12:00:00"} {
+		body := strings.Replace(oatmeal, "2024-05-01T08:30:00", at, 1)
+		if rec := authtest.Request(t, mux, http.MethodPost, "/api/food-entries", 4, body); rec.Code != http.StatusCreated {
+			t.Fatalf("expected 201, got %d", rec.Code)
+		}
+	}
+}
+
+func TestExport_CSV(t *testing.T) {
+	mux := newZonedServer(newMemoryStore(), zones{4: denver(t)})
+	seedExport(t, mux)
+
+	rec := authtest.Request(t, mux, http.MethodGet, "/api/food-entries/export?from=2024-05-01&to=2024-05-02", 4, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Content-Disposition"); got != `attachment; filename="food-entries-from-2024-05-01-to-2024-05-02.csv"` {
+		t.Errorf("unexpected Content-Disposition %q", got)
+	}
+	rows, err := csv.NewReader(rec.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid CSV: %v", err)
+	}
+	var records []string
+	for _, row := range rows[1:] {
+		records = append(records, row[0]+" "+row[1])
+	}
+	want := "entry 2024-05-01,entry 2024-05-01,daily_total 2024-05-01,entry 2024-05-02,daily_total 2024-05-02"
+	if got := strings.Join(records, ","); got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+	if total := rows[3]; total[8] != "600" || total[14] != "2" {
+		t.Errorf("expected 600 calories over 2 entries, got %v", total)
+	}
+	if got := rec.Result().Trailer.Get(ExportStatusTrailer); got != ExportComplete {
+		t.Errorf("expected the export to be complete, got %q", got)
+	}
+}
+
+func TestExport_ReportsFailureAfterTheResponseStarted(t *testing.T) {
+	for _, format := range []string{FormatCSV, FormatNDJSON} {
+		store := newMemoryStore()
+		mux := newServer(store)
+		seedExport(t, mux)
+		store.failEach = errors.New("connection reset")
+
+		rec := authtest.Request(t, mux, http.MethodGet, "/api/food-entries/export?format="+format, 4, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected the 200 already sent, got %d", format, rec.Code)
+		}
+		if got := rec.Result().Trailer.Get(ExportStatusTrailer); got != ExportFailed {
+			t.Errorf("%s: expected a failed trailer, got %q", format, got)
+		}
+		lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+		last := lines[len(lines)-1]
+		if !strings.HasPrefix(last, "error,") && !strings.HasPrefix(last, `{"type":"error"`) {
+			t.Errorf("%s: expected a final error record, got %q", format, last)
+		}
+		if strings.Contains(rec.Body.String(), "connection reset") {
+			t.Errorf("%s: expected the store error to stay out of the export", format)
+		}
+	}
+}
+
+func TestCSVText(t *testing.T) {
+	for in, want := range map[string]string{
+		"Oatmeal":                   "Oatmeal",
+		`=HYPERLINK("http://x", 1)`: `'=HYPERLINK("http://x", 1)`,
+		"+1 cup":                    "'+1 cup",
+		"-2":                        "'-2",
+		"@SUM(A1)":                  "'@SUM(A1)",
+		"":                          "",
+	} {
+		if got := csvText(in); got != want {
+			t.Errorf("csvText(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestExport_NDJSON(t *testing.T) {
+	mux := newServer(newMemoryStore())
+	seedExport(t, mux)
+
+	rec := authtest.Request(t, mux, http.MethodGet, "/api/food-entries/export?format=ndjson&from=2024-05-02", 4, "")
+	if got := rec.Header().Get("Content-Type"); got != "application/x-ndjson" {
+		t.Errorf("unexpected Content-Type %q", got)
+	}
+	var records []exportRecord
+	scanner := bufio.NewScanner(rec.Body)
+	for scanner.Scan() {
+		var r exportRecord
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			t.Fatalf("invalid line %q: %v", scanner.Text(), err)
+		}
+		records = append(records, r)
+	}
+	if len(records) != 2 || records[0].Entry.ID != 3 || records[1].Type != "daily_total" || records[1].Totals.Calories != 300 {
+		t.Errorf("unexpected records %+v", records)
+	}
+}
+
+func TestExport_StopsOnError(t *testing.T) {
+	entries := []Entry{{ID: 1, ConsumedAt: time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)}}
+	broken := errors.New("connection reset")
+	each := func(fn func(Entry) error) error {
+		for _, e := range entries {
+			if err := fn(e); err != nil {
+				return err
+			}
+		}
+		return broken
+	}
+	var out strings.Builder
+	err := export(&ndjsonWriter{enc: json.NewEncoder(&out)}, time.UTC, each, func() {})
+	if !errors.Is(err, broken) {
+		t.Fatalf("expected the store error, got %v", err)
+	}
+	if strings.Contains(out.String(), "daily_total") {
+		t.Error("expected no totals for a day that was cut short")
+	}
+}
+
+func TestExport_Rejects(t *testing.T) {
+	mux := newServer(newMemoryStore())
+	for _, query := range []string{"format=xml", "from=May", "to=soon"} {
+		if rec := authtest.Request(t, mux, http.MethodGet, "/api/food-entries/export?"+query, 4, ""); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, rec.Code)
+		}
+	}
+}
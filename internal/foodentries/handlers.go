@@ -0,0 +1,503 @@
+package foodentries
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/coloradocollective/go-capstone-starter/internal/auth"
+	"github.com/coloradocollective/go-capstone-starter/internal/profile"
+)
+
+// MealTypes lists the accepted values of meal_type.
+var MealTypes = []string{"breakfast", "lunch", "dinner", "snack"}
+
+type handler struct {
+	store     Store
+	locations profile.Locations
+	recipes   Recipes
+}
+
+// Handlers registers the food entry endpoints. Events go to FOOD_ENTRY_EVENTS_TOPIC
+// through the outbox. Days are computed in each user's stored time zone.
+func Handlers(db *sql.DB, authenticator auth.Authenticator) func(mux *http.ServeMux) {
+	store := NewPostgresStore(db, EventsTopic())
+	return routes(store, profile.NewPostgresStore(db), store, authenticator)
+}
+
+// EventsTopic is FOOD_ENTRY_EVENTS_TOPIC, or DefaultEventsTopic when it is unset.
+func EventsTopic() string {
+	if topic := os.Getenv("FOOD_ENTRY_EVENTS_TOPIC"); topic != "" {
+		return topic
+	}
+	return DefaultEventsTopic
+}
+
+func routes(store Store, locations profile.Locations, recipes Recipes, authenticator auth.Authenticator) func(mux *http.ServeMux) {
+	h := &handler{store: store, locations: locations, recipes: recipes}
+	return func(mux *http.ServeMux) {
+		mux.HandleFunc("GET /api/food-entries", authenticator.Require(h.list))
+		mux.HandleFunc("POST /api/food-entries", authenticator.Require(h.create))
+		mux.HandleFunc("POST /api/food-entries/batch", authenticator.Require(h.createBatch))
+		mux.HandleFunc("POST /api/food-entries/recipe", authenticator.Require(h.createFromRecipe))
+		mux.HandleFunc("PUT /api/food-entries/{id}", authenticator.Require(h.update))
+		mux.HandleFunc("DELETE /api/food-entries/{id}", authenticator.Require(h.delete))
+		mux.HandleFunc("GET /api/food-entries/rollup", authenticator.Require(h.rollup))
+		mux.HandleFunc("GET /api/food-entries/export", authenticator.Require(h.export))
+	}
+}
+
+// Input is the request body for creating or replacing an entry. consumed_at is
+// either RFC 3339 or, as the add-entry form sends it, a local time without offset
+// that is taken in the user's time zone.
+type Input struct {
+	Name          string `json:"name"`
+	Portion       int    `json:"portion"`
+	Unit          string `json:"unit"`
+	Calories      int    `json:"calories"`
+	Protein       int    `json:"protein"`
+	Carbohydrates int    `json:"carbohydrates"`
+	Fat           int    `json:"fat"`
+	Fiber         int    `json:"fiber"`
+	Sugar         int    `json:"sugar"`
+	MealType      string `json:"meal_type"`
+	ConsumedAt    string `json:"consumed_at"`
+}
+
+func (in Input) entry(userID int, loc *time.Location) (Entry, error) {
+	consumedAt, err := parseConsumedAt(in.ConsumedAt, loc)
+	if err != nil {
+		return Entry{}, err
+	}
+	entry := Entry{
+		UserID:        userID,
+		Name:          in.Name,
+		Portion:       in.Portion,
+		Unit:          in.Unit,
+		Calories:      in.Calories,
+		Protein:       in.Protein,
+		Carbohydrates: in.Carbohydrates,
+		Fat:           in.Fat,
+		Fiber:         in.Fiber,
+		Sugar:         in.Sugar,
+		MealType:      in.MealType,
+		ConsumedAt:    consumedAt,
+	}
+	if err := Validate(entry); err != nil {
+		return Entry{}, err
+	}
+	return entry, nil
+}
+
+// Validate checks the fields of an entry that fit in food_entries.
+func Validate(e Entry) error {
+	if e.Name == "" || len(e.Name) > 255 {
+		return errors.New("name is required and must be at most 255 characters")
+	}
+	if e.Unit == "" || len(e.Unit) > 20 {
+		return errors.New("unit is required and must be at most 20 characters")
+	}
+	if !validMealType(e.MealType) {
+		return fmt.Errorf("meal_type must be one of %v", MealTypes)
+	}
+	if e.Portion < 0 || e.Calories < 0 || e.Protein < 0 || e.Carbohydrates < 0 || e.Fat < 0 || e.Fiber < 0 || e.Sugar < 0 {
+		return errors.New("portion and nutrient values must not be negative")
+	}
+	return nil
+}
+
+func validMealType(mealType string) bool {
+	for _, m := range MealTypes {
+		if m == mealType {
+			return true
+		}
+	}
+	return false
+}
+
+func parseConsumedAt(raw string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02T15:04:05", raw, loc); err == nil {
+		return t, nil
+	}
+	return time.Time{}, errors.New("consumed_at must be a timestamp such as 2024-05-01T08:30:00Z")
+}
+
+// location looks up the user's time zone, answering with an error if it cannot.
+func (h *handler) location(w http.ResponseWriter, r *http.Request, userID int) (*time.Location, bool) {
+	loc, err := h.locations.Location(r.Context(), userID)
+	if err != nil {
+		log.Printf("Failed to load time zone for user %d: %v", userID, err)
+		http.Error(w, "Failed to load time zone", http.StatusInternalServerError)
+		return nil, false
+	}
+	return loc, true
+}
+
+func (h *handler) decodeEntry(w http.ResponseWriter, r *http.Request, userID int) (Entry, bool) {
+	var in Input
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return Entry{}, false
+	}
+	loc, ok := h.location(w, r, userID)
+	if !ok {
+		return Entry{}, false
+	}
+	entry, err := in.entry(userID, loc)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return Entry{}, false
+	}
+	return entry, true
+}
+
+func (h *handler) create(w http.ResponseWriter, r *http.Request, userID int) {
+	entry, ok := h.decodeEntry(w, r, userID)
+	if !ok {
+		return
+	}
+
+	created, err := h.store.Create(r.Context(), entry)
+	if err != nil {
+		log.Printf("Failed to create food entry for user %d: %v", userID, err)
+		http.Error(w, "Failed to create food entry", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(created); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
+
+// MaxBatchEntries caps the entries created by one batch request.
+const MaxBatchEntries = 50
+
+// BatchInput is the request body for creating several entries at once.
+type BatchInput struct {
+	Entries []Input `json:"entries"`
+}
+
+// createBatch creates all entries of the request in one transaction, so a meal
+// logged in one go is never half saved.
+func (h *handler) createBatch(w http.ResponseWriter, r *http.Request, userID int) {
+	var in BatchInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if len(in.Entries) == 0 || len(in.Entries) > MaxBatchEntries {
+		http.Error(w, fmt.Sprintf("entries must hold 1 to %d entries", MaxBatchEntries), http.StatusBadRequest)
+		return
+	}
+	loc, ok := h.location(w, r, userID)
+	if !ok {
+		return
+	}
+	entries := make([]Entry, len(in.Entries))
+	for i, input := range in.Entries {
+		entry, err := input.entry(userID, loc)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("entries[%d]: %v", i, err), http.StatusBadRequest)
+			return
+		}
+		entries[i] = entry
+	}
+
+	created, err := h.store.CreateAll(r.Context(), entries)
+	if err != nil {
+		log.Printf("Failed to create %d food entries for user %d: %v", len(entries), userID, err)
+		http.Error(w, "Failed to create food entries", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(map[string][]Entry{"entries": created}); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
+
+func (h *handler) update(w http.ResponseWriter, r *http.Request, userID int) {
+	entryID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid food entry id", http.StatusBadRequest)
+		return
+	}
+	entry, ok := h.decodeEntry(w, r, userID)
+	if !ok {
+		return
+	}
+	entry.ID = entryID
+
+	updated, err := h.store.Update(r.Context(), entry)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Food entry not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to update food entry %d: %v", entryID, err)
+		http.Error(w, "Failed to update food entry", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, updated)
+}
+
+func (h *handler) delete(w http.ResponseWriter, r *http.Request, userID int) {
+	entryID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid food entry id", http.StatusBadRequest)
+		return
+	}
+
+	err = h.store.Delete(r.Context(), userID, entryID)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Food entry not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to delete food entry %d: %v", entryID, err)
+		http.Error(w, "Failed to delete food entry", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// listQuery reads the listing parameters:
+//
+//	meal_type   comma-separated meal types; spaces around them are ignored
+//	q           case-insensitive substring of the name
+//	min_<n>     lower bound of nutrient n, e.g. min_protein=20; max_<n> likewise
+//	from, to    inclusive dates in the user's time zone
+//	sort        column, "-" prefixed for descending; defaults to -consumed_at
+//	cursor      next_cursor of the previous page
+//	limit       page size, at most MaxListLimit
+func listQuery(r *http.Request, userID int, loc *time.Location) (ListQuery, error) {
+	params := r.URL.Query()
+	q := ListQuery{UserID: userID, Search: strings.TrimSpace(params.Get("q")), Limit: DefaultListLimit, Ranges: map[string]Range{}}
+
+	if raw := params.Get("meal_type"); raw != "" {
+		for _, m := range strings.Split(raw, ",") {
+			m = strings.TrimSpace(m)
+			if !validMealType(m) {
+				return ListQuery{}, fmt.Errorf("meal_type must be one of %v", MealTypes)
+			}
+			q.MealTypes = append(q.MealTypes, m)
+		}
+	}
+	for _, n := range Nutrients {
+		lower, err := bound(params, "min_"+n)
+		if err != nil {
+			return ListQuery{}, err
+		}
+		upper, err := bound(params, "max_"+n)
+		if err != nil {
+			return ListQuery{}, err
+		}
+		q.Ranges[n] = Range{Min: lower, Max: upper}
+	}
+	if raw := params.Get("from"); raw != "" {
+		from, err := time.ParseInLocation(time.DateOnly, raw, loc)
+		if err != nil {
+			return ListQuery{}, errors.New("from must be a date such as 2024-05-01")
+		}
+		q.From = from
+	}
+	if raw := params.Get("to"); raw != "" {
+		to, err := time.ParseInLocation(time.DateOnly, raw, loc)
+		if err != nil {
+			return ListQuery{}, errors.New("to must be a date such as 2024-05-31")
+		}
+		q.To = to.AddDate(0, 0, 1)
+	}
+	if raw := params.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > MaxListLimit {
+			return ListQuery{}, fmt.Errorf("limit must be between 1 and %d", MaxListLimit)
+		}
+		q.Limit = limit
+	}
+	var err error
+	if q.Sort, err = ParseSort(params.Get("sort")); err != nil {
+		return ListQuery{}, err
+	}
+	if raw := params.Get("cursor"); raw != "" {
+		if q.After, err = DecodeCursor(raw, q.Sort); err != nil {
+			return ListQuery{}, err
+		}
+	}
+	return q, nil
+}
+
+// bound reads an optional whole-number parameter.
+func bound(params url.Values, name string) (*int, error) {
+	raw := params.Get(name)
+	if raw == "" {
+		return nil, nil
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be a whole number", name)
+	}
+	return &v, nil
+}
+
+// list serves GET /api/food-entries, one page at a time.
+func (h *handler) list(w http.ResponseWriter, r *http.Request, userID int) {
+	loc, ok := h.location(w, r, userID)
+	if !ok {
+		return
+	}
+	q, err := listQuery(r, userID, loc)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	page, err := h.store.List(r.Context(), q)
+	if err != nil {
+		log.Printf("Failed to list food entries for user %d: %v", userID, err)
+		http.Error(w, "Failed to list food entries", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, page)
+}
+
+// rollup serves GET /api/food-entries/rollup?period=day|week|month&from=YYYY-MM-DD&to=YYYY-MM-DD.
+// Both dates are inclusive and days are those of the user's time zone; period defaults to day.
+func (h *handler) rollup(w http.ResponseWriter, r *http.Request, userID int) {
+	q := RollupQuery{UserID: userID, Period: r.URL.Query().Get("period")}
+	switch q.Period {
+	case "":
+		q.Period = PeriodDay
+	case PeriodDay, PeriodWeek, PeriodMonth:
+	default:
+		http.Error(w, "period must be day, week or month", http.StatusBadRequest)
+		return
+	}
+	var err error
+	if q.From, err = time.Parse(time.DateOnly, r.URL.Query().Get("from")); err != nil {
+		http.Error(w, "from must be a date such as 2024-05-01", http.StatusBadRequest)
+		return
+	}
+	if q.To, err = time.Parse(time.DateOnly, r.URL.Query().Get("to")); err != nil {
+		http.Error(w, "to must be a date such as 2024-05-31", http.StatusBadRequest)
+		return
+	}
+	if q.To.Before(q.From) {
+		http.Error(w, "to must not be before from", http.StatusBadRequest)
+		return
+	}
+	var ok bool
+	if q.Location, ok = h.location(w, r, userID); !ok {
+		return
+	}
+	if _, err := emptyBuckets(q); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	buckets, err := h.store.Rollup(r.Context(), q)
+	if err != nil {
+		log.Printf("Failed to roll up food entries for user %d: %v", userID, err)
+		http.Error(w, "Failed to roll up food entries", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, Rollup{
+		Period:   q.Period,
+		From:     q.From.Format(time.DateOnly),
+		To:       q.To.Format(time.DateOnly),
+		TimeZone: q.Location.String(),
+		Buckets:  buckets,
+	})
+}
+
+// export serves GET /api/food-entries/export?format=csv|ndjson&from=&to=, streaming
+// the entries with the totals of each day. Both dates are optional and inclusive.
+// An export that fails part way ends with an error record, and its Export-Status
+// trailer says failed rather than complete.
+func (h *handler) export(w http.ResponseWriter, r *http.Request, userID int) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = FormatCSV
+	}
+	if format != FormatCSV && format != FormatNDJSON {
+		http.Error(w, "format must be csv or ndjson", http.StatusBadRequest)
+		return
+	}
+	loc, ok := h.location(w, r, userID)
+	if !ok {
+		return
+	}
+	q := ExportQuery{UserID: userID}
+	name := "food-entries"
+	if raw := r.URL.Query().Get("from"); raw != "" {
+		from, err := time.ParseInLocation(time.DateOnly, raw, loc)
+		if err != nil {
+			http.Error(w, "from must be a date such as 2024-05-01", http.StatusBadRequest)
+			return
+		}
+		q.From, name = from, name+"-from-"+raw
+	}
+	if raw := r.URL.Query().Get("to"); raw != "" {
+		to, err := time.ParseInLocation(time.DateOnly, raw, loc)
+		if err != nil {
+			http.Error(w, "to must be a date such as 2024-05-31", http.StatusBadRequest)
+			return
+		}
+		q.To, name = to.AddDate(0, 0, 1), name+"-to-"+raw
+	}
+
+	var out exportWriter
+	if format == FormatCSV {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		csvOut, err := newCSVWriter(w)
+		if err != nil {
+			log.Printf("Failed to write export for user %d: %v", userID, err)
+			return
+		}
+		out = csvOut
+	} else {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		out = &ndjsonWriter{enc: json.NewEncoder(w)}
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, name, format))
+	w.Header().Set("Trailer", ExportStatusTrailer)
+
+	// Send each completed day on its way rather than buffering the response.
+	flusher, _ := w.(http.Flusher)
+	afterDay := func() {
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	each := func(fn func(Entry) error) error { return h.store.Each(r.Context(), q, fn) }
+	if err := export(out, loc, each, afterDay); err != nil {
+		// The status line has gone out, so the failure is reported in the body and
+		// the trailer instead.
+		log.Printf("Failed to export food entries for user %d: %v", userID, err)
+		if err := errors.Join(out.fail(), out.flush()); err != nil {
+			log.Printf("Failed to end the export for user %d: %v", userID, err)
+		}
+		w.Header().Set(ExportStatusTrailer, ExportFailed)
+		return
+	}
+	w.Header().Set(ExportStatusTrailer, ExportComplete)
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
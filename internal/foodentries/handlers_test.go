@@ -0,0 +1,311 @@
+package foodentries
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coloradocollective/go-capstone-starter/internal/auth/authtest"
+)
+
+type memoryStore struct {
+	entries map[int]Entry
+	nextID  int
+	// failCreate makes CreateAll fail for a batch holding an entry of that name.
+	failCreate string
+	// failEach makes Each fail after streaming the entries.
+	failEach error
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{entries: map[int]Entry{}}
+}
+
+func (s *memoryStore) Create(_ context.Context, entry Entry) (Entry, error) {
+	s.nextID++
+	entry.ID = s.nextID
+	s.entries[entry.ID] = entry
+	return entry, nil
+}
+
+// CreateAll keeps nothing when one entry fails, like the transaction it stands in for.
+func (s *memoryStore) CreateAll(ctx context.Context, entries []Entry) ([]Entry, error) {
+	if s.failCreate != "" {
+		for _, e := range entries {
+			if e.Name == s.failCreate {
+				return nil, errors.New("insert failed")
+			}
+		}
+	}
+	var created []Entry
+	for _, e := range entries {
+		e, _ = s.Create(ctx, e)
+		created = append(created, e)
+	}
+	return created, nil
+}
+
+func (s *memoryStore) Update(_ context.Context, entry Entry) (Entry, error) {
+	if existing, ok := s.entries[entry.ID]; !ok || existing.UserID != entry.UserID {
+		return Entry{}, sql.ErrNoRows
+	}
+	s.entries[entry.ID] = entry
+	return entry, nil
+}
+
+func (s *memoryStore) Delete(_ context.Context, userID, entryID int) error {
+	if existing, ok := s.entries[entryID]; !ok || existing.UserID != userID {
+		return sql.ErrNoRows
+	}
+	delete(s.entries, entryID)
+	return nil
+}
+
+// List filters and sorts in Go the way PostgresStore does in SQL.
+func (s *memoryStore) List(_ context.Context, q ListQuery) (Page, error) {
+	var matched []Entry
+	for _, e := range s.entries {
+		if e.UserID != q.UserID || (len(q.MealTypes) > 0 && !slices.Contains(q.MealTypes, e.MealType)) ||
+			!strings.Contains(strings.ToLower(e.Name), strings.ToLower(q.Search)) ||
+			(!q.From.IsZero() && e.ConsumedAt.Before(q.From)) || (!q.To.IsZero() && !e.ConsumedAt.Before(q.To)) {
+			continue
+		}
+		inRange := true
+		for n, r := range q.Ranges {
+			v := nutrient(e, n)
+			if (r.Min != nil && v < *r.Min) || (r.Max != nil && v > *r.Max) {
+				inRange = false
+			}
+		}
+		if inRange {
+			matched = append(matched, e)
+		}
+	}
+	compare := func(a, b Entry) int {
+		var c int
+		switch q.Sort.Column {
+		case "consumed_at":
+			c = a.ConsumedAt.Compare(b.ConsumedAt)
+		case "created_at":
+			c = a.CreatedAt.Compare(b.CreatedAt)
+		case "name":
+			c = strings.Compare(a.Name, b.Name)
+		default:
+			c = nutrient(a, q.Sort.Column) - nutrient(b, q.Sort.Column)
+		}
+		if c == 0 {
+			c = a.ID - b.ID
+		}
+		if q.Sort.Desc {
+			return -c
+		}
+		return c
+	}
+	slices.SortFunc(matched, compare)
+
+	var entries []Entry
+	for _, e := range matched {
+		if q.After != nil {
+			// An entry comes after the cursor if it sorts after the cursor's own position.
+			if compare(e, s.entries[q.After.ID]) <= 0 {
+				continue
+			}
+		}
+		if len(entries) == q.Limit+1 {
+			break
+		}
+		entries = append(entries, e)
+	}
+	return page(entries, q), nil
+}
+
+func (s *memoryStore) Each(_ context.Context, q ExportQuery, fn func(Entry) error) error {
+	var entries []Entry
+	for _, e := range s.entries {
+		if e.UserID == q.UserID && (q.From.IsZero() || !e.ConsumedAt.Before(q.From)) && (q.To.IsZero() || e.ConsumedAt.Before(q.To)) {
+			entries = append(entries, e)
+		}
+	}
+	slices.SortFunc(entries, func(a, b Entry) int { return a.ConsumedAt.Compare(b.ConsumedAt) })
+	for _, e := range entries {
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return s.failEach
+}
+
+// Rollup aggregates in Go the way PostgresStore does in SQL.
+func (s *memoryStore) Rollup(_ context.Context, q RollupQuery) ([]Bucket, error) {
+	layout, err := emptyBuckets(q)
+	if err != nil {
+		return nil, err
+	}
+	byLabel := map[string]*Bucket{}
+	days := map[string]map[string]bool{}
+	for _, e := range s.entries {
+		if e.UserID != q.UserID || e.ConsumedAt.Before(q.start()) || !e.ConsumedAt.Before(q.end()) {
+			continue
+		}
+		local := e.ConsumedAt.In(q.Location)
+		label := periodLabel(periodStart(local, q.Period), q.Period)
+		b, ok := byLabel[label]
+		if !ok {
+			b = &Bucket{Label: label}
+			byLabel[label] = b
+			days[label] = map[string]bool{}
+		}
+		b.EntryCount++
+		days[label][local.Format(time.DateOnly)] = true
+		b.Totals.Calories += e.Calories
+		b.Totals.Protein += e.Protein
+		b.Totals.Carbohydrates += e.Carbohydrates
+		b.Totals.Fat += e.Fat
+		b.Totals.Fiber += e.Fiber
+		b.Totals.Sugar += e.Sugar
+	}
+	var found []Bucket
+	for label, b := range byLabel {
+		b.DaysLogged = len(days[label])
+		b.DailyAverage = NutrientAverages{
+			Calories:      average(b.Totals.Calories, b.DaysLogged),
+			Protein:       average(b.Totals.Protein, b.DaysLogged),
+			Carbohydrates: average(b.Totals.Carbohydrates, b.DaysLogged),
+			Fat:           average(b.Totals.Fat, b.DaysLogged),
+			Fiber:         average(b.Totals.Fiber, b.DaysLogged),
+			Sugar:         average(b.Totals.Sugar, b.DaysLogged),
+		}
+		found = append(found, *b)
+	}
+	return fillBuckets(layout, found), nil
+}
+
+// zones maps users to time zones; everyone else is in UTC.
+type zones map[int]*time.Location
+
+func (z zones) Location(_ context.Context, userID int) (*time.Location, error) {
+	if loc, ok := z[userID]; ok {
+		return loc, nil
+	}
+	return time.UTC, nil
+}
+
+func newServer(store Store) *http.ServeMux {
+	return newZonedServer(store, zones{})
+}
+
+func newZonedServer(store Store, locations zones) *http.ServeMux {
+	return newRecipeServer(store, locations, recipeBook{})
+}
+
+func newRecipeServer(store Store, locations zones, recipes recipeBook) *http.ServeMux {
+	mux := http.NewServeMux()
+	routes(store, locations, recipes, authtest.Auth)(mux)
+	return mux
+}
+
+const oatmeal = `{"name": "Oatmeal", "portion": 1, "unit": "bowl", "calories": 300, "protein": 10,
+	"carbohydrates": 54, "fat": 5, "fiber": 8, "sugar": 1, "meal_type": "breakfast", "consumed_at": "2024-05-01T08:30:00"}`
+
+func TestCreate(t *testing.T) {
+	store := newMemoryStore()
+	rec := authtest.Request(t, newServer(store), http.MethodPost, "/api/food-entries", 4, oatmeal)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var created Entry
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	want := time.Date(2024, 5, 1, 8, 30, 0, 0, time.UTC)
+	if created.UserID != 4 || created.Name != "Oatmeal" || !created.ConsumedAt.Equal(want) {
+		t.Fatalf("unexpected entry: %+v", created)
+	}
+}
+
+func TestCreate_Rejects(t *testing.T) {
+	mux := newServer(newMemoryStore())
+
+	if rec := authtest.Request(t, mux, http.MethodPost, "/api/food-entries", 0, oatmeal); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without a token, got %d", rec.Code)
+	}
+	invalid := map[string]string{
+		"meal type": strings.Replace(oatmeal, `"breakfast"`, `"brunch"`, 1),
+		"negative":  strings.Replace(oatmeal, `"calories": 300`, `"calories": -1`, 1),
+		"no name":   strings.Replace(oatmeal, `"Oatmeal"`, `""`, 1),
+		"bad time":  strings.Replace(oatmeal, `2024-05-01T08:30:00`, `yesterday`, 1),
+		"not json":  "{",
+	}
+	for name, body := range invalid {
+		if rec := authtest.Request(t, mux, http.MethodPost, "/api/food-entries", 4, body); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", name, rec.Code)
+		}
+	}
+}
+
+func TestCreateBatch(t *testing.T) {
+	store := newMemoryStore()
+	mux := newServer(store)
+	coffee := strings.Replace(oatmeal, `"Oatmeal"`, `"Black coffee"`, 1)
+
+	rec := authtest.Request(t, mux, http.MethodPost, "/api/food-entries/batch", 4, `{"entries": [`+oatmeal+`, `+coffee+`]}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var created struct{ Entries []Entry }
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if len(created.Entries) != 2 || created.Entries[1].Name != "Black coffee" || created.Entries[1].UserID != 4 {
+		t.Fatalf("unexpected entries: %+v", created.Entries)
+	}
+
+	invalid := strings.Replace(coffee, `"breakfast"`, `"brunch"`, 1)
+	rec = authtest.Request(t, mux, http.MethodPost, "/api/food-entries/batch", 4, `{"entries": [`+oatmeal+`, `+invalid+`]}`)
+	if rec.Code != http.StatusBadRequest || !strings.HasPrefix(rec.Body.String(), "entries[1]:") {
+		t.Errorf("expected the invalid entry to be named, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := authtest.Request(t, mux, http.MethodPost, "/api/food-entries/batch", 4, `{"entries": []}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an empty batch, got %d", rec.Code)
+	}
+
+	store.failCreate = "Black coffee"
+	rec = authtest.Request(t, mux, http.MethodPost, "/api/food-entries/batch", 4, `{"entries": [`+oatmeal+`, `+coffee+`]}`)
+	if rec.Code != http.StatusInternalServerError || len(store.entries) != 2 {
+		t.Errorf("expected a failed batch to create nothing, got %d with %d entries", rec.Code, len(store.entries))
+	}
+}
+
+func TestUpdateAndDelete_OnlyOwnEntries(t *testing.T) {
+	store := newMemoryStore()
+	mux := newServer(store)
+	authtest.Request(t, mux, http.MethodPost, "/api/food-entries", 4, oatmeal)
+
+	if rec := authtest.Request(t, mux, http.MethodPut, "/api/food-entries/1", 5, oatmeal); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 updating another user's entry, got %d", rec.Code)
+	}
+	if rec := authtest.Request(t, mux, http.MethodDelete, "/api/food-entries/1", 5, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 deleting another user's entry, got %d", rec.Code)
+	}
+
+	updated := strings.Replace(oatmeal, `"calories": 300`, `"calories": 350`, 1)
+	if rec := authtest.Request(t, mux, http.MethodPut, "/api/food-entries/1", 4, updated); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if store.entries[1].Calories != 350 {
+		t.Errorf("expected the entry to be updated, got %+v", store.entries[1])
+	}
+	if rec := authtest.Request(t, mux, http.MethodDelete, "/api/food-entries/1", 4, ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", rec.Code)
+	}
+	if len(store.entries) != 0 {
+		t.Errorf("expected the entry to be deleted")
+	}
+}
@@ -0,0 +1,271 @@
+package foodentries
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Listing limits.
+const (
+	DefaultListLimit = 50
+	MaxListLimit     = 200
+)
+
+// Nutrients lists the columns that can be filtered by range.
+var Nutrients = []string{"calories", "protein", "carbohydrates", "fat", "fiber", "sugar"}
+
+// sortColumns are the columns a listing can be ordered by, with the kind of their values.
+var sortColumns = map[string]string{
+	"consumed_at":   "time",
+	"created_at":    "time",
+	"name":          "text",
+	"calories":      "int",
+	"protein":       "int",
+	"carbohydrates": "int",
+	"fat":           "int",
+	"fiber":         "int",
+	"sugar":         "int",
+}
+
+// DefaultSort lists the most recent entries first.
+var DefaultSort = Sort{Column: "consumed_at", Desc: true}
+
+// Sort orders a listing by one column, then by id in the same direction.
+type Sort struct {
+	Column string
+	Desc   bool
+}
+
+// ParseSort accepts a column name, prefixed with "-" for descending order.
+func ParseSort(raw string) (Sort, error) {
+	if raw == "" {
+		return DefaultSort, nil
+	}
+	s := Sort{Column: strings.TrimPrefix(raw, "-"), Desc: strings.HasPrefix(raw, "-")}
+	if _, ok := sortColumns[s.Column]; !ok {
+		return Sort{}, fmt.Errorf("sort must be one of consumed_at, created_at, name or a nutrient, optionally prefixed with -")
+	}
+	return s, nil
+}
+
+// expression is the SQL the listing orders by. created_at is NULL on legacy rows,
+// which sort by consumed_at instead, the value scanEntry gives them.
+func (s Sort) expression() string {
+	if s.Column == "created_at" {
+		return "COALESCE(created_at, consumed_at)"
+	}
+	return s.Column
+}
+
+func (s Sort) String() string {
+	if s.Desc {
+		return "-" + s.Column
+	}
+	return s.Column
+}
+
+// Range bounds a nutrient; nil ends are open.
+type Range struct {
+	Min *int
+	Max *int
+}
+
+// Cursor is the position after the last entry of a page.
+type Cursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    int    `json:"id"`
+}
+
+// ErrInvalidCursor is returned for cursors that were not issued for the requested sort.
+var ErrInvalidCursor = errors.New("cursor is invalid for this listing")
+
+func (c Cursor) Encode() string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// DecodeCursor reads a cursor and checks it belongs to a listing sorted by s.
+func DecodeCursor(raw string, s Sort) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.Sort != s.String() {
+		return nil, ErrInvalidCursor
+	}
+	if _, err := cursorValue(c, s.Column); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// cursorAfter returns the cursor positioned after entry.
+func cursorAfter(entry Entry, s Sort) Cursor {
+	c := Cursor{Sort: s.String(), ID: entry.ID}
+	switch s.Column {
+	case "consumed_at":
+		c.Value = entry.ConsumedAt.UTC().Format(time.RFC3339Nano)
+	case "created_at":
+		c.Value = entry.CreatedAt.UTC().Format(time.RFC3339Nano)
+	case "name":
+		c.Value = entry.Name
+	default:
+		c.Value = strconv.Itoa(nutrient(entry, s.Column))
+	}
+	return c
+}
+
+// cursorValue converts the cursor's value to the type of column.
+func cursorValue(c Cursor, column string) (any, error) {
+	switch sortColumns[column] {
+	case "time":
+		return time.Parse(time.RFC3339Nano, c.Value)
+	case "int":
+		return strconv.Atoi(c.Value)
+	default:
+		return c.Value, nil
+	}
+}
+
+func nutrient(e Entry, name string) int {
+	switch name {
+	case "calories":
+		return e.Calories
+	case "protein":
+		return e.Protein
+	case "carbohydrates":
+		return e.Carbohydrates
+	case "fat":
+		return e.Fat
+	case "fiber":
+		return e.Fiber
+	default:
+		return e.Sugar
+	}
+}
+
+// ListQuery selects one page of a user's entries. From and To bound consumed_at,
+// To exclusive; zero values leave them open.
+type ListQuery struct {
+	UserID    int
+	MealTypes []string
+	Search    string
+	Ranges    map[string]Range
+	From      time.Time
+	To        time.Time
+	Sort      Sort
+	After     *Cursor
+	Limit     int
+}
+
+// Page is one page of a listing. NextCursor is empty on the last page.
+type Page struct {
+	Entries    []Entry `json:"entries"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+}
+
+// page trims entries fetched with one extra row into a page.
+func page(entries []Entry, q ListQuery) Page {
+	p := Page{Entries: entries}
+	if p.Entries == nil {
+		p.Entries = []Entry{}
+	}
+	if len(entries) > q.Limit {
+		p.Entries = entries[:q.Limit]
+		p.NextCursor = cursorAfter(p.Entries[q.Limit-1], q.Sort).Encode()
+	}
+	return p
+}
+
+// likePattern matches names containing search, with LIKE wildcards escaped.
+func likePattern(search string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(search)
+	return "%" + escaped + "%"
+}
+
+// listSQL builds the listing query. Only whitelisted column names are interpolated;
+// every value is a parameter.
+func listSQL(q ListQuery) (string, []any, error) {
+	args := []any{q.UserID}
+	arg := func(v any) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	where := []string{"user_id = $1"}
+	if len(q.MealTypes) > 0 {
+		var placeholders []string
+		for _, m := range q.MealTypes {
+			placeholders = append(placeholders, arg(m))
+		}
+		where = append(where, "meal_type IN ("+strings.Join(placeholders, ", ")+")")
+	}
+	if q.Search != "" {
+		where = append(where, "name ILIKE "+arg(likePattern(q.Search)))
+	}
+	for _, n := range Nutrients {
+		r := q.Ranges[n]
+		if r.Min != nil {
+			where = append(where, n+" >= "+arg(*r.Min))
+		}
+		if r.Max != nil {
+			where = append(where, n+" <= "+arg(*r.Max))
+		}
+	}
+	if !q.From.IsZero() {
+		where = append(where, "consumed_at >= "+arg(q.From))
+	}
+	if !q.To.IsZero() {
+		where = append(where, "consumed_at < "+arg(q.To))
+	}
+
+	direction, comparison := "ASC", ">"
+	if q.Sort.Desc {
+		direction, comparison = "DESC", "<"
+	}
+	if q.After != nil {
+		value, err := cursorValue(*q.After, q.Sort.Column)
+		if err != nil {
+			return "", nil, ErrInvalidCursor
+		}
+		where = append(where, fmt.Sprintf("(%s, id) %s (%s, %s)", q.Sort.expression(), comparison, arg(value), arg(q.After.ID)))
+	}
+
+	query := fmt.Sprintf(`SELECT %s FROM food_entries WHERE %s ORDER BY %s %s, id %s LIMIT %s;`,
+		entryColumns, strings.Join(where, " AND "), q.Sort.expression(), direction, direction, arg(q.Limit+1))
+	return query, args, nil
+}
+
+// List returns one page of entries, using keyset pagination on (sort column, id).
+func (s *PostgresStore) List(ctx context.Context, q ListQuery) (Page, error) {
+	query, args, err := listSQL(q)
+	if err != nil {
+		return Page{}, err
+	}
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return Page{}, err
+	}
+	defer rows.Close()
+
+	var entries []Entry
+	for rows.Next() {
+		e, err := scanEntry(rows)
+		if err != nil {
+			return Page{}, err
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return Page{}, err
+	}
+	return page(entries, q), nil
+}
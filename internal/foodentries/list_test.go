@@ -0,0 +1,145 @@
+package foodentries
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coloradocollective/go-capstone-starter/internal/auth/authtest"
+)
+
+func TestListSQL(t *testing.T) {
+	lower := 20
+	q := ListQuery{
+		UserID:    4,
+		MealTypes: []string{"lunch", "dinner"},
+		Search:    "50%_off",
+		Ranges:    map[string]Range{"protein": {Min: &lower}},
+		Sort:      Sort{Column: "calories", Desc: true},
+		After:     &Cursor{Sort: "-calories", Value: "300", ID: 9},
+		Limit:     10,
+	}
+	query, args, err := listSQL(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"user_id = $1 AND meal_type IN ($2, $3) AND name ILIKE $4 AND protein >= $5 AND (calories, id) < ($6, $7)",
+		"ORDER BY calories DESC, id DESC LIMIT $8",
+	} {
+		if !strings.Contains(query, want) {
+			t.Errorf("expected %q in %s", want, query)
+		}
+	}
+	if args[3] != `%50\%\_off%` || args[5] != 300 || args[7] != 11 {
+		t.Errorf("unexpected args %v", args)
+	}
+
+	q = ListQuery{UserID: 4, Sort: Sort{Column: "created_at"}, After: &Cursor{Sort: "created_at", Value: "2024-05-01T08:30:00Z", ID: 9}, Limit: 10}
+	query, _, err = listSQL(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(query, "(COALESCE(created_at, consumed_at), id) > ($2, $3) ORDER BY COALESCE(created_at, consumed_at) ASC") {
+		t.Errorf("expected created_at to fall back to consumed_at in %s", query)
+	}
+}
+
+func TestList_Pages(t *testing.T) {
+	store := newMemoryStore()
+	mux := newServer(store)
+	for day := 1; day <= 5; day++ {
+		for _, meal := range []string{"breakfast", "dinner"} {
+			body := strings.Replace(oatmeal, "2024-05-01", fmt.Sprintf("2024-05-%02d", day), 1)
+			body = strings.Replace(body, `"breakfast"`, `"`+meal+`"`, 1)
+			authtest.Request(t, mux, http.MethodPost, "/api/food-entries", 4, body)
+		}
+	}
+	authtest.Request(t, mux, http.MethodPost, "/api/food-entries", 5, oatmeal)
+
+	var seen []Entry
+	path := "/api/food-entries?meal_type=breakfast&from=2024-05-02&to=2024-05-05&limit=2"
+	for pages := 0; ; pages++ {
+		if pages > 3 {
+			t.Fatal("too many pages")
+		}
+		rec := authtest.Request(t, mux, http.MethodGet, path, 4, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var p Page
+		if err := json.NewDecoder(rec.Body).Decode(&p); err != nil {
+			t.Fatalf("invalid response: %v", err)
+		}
+		seen = append(seen, p.Entries...)
+		if p.NextCursor == "" {
+			break
+		}
+		path = "/api/food-entries?meal_type=breakfast&from=2024-05-02&to=2024-05-05&limit=2&cursor=" + p.NextCursor
+	}
+
+	if len(seen) != 4 {
+		t.Fatalf("expected 4 breakfasts from May 2 to 5, got %d", len(seen))
+	}
+	for i, e := range seen {
+		want := time.Date(2024, 5, 5-i, 8, 30, 0, 0, time.UTC)
+		if e.MealType != "breakfast" || !e.ConsumedAt.Equal(want) {
+			t.Errorf("entry %d: expected breakfast at %v, got %s at %v", i, want, e.MealType, e.ConsumedAt)
+		}
+	}
+}
+
+func TestList_Filters(t *testing.T) {
+	store := newMemoryStore()
+	mux := newServer(store)
+	authtest.Request(t, mux, http.MethodPost, "/api/food-entries", 4, oatmeal)
+	authtest.Request(t, mux, http.MethodPost, "/api/food-entries", 4, strings.Replace(strings.Replace(oatmeal, "Oatmeal", "Steak", 1), `"protein": 10`, `"protein": 45`, 1))
+	authtest.Request(t, mux, http.MethodPost, "/api/food-entries", 4, strings.Replace(oatmeal, "Oatmeal", "Oat cookies", 1))
+
+	names := func(path string) string {
+		rec := authtest.Request(t, mux, http.MethodGet, path, 4, "")
+		var p Page
+		if err := json.NewDecoder(rec.Body).Decode(&p); err != nil {
+			t.Fatalf("invalid response: %v", err)
+		}
+		var names []string
+		for _, e := range p.Entries {
+			names = append(names, e.Name)
+		}
+		return strings.Join(names, ",")
+	}
+	if got := names("/api/food-entries?q=OAT&sort=name"); got != "Oat cookies,Oatmeal" {
+		t.Errorf("unexpected search result %q", got)
+	}
+	if got := names("/api/food-entries?min_protein=20"); got != "Steak" {
+		t.Errorf("unexpected range result %q", got)
+	}
+	if got := names("/api/food-entries?max_protein=20&sort=-name"); got != "Oatmeal,Oat cookies" {
+		t.Errorf("unexpected range result %q", got)
+	}
+	if got := names("/api/food-entries?meal_type=lunch,%20breakfast&sort=name"); got != "Oat cookies,Oatmeal,Steak" {
+		t.Errorf("unexpected meal type result %q", got)
+	}
+}
+
+func TestList_Rejects(t *testing.T) {
+	mux := newServer(newMemoryStore())
+	ascending := Cursor{Sort: "consumed_at", Value: "2024-05-01T08:30:00Z", ID: 1}.Encode()
+	invalid := map[string]string{
+		"meal type":  "meal_type=brunch",
+		"sort":       "sort=password_hash",
+		"range":      "min_calories=lots",
+		"limit":      "limit=1000",
+		"date":       "from=May",
+		"cursor":     "cursor=not-a-cursor",
+		"other sort": "cursor=" + ascending,
+	}
+	for name, query := range invalid {
+		if rec := authtest.Request(t, mux, http.MethodGet, "/api/food-entries?"+query, 4, ""); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", name, rec.Code)
+		}
+	}
+}
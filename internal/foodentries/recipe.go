@@ -0,0 +1,210 @@
+package foodentries
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strings"
+)
+
+// MaxRecipeServings caps the servings logged in one entry.
+const MaxRecipeServings = 20
+
+var (
+	// ErrNoRecipeNutrition is returned for recipes the analyzer has not fully
+	// estimated yet.
+	ErrNoRecipeNutrition = errors.New("recipe has no nutrition yet")
+	// ErrUnknownRecipeYield is returned for recipes that do not say how many
+	// servings they make, so a serving cannot be sized.
+	ErrUnknownRecipeYield = errors.New("recipe does not say how many servings it makes")
+)
+
+// RecipeTotals is the nutrition of a whole recipe, as recipe_nutrition stores it.
+type RecipeTotals struct {
+	Calories      int `json:"calories"`
+	Protein       int `json:"protein"`
+	Fat           int `json:"fat"`
+	Carbohydrates int `json:"carbohydrates"`
+}
+
+// RecipeNutrition is what logging a recipe needs. Yield is the number of servings
+// the totals make.
+type RecipeNutrition struct {
+	RecipeID int
+	Name     string
+	Yield    int
+	Totals   RecipeTotals
+}
+
+// Entry logs servings of the recipe. Fiber and sugar are not estimated for
+// recipes and stay zero.
+func (n RecipeNutrition) Entry(userID, servings int) Entry {
+	share := func(total int) int {
+		return int(math.Round(float64(total) * float64(servings) / float64(n.Yield)))
+	}
+	name := n.Name
+	if len(name) > 255 {
+		name = strings.ToValidUTF8(name[:255], "")
+	}
+	recipeID := n.RecipeID
+	return Entry{
+		UserID:        userID,
+		Name:          name,
+		Portion:       servings,
+		Unit:          "serving",
+		Calories:      share(n.Totals.Calories),
+		Protein:       share(n.Totals.Protein),
+		Carbohydrates: share(n.Totals.Carbohydrates),
+		Fat:           share(n.Totals.Fat),
+		RecipeID:      &recipeID,
+	}
+}
+
+// Recipes reads the nutrition of recipes.
+type Recipes interface {
+	// RecipeNutrition returns sql.ErrNoRows for unknown recipes,
+	// ErrNoRecipeNutrition for recipes without a complete estimate and
+	// ErrUnknownRecipeYield for recipes without a yield.
+	RecipeNutrition(ctx context.Context, recipeID int) (RecipeNutrition, error)
+}
+
+func (s *PostgresStore) RecipeNutrition(ctx context.Context, recipeID int) (RecipeNutrition, error) {
+	n := RecipeNutrition{RecipeID: recipeID}
+	var yield, calories, protein, fat, carbohydrates sql.NullInt64
+	err := s.db.QueryRowContext(ctx, `
+	SELECT r.name, r.servings, n.calories, n.protein, n.fat, n.carbohydrates
+	FROM recipes r
+	LEFT JOIN recipe_nutrition n ON n.recipe_id = r.id
+	WHERE r.id = $1;
+	`, recipeID).Scan(&n.Name, &yield, &calories, &protein, &fat, &carbohydrates)
+	if err != nil {
+		return RecipeNutrition{}, err
+	}
+	if !calories.Valid || !protein.Valid || !fat.Valid || !carbohydrates.Valid {
+		return RecipeNutrition{}, ErrNoRecipeNutrition
+	}
+	if !yield.Valid {
+		return RecipeNutrition{}, ErrUnknownRecipeYield
+	}
+	n.Yield = int(yield.Int64)
+	n.Totals = RecipeTotals{
+		Calories:      int(calories.Int64),
+		Protein:       int(protein.Int64),
+		Fat:           int(fat.Int64),
+		Carbohydrates: int(carbohydrates.Int64),
+	}
+	return n, nil
+}
+
+// ApplyRecipeNutrition recomputes the entries logged from a recipe after its
+// totals change, inside the caller's transaction, and records an update event
+// for each. It returns the number of entries changed; none are when the recipe
+// does not say how many servings it makes.
+func ApplyRecipeNutrition(ctx context.Context, tx *sql.Tx, topic string, recipeID int, totals RecipeTotals) (int, error) {
+	columns := "e." + strings.ReplaceAll(entryColumns, ", ", ", e.")
+	rows, err := tx.QueryContext(ctx, `
+	UPDATE food_entries e SET
+		calories = ROUND($2::numeric * e.portion / r.servings),
+		protein = ROUND($3::numeric * e.portion / r.servings),
+		fat = ROUND($4::numeric * e.portion / r.servings),
+		carbohydrates = ROUND($5::numeric * e.portion / r.servings),
+		updated_at = NOW()
+	FROM recipes r
+	WHERE r.id = $1 AND r.servings IS NOT NULL AND e.recipe_id = $1
+	RETURNING `+columns+`;
+	`, recipeID, totals.Calories, totals.Protein, totals.Fat, totals.Carbohydrates)
+	if err != nil {
+		return 0, err
+	}
+	// The events are written once the rows are read; a transaction runs one
+	// statement at a time.
+	var updated []Entry
+	for rows.Next() {
+		e, err := scanEntry(rows)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		updated = append(updated, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	for _, e := range updated {
+		if err := record(ctx, tx, topic, EntryUpdated, e); err != nil {
+			return 0, err
+		}
+	}
+	return len(updated), nil
+}
+
+// RecipeInput is the request body for logging servings of a recipe. meal_type
+// and consumed_at are as for any entry.
+type RecipeInput struct {
+	RecipeID   int    `json:"recipe_id"`
+	Servings   int    `json:"servings"`
+	MealType   string `json:"meal_type"`
+	ConsumedAt string `json:"consumed_at"`
+}
+
+func (h *handler) createFromRecipe(w http.ResponseWriter, r *http.Request, userID int) {
+	var in RecipeInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if in.Servings == 0 {
+		in.Servings = 1
+	}
+	if in.Servings < 1 || in.Servings > MaxRecipeServings {
+		http.Error(w, fmt.Sprintf("servings must be between 1 and %d", MaxRecipeServings), http.StatusBadRequest)
+		return
+	}
+	loc, ok := h.location(w, r, userID)
+	if !ok {
+		return
+	}
+	consumedAt, err := parseConsumedAt(in.ConsumedAt, loc)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	nutrition, err := h.recipes.RecipeNutrition(r.Context(), in.RecipeID)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		http.Error(w, "Recipe not found", http.StatusNotFound)
+		return
+	case errors.Is(err, ErrNoRecipeNutrition), errors.Is(err, ErrUnknownRecipeYield):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	case err != nil:
+		log.Printf("Failed to load nutrition of recipe %d: %v", in.RecipeID, err)
+		http.Error(w, "Failed to load recipe", http.StatusInternalServerError)
+		return
+	}
+	entry := nutrition.Entry(userID, in.Servings)
+	entry.MealType, entry.ConsumedAt = in.MealType, consumedAt
+	if err := Validate(entry); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	created, err := h.store.Create(r.Context(), entry)
+	if err != nil {
+		log.Printf("Failed to log recipe %d for user %d: %v", in.RecipeID, userID, err)
+		http.Error(w, "Failed to create food entry", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(created); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
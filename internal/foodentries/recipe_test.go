@@ -0,0 +1,92 @@
+package foodentries
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/coloradocollective/go-capstone-starter/internal/auth/authtest"
+)
+
+// recipeBook holds recipe nutrition by ID; zero Totals stand for a recipe
+// without an estimate and a zero Yield for one that does not say how many
+// servings it makes.
+type recipeBook map[int]RecipeNutrition
+
+func (b recipeBook) RecipeNutrition(_ context.Context, recipeID int) (RecipeNutrition, error) {
+	n, ok := b[recipeID]
+	if !ok {
+		return RecipeNutrition{}, sql.ErrNoRows
+	}
+	if n.Totals == (RecipeTotals{}) {
+		return RecipeNutrition{}, ErrNoRecipeNutrition
+	}
+	if n.Yield == 0 {
+		return RecipeNutrition{}, ErrUnknownRecipeYield
+	}
+	return n, nil
+}
+
+var chili = RecipeNutrition{
+	RecipeID: 7,
+	Name:     "Turkey Chili",
+	Yield:    6,
+	Totals:   RecipeTotals{Calories: 1850, Protein: 160, Fat: 52, Carbohydrates: 175},
+}
+
+func TestRecipeNutrition_Entry(t *testing.T) {
+	e := chili.Entry(4, 2)
+	if e.Portion != 2 || e.Unit != "serving" || e.Calories != 617 || e.Protein != 53 || e.Fat != 17 || e.Carbohydrates != 58 {
+		t.Errorf("expected a third of the recipe, got %+v", e)
+	}
+	if e.RecipeID == nil || *e.RecipeID != 7 || e.Name != "Turkey Chili" {
+		t.Errorf("expected the entry to keep its recipe, got %+v", e)
+	}
+}
+
+func TestCreateFromRecipe(t *testing.T) {
+	store := newMemoryStore()
+	mux := newRecipeServer(store, zones{4: denver(t)}, recipeBook{
+		7: chili,
+		8: {RecipeID: 8, Name: "New Recipe", Yield: 4},
+		9: {RecipeID: 9, Name: "Potluck Salad", Totals: chili.Totals},
+	})
+
+	rec := authtest.Request(t, mux, http.MethodPost, "/api/food-entries/recipe", 4,
+		`{"recipe_id": 7, "servings": 3, "meal_type": "dinner", "consumed_at": "2024-05-01T18:30:00"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var created Entry
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	want := time.Date(2024, 5, 2, 0, 30, 0, 0, time.UTC)
+	if created.Calories != 925 || created.Portion != 3 || *created.RecipeID != 7 || !created.ConsumedAt.Equal(want) {
+		t.Errorf("unexpected entry %+v", created)
+	}
+
+	cases := map[string]struct {
+		body string
+		code int
+	}{
+		"unknown recipe": {`{"recipe_id": 99, "meal_type": "dinner", "consumed_at": "2024-05-01T18:30:00"}`, http.StatusNotFound},
+		"no nutrition":   {`{"recipe_id": 8, "meal_type": "dinner", "consumed_at": "2024-05-01T18:30:00"}`, http.StatusUnprocessableEntity},
+		"no yield":       {`{"recipe_id": 9, "meal_type": "dinner", "consumed_at": "2024-05-01T18:30:00"}`, http.StatusUnprocessableEntity},
+		"too many":       {`{"recipe_id": 7, "servings": 21, "meal_type": "dinner", "consumed_at": "2024-05-01T18:30:00"}`, http.StatusBadRequest},
+		"negative":       {`{"recipe_id": 7, "servings": -1, "meal_type": "dinner", "consumed_at": "2024-05-01T18:30:00"}`, http.StatusBadRequest},
+		"meal type":      {`{"recipe_id": 7, "meal_type": "meal", "consumed_at": "2024-05-01T18:30:00"}`, http.StatusBadRequest},
+		"bad time":       {`{"recipe_id": 7, "meal_type": "dinner", "consumed_at": "tonight"}`, http.StatusBadRequest},
+	}
+	for name, c := range cases {
+		if rec := authtest.Request(t, mux, http.MethodPost, "/api/food-entries/recipe", 4, c.body); rec.Code != c.code {
+			t.Errorf("%s: expected %d, got %d: %s", name, c.code, rec.Code, rec.Body.String())
+		}
+	}
+	if len(store.entries) != 1 {
+		t.Errorf("expected only the valid request to log an entry, got %d", len(store.entries))
+	}
+}
@@ -0,0 +1,207 @@
+package foodentries
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Rollup periods. Weeks are ISO weeks, starting on Monday.
+const (
+	PeriodDay   = "day"
+	PeriodWeek  = "week"
+	PeriodMonth = "month"
+)
+
+// maxRollupBuckets bounds the size of one rollup response.
+const maxRollupBuckets = 400
+
+// RollupQuery selects the entries of one user consumed on the dates From through To
+// (inclusive), with days starting at midnight in Location.
+type RollupQuery struct {
+	UserID   int
+	Period   string
+	From     time.Time
+	To       time.Time
+	Location *time.Location
+}
+
+// start and end are the instants bounding the query, end exclusive.
+func (q RollupQuery) start() time.Time {
+	return time.Date(q.From.Year(), q.From.Month(), q.From.Day(), 0, 0, 0, 0, q.Location)
+}
+
+func (q RollupQuery) end() time.Time {
+	return time.Date(q.To.Year(), q.To.Month(), q.To.Day()+1, 0, 0, 0, 0, q.Location)
+}
+
+// NutrientTotals sums the nutrients of the entries in a bucket.
+type NutrientTotals struct {
+	Calories      int `json:"calories"`
+	Protein       int `json:"protein"`
+	Carbohydrates int `json:"carbohydrates"`
+	Fat           int `json:"fat"`
+	Fiber         int `json:"fiber"`
+	Sugar         int `json:"sugar"`
+}
+
+// NutrientAverages are per-day averages over the days with at least one entry.
+type NutrientAverages struct {
+	Calories      float64 `json:"calories"`
+	Protein       float64 `json:"protein"`
+	Carbohydrates float64 `json:"carbohydrates"`
+	Fat           float64 `json:"fat"`
+	Fiber         float64 `json:"fiber"`
+	Sugar         float64 `json:"sugar"`
+}
+
+// Bucket aggregates one day, week or month. The first and last buckets only count
+// entries inside the requested range.
+type Bucket struct {
+	Label        string           `json:"label"`
+	Start        time.Time        `json:"start"`
+	End          time.Time        `json:"end"`
+	EntryCount   int              `json:"entry_count"`
+	DaysLogged   int              `json:"days_logged"`
+	Totals       NutrientTotals   `json:"totals"`
+	DailyAverage NutrientAverages `json:"daily_average"`
+}
+
+// Rollup is the response of the rollup endpoint.
+type Rollup struct {
+	Period   string   `json:"period"`
+	From     string   `json:"from"`
+	To       string   `json:"to"`
+	TimeZone string   `json:"time_zone"`
+	Buckets  []Bucket `json:"buckets"`
+}
+
+// periodStart returns the start of the period containing t, in t's location.
+func periodStart(t time.Time, period string) time.Time {
+	y, m, d := t.Date()
+	switch period {
+	case PeriodWeek:
+		// Go's Weekday has Sunday as 0; ISO weeks start on Monday.
+		offset := (int(t.Weekday()) + 6) % 7
+		return time.Date(y, m, d-offset, 0, 0, 0, 0, t.Location())
+	case PeriodMonth:
+		return time.Date(y, m, 1, 0, 0, 0, 0, t.Location())
+	default:
+		return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+	}
+}
+
+// nextPeriod returns the start of the period after the one starting at start.
+// Calendar arithmetic keeps midnights aligned across daylight saving changes.
+func nextPeriod(start time.Time, period string) time.Time {
+	switch period {
+	case PeriodWeek:
+		return start.AddDate(0, 0, 7)
+	case PeriodMonth:
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+func periodLabel(start time.Time, period string) string {
+	switch period {
+	case PeriodWeek:
+		year, week := start.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	case PeriodMonth:
+		return start.Format("2006-01")
+	default:
+		return start.Format(time.DateOnly)
+	}
+}
+
+// emptyBuckets lays out one bucket per period touching the query's dates.
+func emptyBuckets(q RollupQuery) ([]Bucket, error) {
+	var buckets []Bucket
+	end := q.end()
+	for start := periodStart(q.start(), q.Period); start.Before(end); start = nextPeriod(start, q.Period) {
+		if len(buckets) == maxRollupBuckets {
+			return nil, fmt.Errorf("the range spans more than %d %ss", maxRollupBuckets, q.Period)
+		}
+		buckets = append(buckets, Bucket{
+			Label: periodLabel(start, q.Period),
+			Start: start,
+			End:   nextPeriod(start, q.Period),
+		})
+	}
+	return buckets, nil
+}
+
+// fillBuckets places the aggregated buckets into the full layout, keyed by label.
+func fillBuckets(layout, found []Bucket) []Bucket {
+	byLabel := make(map[string]Bucket, len(found))
+	for _, b := range found {
+		byLabel[b.Label] = b
+	}
+	for i, b := range layout {
+		if f, ok := byLabel[b.Label]; ok {
+			f.Start, f.End = b.Start, b.End
+			layout[i] = f
+		}
+	}
+	return layout
+}
+
+func average(total, days int) float64 {
+	if days == 0 {
+		return 0
+	}
+	return math.Round(float64(total)/float64(days)*10) / 10
+}
+
+// Rollup aggregates in SQL. date_trunc works on local wall time, so entries are
+// converted to the query's time zone before truncation.
+func (s *PostgresStore) Rollup(ctx context.Context, q RollupQuery) ([]Bucket, error) {
+	layout, err := emptyBuckets(q)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+	SELECT date_trunc($2, consumed_at AT TIME ZONE $5) AS bucket,
+		COUNT(*),
+		COUNT(DISTINCT (consumed_at AT TIME ZONE $5)::date),
+		SUM(calories), SUM(protein), SUM(carbohydrates), SUM(fat), SUM(fiber), SUM(sugar)
+	FROM food_entries
+	WHERE user_id = $1 AND consumed_at >= $3 AND consumed_at < $4
+	GROUP BY bucket
+	ORDER BY bucket;
+	`, q.UserID, q.Period, q.start(), q.end(), q.Location.String())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var found []Bucket
+	for rows.Next() {
+		var wall time.Time
+		var b Bucket
+		err := rows.Scan(&wall, &b.EntryCount, &b.DaysLogged, &b.Totals.Calories, &b.Totals.Protein,
+			&b.Totals.Carbohydrates, &b.Totals.Fat, &b.Totals.Fiber, &b.Totals.Sugar)
+		if err != nil {
+			return nil, err
+		}
+		start := time.Date(wall.Year(), wall.Month(), wall.Day(), 0, 0, 0, 0, q.Location)
+		b.Label = periodLabel(start, q.Period)
+		b.DailyAverage = NutrientAverages{
+			Calories:      average(b.Totals.Calories, b.DaysLogged),
+			Protein:       average(b.Totals.Protein, b.DaysLogged),
+			Carbohydrates: average(b.Totals.Carbohydrates, b.DaysLogged),
+			Fat:           average(b.Totals.Fat, b.DaysLogged),
+			Fiber:         average(b.Totals.Fiber, b.DaysLogged),
+			Sugar:         average(b.Totals.Sugar, b.DaysLogged),
+		}
+		found = append(found, b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return fillBuckets(layout, found), nil
+}
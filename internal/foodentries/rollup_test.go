@@ -0,0 +1,109 @@
+package foodentries
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coloradocollective/go-capstone-starter/internal/auth/authtest"
+)
+
+func TestPeriodStart_ISOWeeks(t *testing.T) {
+	// 2025-01-01 is a Wednesday in ISO week 1 of 2025, which starts on 2024-12-30.
+	start := periodStart(time.Date(2025, 1, 1, 15, 0, 0, 0, time.UTC), PeriodWeek)
+	if want := time.Date(2024, 12, 30, 0, 0, 0, 0, time.UTC); !start.Equal(want) {
+		t.Fatalf("expected %v, got %v", want, start)
+	}
+	if label := periodLabel(start, PeriodWeek); label != "2025-W01" {
+		t.Errorf("expected 2025-W01, got %s", label)
+	}
+	// Sundays belong to the week that started the Monday before.
+	sunday := periodStart(time.Date(2024, 5, 5, 23, 0, 0, 0, time.UTC), PeriodWeek)
+	if want := time.Date(2024, 4, 29, 0, 0, 0, 0, time.UTC); !sunday.Equal(want) {
+		t.Errorf("expected %v, got %v", want, sunday)
+	}
+}
+
+func TestEmptyBuckets(t *testing.T) {
+	q := RollupQuery{
+		Period:   PeriodMonth,
+		From:     time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+		To:       time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		Location: time.UTC,
+	}
+	buckets, err := emptyBuckets(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var labels []string
+	for _, b := range buckets {
+		labels = append(labels, b.Label)
+	}
+	if got := strings.Join(labels, ","); got != "2024-01,2024-02,2024-03" {
+		t.Errorf("unexpected buckets %s", got)
+	}
+
+	q.Period = PeriodDay
+	q.To = q.From.AddDate(2, 0, 0)
+	if _, err := emptyBuckets(q); err == nil {
+		t.Error("expected an error for a range of more than 400 days")
+	}
+}
+
+func TestRollup(t *testing.T) {
+	store := newMemoryStore()
+	mux := newServer(store)
+	for _, at := range []string{"2024-04-29T08:00:00Z", "2024-04-29T19:00:00Z", "2024-05-01T08:00:00Z", "2024-05-13T08:00:00Z"} {
+		body := strings.Replace(oatmeal, "2024-05-01T08:30:00", at, 1)
+		if rec := authtest.Request(t, mux, http.MethodPost, "/api/food-entries", 4, body); rec.Code != http.StatusCreated {
+			t.Fatalf("expected 201, got %d", rec.Code)
+		}
+	}
+	authtest.Request(t, mux, http.MethodPost, "/api/food-entries", 5, oatmeal)
+
+	rec := authtest.Request(t, mux, http.MethodGet, "/api/food-entries/rollup?period=week&from=2024-04-29&to=2024-05-19", 4, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var rollup Rollup
+	if err := json.NewDecoder(rec.Body).Decode(&rollup); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if len(rollup.Buckets) != 3 {
+		t.Fatalf("expected 3 weeks, got %+v", rollup.Buckets)
+	}
+	first, empty := rollup.Buckets[0], rollup.Buckets[1]
+	if first.Label != "2024-W18" || first.EntryCount != 3 || first.DaysLogged != 2 || first.Totals.Calories != 900 {
+		t.Errorf("unexpected first week %+v", first)
+	}
+	if first.DailyAverage.Calories != 450 {
+		t.Errorf("expected a daily average of 450 calories, got %v", first.DailyAverage.Calories)
+	}
+	if empty.Label != "2024-W19" || empty.EntryCount != 0 || empty.Totals.Calories != 0 {
+		t.Errorf("expected an empty second week, got %+v", empty)
+	}
+	if rollup.Buckets[2].EntryCount != 1 {
+		t.Errorf("unexpected third week %+v", rollup.Buckets[2])
+	}
+}
+
+func TestRollup_Rejects(t *testing.T) {
+	mux := newServer(newMemoryStore())
+	invalid := map[string]string{
+		"period":   "period=year&from=2024-01-01&to=2024-02-01",
+		"no from":  "to=2024-02-01",
+		"bad to":   "from=2024-01-01&to=soon",
+		"reversed": "from=2024-02-01&to=2024-01-01",
+		"too long": "period=day&from=2020-01-01&to=2024-01-01",
+	}
+	for name, query := range invalid {
+		if rec := authtest.Request(t, mux, http.MethodGet, "/api/food-entries/rollup?"+query, 4, ""); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", name, rec.Code)
+		}
+	}
+	if rec := authtest.Request(t, mux, http.MethodGet, "/api/food-entries/rollup?from=2024-01-01&to=2024-01-02", 0, ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without a token, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,211 @@
+package foodentries
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/coloradocollective/go-capstone-starter/internal/events"
+	"github.com/coloradocollective/go-capstone-starter/internal/outbox"
+)
+
+// Entry is one row of food_entries.
+type Entry struct {
+	ID            int       `json:"id"`
+	UserID        int       `json:"user_id"`
+	Name          string    `json:"name"`
+	Portion       int       `json:"portion"`
+	Unit          string    `json:"unit"`
+	Calories      int       `json:"calories"`
+	Protein       int       `json:"protein"`
+	Carbohydrates int       `json:"carbohydrates"`
+	Fat           int       `json:"fat"`
+	Fiber         int       `json:"fiber"`
+	Sugar         int       `json:"sugar"`
+	MealType      string    `json:"meal_type"`
+	ConsumedAt    time.Time `json:"consumed_at"`
+	// RecipeID is set on entries logged from a recipe, whose portion counts servings.
+	RecipeID  *int      `json:"recipe_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Event types written to the outbox for food entry changes.
+const (
+	EntryCreated = "food_entry.created"
+	EntryUpdated = "food_entry.updated"
+	EntryDeleted = "food_entry.deleted"
+)
+
+// DefaultEventsTopic is used when FOOD_ENTRY_EVENTS_TOPIC is unset.
+const DefaultEventsTopic = "food-entry-events"
+
+// EventVersion is the schema version of the food entry events this package publishes.
+const EventVersion = 1
+
+// EventData is the data of a food entry event. Entry is omitted for deletions.
+type EventData struct {
+	EntryID int    `json:"entry_id"`
+	Entry   *Entry `json:"entry,omitempty"`
+}
+
+// entryEvent builds the validated envelope for a change to entry.
+func entryEvent(eventType string, entry Entry) (events.Envelope, error) {
+	data := EventData{EntryID: entry.ID}
+	if eventType != EntryDeleted {
+		data.Entry = &entry
+	}
+	return events.New(eventType, EventVersion, entry.UserID, data)
+}
+
+// Store persists food entries. Every change is recorded in the outbox in the same
+// transaction, so its event is published exactly when the change commits.
+type Store interface {
+	Create(ctx context.Context, entry Entry) (Entry, error)
+	// CreateAll creates entries in one transaction: all of them or none.
+	CreateAll(ctx context.Context, entries []Entry) ([]Entry, error)
+	Update(ctx context.Context, entry Entry) (Entry, error)
+	Delete(ctx context.Context, userID, entryID int) error
+	// List returns one page of a user's entries.
+	List(ctx context.Context, q ListQuery) (Page, error)
+	// Each streams a user's entries, oldest first.
+	Each(ctx context.Context, q ExportQuery, fn func(Entry) error) error
+	// Rollup returns one bucket per period of the query range, including empty ones.
+	Rollup(ctx context.Context, q RollupQuery) ([]Bucket, error)
+}
+
+type PostgresStore struct {
+	db    *sql.DB
+	topic string
+}
+
+func NewPostgresStore(db *sql.DB, topic string) *PostgresStore {
+	return &PostgresStore{db: db, topic: topic}
+}
+
+const entryColumns = `id, user_id, name, portion, unit, calories, protein, carbohydrates, fat, fiber, sugar, meal_type, consumed_at, recipe_id, created_at, updated_at`
+
+// scanEntry reads entryColumns. Legacy rows may lack created_at and updated_at;
+// like cmd/replay, they fall back to consumed_at and created_at.
+func scanEntry(row interface{ Scan(...any) error }) (Entry, error) {
+	var e Entry
+	var recipeID sql.NullInt64
+	var createdAt, updatedAt sql.NullTime
+	err := row.Scan(&e.ID, &e.UserID, &e.Name, &e.Portion, &e.Unit, &e.Calories, &e.Protein, &e.Carbohydrates,
+		&e.Fat, &e.Fiber, &e.Sugar, &e.MealType, &e.ConsumedAt, &recipeID, &createdAt, &updatedAt)
+	if recipeID.Valid {
+		id := int(recipeID.Int64)
+		e.RecipeID = &id
+	}
+	e.CreatedAt = e.ConsumedAt
+	if createdAt.Valid {
+		e.CreatedAt = createdAt.Time
+	}
+	e.UpdatedAt = e.CreatedAt
+	if updatedAt.Valid {
+		e.UpdatedAt = updatedAt.Time
+	}
+	return e, err
+}
+
+func (s *PostgresStore) Create(ctx context.Context, entry Entry) (Entry, error) {
+	return s.change(ctx, EntryCreated, func(tx *sql.Tx) (Entry, error) {
+		return insert(ctx, tx, entry)
+	})
+}
+
+func (s *PostgresStore) CreateAll(ctx context.Context, entries []Entry) ([]Entry, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	created := make([]Entry, 0, len(entries))
+	for _, entry := range entries {
+		entry, err := insert(ctx, tx, entry)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.record(ctx, tx, EntryCreated, entry); err != nil {
+			return nil, err
+		}
+		created = append(created, entry)
+	}
+	return created, tx.Commit()
+}
+
+func insert(ctx context.Context, tx *sql.Tx, entry Entry) (Entry, error) {
+	return scanEntry(tx.QueryRowContext(ctx, `
+	INSERT INTO food_entries (user_id, name, portion, unit, calories, protein, carbohydrates, fat, fiber, sugar, meal_type, consumed_at, recipe_id)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+	RETURNING `+entryColumns+`;
+	`, entry.UserID, entry.Name, entry.Portion, entry.Unit, entry.Calories, entry.Protein, entry.Carbohydrates,
+		entry.Fat, entry.Fiber, entry.Sugar, entry.MealType, entry.ConsumedAt, entry.RecipeID))
+}
+
+// Update replaces an entry owned by entry.UserID; it returns sql.ErrNoRows for other users' entries.
+// An entry logged from a recipe keeps its recipe only while its portion and
+// nutrients are unchanged, so recipe corrections never overwrite a user's edit.
+func (s *PostgresStore) Update(ctx context.Context, entry Entry) (Entry, error) {
+	return s.change(ctx, EntryUpdated, func(tx *sql.Tx) (Entry, error) {
+		return scanEntry(tx.QueryRowContext(ctx, `
+		UPDATE food_entries SET
+			name = $3, portion = $4, unit = $5, calories = $6, protein = $7, carbohydrates = $8,
+			fat = $9, fiber = $10, sugar = $11, meal_type = $12, consumed_at = $13, updated_at = NOW(),
+			recipe_id = CASE WHEN (portion, calories, protein, carbohydrates, fat, fiber, sugar) = ($4, $6, $7, $8, $9, $10, $11)
+				THEN recipe_id END
+		WHERE id = $1 AND user_id = $2
+		RETURNING `+entryColumns+`;
+		`, entry.ID, entry.UserID, entry.Name, entry.Portion, entry.Unit, entry.Calories, entry.Protein,
+			entry.Carbohydrates, entry.Fat, entry.Fiber, entry.Sugar, entry.MealType, entry.ConsumedAt))
+	})
+}
+
+// Delete removes an entry owned by userID; it returns sql.ErrNoRows for other users' entries.
+func (s *PostgresStore) Delete(ctx context.Context, userID, entryID int) error {
+	_, err := s.change(ctx, EntryDeleted, func(tx *sql.Tx) (Entry, error) {
+		return scanEntry(tx.QueryRowContext(ctx, `
+		DELETE FROM food_entries WHERE id = $1 AND user_id = $2
+		RETURNING `+entryColumns+`;
+		`, entryID, userID))
+	})
+	return err
+}
+
+// change applies one write and its outbox event in a single transaction.
+func (s *PostgresStore) change(ctx context.Context, eventType string, write func(tx *sql.Tx) (Entry, error)) (Entry, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Entry{}, err
+	}
+	defer tx.Rollback()
+
+	entry, err := write(tx)
+	if err != nil {
+		return Entry{}, err
+	}
+
+	if err := s.record(ctx, tx, eventType, entry); err != nil {
+		return Entry{}, err
+	}
+	return entry, tx.Commit()
+}
+
+// record writes the outbox event of a change inside its transaction.
+func (s *PostgresStore) record(ctx context.Context, tx *sql.Tx, eventType string, entry Entry) error {
+	return record(ctx, tx, s.topic, eventType, entry)
+}
+
+func record(ctx context.Context, tx *sql.Tx, topic, eventType string, entry Entry) error {
+	event, err := entryEvent(eventType, entry)
+	if err != nil {
+		return err
+	}
+	return outbox.Write(ctx, tx, outbox.Event{
+		Topic:       topic,
+		Type:        eventType,
+		OrderingKey: outbox.UserKey(entry.UserID),
+		Payload:     event,
+	})
+}
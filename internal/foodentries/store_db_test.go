@@ -0,0 +1,118 @@
+package foodentries
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/coloradocollective/go-capstone-starter/internal/dbtest"
+	"github.com/lib/pq"
+)
+
+// insertEntry writes a bare entry straight to food_entries and returns its id.
+func insertEntry(t *testing.T, db *sql.DB, userID int, name string, calories int, consumedAt time.Time) int {
+	t.Helper()
+	var id int
+	err := db.QueryRow(`
+	INSERT INTO food_entries (user_id, name, portion, unit, calories, protein, carbohydrates, fat, fiber, sugar, meal_type, consumed_at)
+	VALUES ($1, $2, 1, 'serving', $3, 0, 0, 0, 0, 0, 'lunch', $4)
+	RETURNING id;
+	`, userID, name, calories, consumedAt).Scan(&id)
+	if err != nil {
+		t.Fatalf("failed to insert %s: %v", name, err)
+	}
+	return id
+}
+
+func TestPostgresStore_RollupDaysFollowTheUsersZone(t *testing.T) {
+	db := dbtest.Open(t)
+	userID := dbtest.UserID(t, db, "food_entries")
+	denver, err := time.LoadLocation("America/Denver")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 23:30 and 00:30 in Denver on either side of midnight, and the last hour of
+	// 2024-03-10, a 23-hour day.
+	insertEntry(t, db, userID, "late snack", 100, time.Date(2024, 4, 30, 23, 30, 0, 0, denver))
+	insertEntry(t, db, userID, "early snack", 200, time.Date(2024, 5, 1, 0, 30, 0, 0, denver))
+	insertEntry(t, db, userID, "dst snack", 300, time.Date(2024, 3, 10, 23, 30, 0, 0, denver))
+
+	store := NewPostgresStore(db, DefaultEventsTopic)
+	buckets, err := store.Rollup(context.Background(), RollupQuery{
+		UserID:   userID,
+		Period:   PeriodDay,
+		From:     time.Date(2024, 4, 30, 0, 0, 0, 0, time.UTC),
+		To:       time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+		Location: denver,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(buckets) != 2 || buckets[0].Totals.Calories != 100 || buckets[1].Totals.Calories != 200 {
+		t.Fatalf("expected 100 on 2024-04-30 and 200 on 2024-05-01, got %+v", buckets)
+	}
+
+	buckets, err = store.Rollup(context.Background(), RollupQuery{
+		UserID:   userID,
+		Period:   PeriodDay,
+		From:     time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC),
+		To:       time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC),
+		Location: denver,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(buckets) != 2 || buckets[0].Label != "2024-03-10" || buckets[0].EntryCount != 1 || buckets[1].EntryCount != 0 {
+		t.Fatalf("expected the entry on 2024-03-10, got %+v", buckets)
+	}
+}
+
+func TestPostgresStore_ListPagesThroughLegacyRows(t *testing.T) {
+	db := dbtest.Open(t)
+	userID := dbtest.UserID(t, db, "food_entries")
+	consumed := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	var ids []int
+	for i := range 5 {
+		ids = append(ids, insertEntry(t, db, userID, fmt.Sprintf("entry %d", i), 100, consumed.Add(time.Duration(i)*time.Hour)))
+	}
+	// Rows from before created_at had a default, with their timestamps missing.
+	if _, err := db.Exec(`UPDATE food_entries SET created_at = NULL, updated_at = NULL WHERE id = ANY($1)`, pq.Array(ids[1:3])); err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewPostgresStore(db, DefaultEventsTopic)
+	for _, sort := range []Sort{DefaultSort, {Column: "created_at"}, {Column: "calories", Desc: true}} {
+		q := ListQuery{UserID: userID, Sort: sort, Limit: 2}
+		seen := map[int]bool{}
+		for pages := 0; ; pages++ {
+			if pages > 3 {
+				t.Fatalf("%s: too many pages", sort)
+			}
+			p, err := store.List(context.Background(), q)
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %v", sort, err)
+			}
+			for _, e := range p.Entries {
+				if seen[e.ID] {
+					t.Fatalf("%s: entry %d listed twice", sort, e.ID)
+				}
+				seen[e.ID] = true
+				if e.CreatedAt.IsZero() || e.UpdatedAt.IsZero() {
+					t.Errorf("%s: entry %d has no timestamps", sort, e.ID)
+				}
+			}
+			if p.NextCursor == "" {
+				break
+			}
+			if q.After, err = DecodeCursor(p.NextCursor, sort); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if len(seen) != len(ids) {
+			t.Errorf("%s: expected %d entries, got %d", sort, len(ids), len(seen))
+		}
+	}
+}
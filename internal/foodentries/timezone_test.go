@@ -0,0 +1,99 @@
+package foodentries
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coloradocollective/go-capstone-starter/internal/auth/authtest"
+)
+
+func denver(t *testing.T) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation("America/Denver")
+	if err != nil {
+		t.Fatalf("failed to load time zone: %v", err)
+	}
+	return loc
+}
+
+func TestEmptyBuckets_DSTDays(t *testing.T) {
+	loc := denver(t)
+	q := RollupQuery{
+		Period:   PeriodDay,
+		From:     time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC),
+		To:       time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC),
+		Location: loc,
+	}
+	spring, err := emptyBuckets(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.From = time.Date(2024, 11, 3, 0, 0, 0, 0, time.UTC)
+	q.To = q.From
+	fall, err := emptyBuckets(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := spring[0].End.Sub(spring[0].Start); got != 23*time.Hour {
+		t.Errorf("expected the spring-forward day to last 23h, got %v", got)
+	}
+	if got := fall[0].End.Sub(fall[0].Start); got != 25*time.Hour {
+		t.Errorf("expected the fall-back day to last 25h, got %v", got)
+	}
+	if h := fall[0].End.In(loc).Hour(); h != 0 {
+		t.Errorf("expected the day to end at local midnight, got hour %d", h)
+	}
+}
+
+func TestRollup_LateDinnersAcrossDST(t *testing.T) {
+	loc := denver(t)
+	store := newMemoryStore()
+	mux := newZonedServer(store, zones{4: loc})
+
+	// Local times the add-entry form sends, each an hour before midnight in Denver
+	// and already the next day in UTC.
+	for _, at := range []string{"2024-03-09T23:30:00", "2024-03-10T23:30:00", "2024-11-02T23:30:00", "2024-11-03T23:30:00"} {
+		body := strings.Replace(oatmeal, "2024-05-01T08:30:00", at, 1)
+		if rec := authtest.Request(t, mux, http.MethodPost, "/api/food-entries", 4, body); rec.Code != http.StatusCreated {
+			t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+		}
+	}
+	if got := store.entries[2].ConsumedAt.UTC(); !got.Equal(time.Date(2024, 3, 11, 5, 30, 0, 0, time.UTC)) {
+		t.Errorf("expected 23:30 MDT to be stored as 05:30 UTC, got %v", got)
+	}
+	if got := store.entries[4].ConsumedAt.UTC(); !got.Equal(time.Date(2024, 11, 4, 6, 30, 0, 0, time.UTC)) {
+		t.Errorf("expected 23:30 MST to be stored as 06:30 UTC, got %v", got)
+	}
+
+	for _, tc := range []struct{ from, to string }{{"2024-03-09", "2024-03-11"}, {"2024-11-02", "2024-11-04"}} {
+		rec := authtest.Request(t, mux, http.MethodGet, "/api/food-entries/rollup?from="+tc.from+"&to="+tc.to, 4, "")
+		var rollup Rollup
+		if err := json.NewDecoder(rec.Body).Decode(&rollup); err != nil {
+			t.Fatalf("invalid response: %v", err)
+		}
+		if rollup.TimeZone != "America/Denver" {
+			t.Errorf("expected the user's time zone, got %q", rollup.TimeZone)
+		}
+		var counts []int
+		for _, b := range rollup.Buckets {
+			counts = append(counts, b.EntryCount)
+		}
+		if len(counts) != 3 || counts[0] != 1 || counts[1] != 1 || counts[2] != 0 {
+			t.Errorf("%s: expected each dinner on its local day, got %v", tc.from, counts)
+		}
+	}
+}
+
+func TestParseConsumedAt_OffsetWins(t *testing.T) {
+	got, err := parseConsumedAt("2024-03-10T23:30:00Z", denver(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equal(time.Date(2024, 3, 10, 23, 30, 0, 0, time.UTC)) {
+		t.Errorf("expected an explicit offset to be kept, got %v", got)
+	}
+}
@@ -0,0 +1,112 @@
+package foodimport
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/coloradocollective/go-capstone-starter/internal/auth"
+	"github.com/coloradocollective/go-capstone-starter/internal/foodentries"
+	"github.com/coloradocollective/go-capstone-starter/internal/profile"
+)
+
+// maxUploadBytes bounds an uploaded export.
+const maxUploadBytes = 10 << 20
+
+type handler struct {
+	entries   Entries
+	locations profile.Locations
+}
+
+// Handlers registers the import endpoint.
+func Handlers(db *sql.DB, authenticator auth.Authenticator) func(mux *http.ServeMux) {
+	return routes(foodentries.NewPostgresStore(db, foodentries.EventsTopic()), profile.NewPostgresStore(db), authenticator)
+}
+
+func routes(entries Entries, locations profile.Locations, authenticator auth.Authenticator) func(mux *http.ServeMux) {
+	h := &handler{entries: entries, locations: locations}
+	return func(mux *http.ServeMux) {
+		mux.HandleFunc("POST /api/food-entries/import", authenticator.Require(h.importEntries))
+	}
+}
+
+// upload returns the CSV, sent either as the "file" field of a multipart form or
+// as the request body.
+func upload(r *http.Request) (io.Reader, error) {
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		return r.Body, nil
+	}
+	file, _, err := r.FormFile("file")
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, errors.New("the form has no file field")
+	}
+	return file, nil
+}
+
+// badUpload answers an upload that could not be read, with 413 when it is over
+// maxUploadBytes.
+func badUpload(w http.ResponseWriter, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		http.Error(w, "The file is larger than 10 MB", http.StatusRequestEntityTooLarge)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusBadRequest)
+}
+
+// importEntries serves POST /api/food-entries/import?format=&commit=true. Without
+// commit=true it only previews. format may be myfitnesspal or cronometer, and is
+// detected when omitted. Times without an offset are taken in the user's time zone.
+func (h *handler) importEntries(w http.ResponseWriter, r *http.Request, userID int) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadBytes)
+	format := r.URL.Query().Get("format")
+	if format != "" && format != MyFitnessPal && format != Cronometer {
+		http.Error(w, "format must be myfitnesspal or cronometer", http.StatusBadRequest)
+		return
+	}
+	loc, err := h.locations.Location(r.Context(), userID)
+	if err != nil {
+		log.Printf("Failed to load time zone for user %d: %v", userID, err)
+		http.Error(w, "Failed to load time zone", http.StatusInternalServerError)
+		return
+	}
+	body, err := upload(r)
+	if err != nil {
+		badUpload(w, err)
+		return
+	}
+
+	format, rows, err := Parse(body, format, loc)
+	if err != nil {
+		badUpload(w, err)
+		return
+	}
+	if err := Prepare(r.Context(), h.entries, userID, rows); err != nil {
+		log.Printf("Failed to check duplicates for user %d: %v", userID, err)
+		http.Error(w, "Failed to check for duplicates", http.StatusInternalServerError)
+		return
+	}
+
+	result := Summarize(format, rows)
+	if r.URL.Query().Get("commit") == "true" {
+		result.Committed = true
+		result.Imported, err = Commit(r.Context(), h.entries, rows)
+		if err != nil {
+			log.Printf("Import for user %d stopped after %d entries: %v", userID, result.Imported, err)
+			http.Error(w, "The import stopped part way; run it again to import the remaining rows", http.StatusInternalServerError)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
@@ -0,0 +1,112 @@
+package foodimport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/coloradocollective/go-capstone-starter/internal/auth/authtest"
+	"github.com/coloradocollective/go-capstone-starter/internal/foodentries"
+)
+
+type memoryEntries struct {
+	entries []foodentries.Entry
+}
+
+func (m *memoryEntries) Create(_ context.Context, e foodentries.Entry) (foodentries.Entry, error) {
+	e.ID = len(m.entries) + 1
+	m.entries = append(m.entries, e)
+	return e, nil
+}
+
+func (m *memoryEntries) Each(_ context.Context, q foodentries.ExportQuery, fn func(foodentries.Entry) error) error {
+	for _, e := range m.entries {
+		if e.UserID == q.UserID && !e.ConsumedAt.Before(q.From) && e.ConsumedAt.Before(q.To) {
+			if err := fn(e); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+type utc struct{}
+
+func (utc) Location(context.Context, int) (*time.Location, error) { return time.UTC, nil }
+
+func post(t *testing.T, mux *http.ServeMux, query, file string) Result {
+	t.Helper()
+	data, err := os.ReadFile("testdata/" + file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, _ := form.CreateFormFile("file", file)
+	part.Write(data)
+	form.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/food-entries/import"+query, &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	authtest.Sign(t, req, 4)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var result Result
+	if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	return result
+}
+
+func TestImport(t *testing.T) {
+	store := &memoryEntries{}
+	mux := http.NewServeMux()
+	routes(store, utc{}, authtest.Auth)(mux)
+
+	preview := post(t, mux, "", "cronometer.csv")
+	if preview.Committed || preview.Valid != 3 || preview.Invalid != 1 || len(preview.Problems) != 1 || len(store.entries) != 0 {
+		t.Fatalf("expected a preview of 3 valid rows and 1 problem, got %+v", preview)
+	}
+	if preview.Problems[0].Line != 5 {
+		t.Errorf("expected the problem on line 5, got %d", preview.Problems[0].Line)
+	}
+
+	committed := post(t, mux, "?commit=true", "cronometer.csv")
+	if committed.Imported != 3 || len(store.entries) != 3 || store.entries[0].UserID != 4 {
+		t.Fatalf("expected 3 entries for user 4, got %+v", committed)
+	}
+
+	again := post(t, mux, "?commit=true", "cronometer.csv")
+	if again.Duplicates != 3 || again.Imported != 0 || len(store.entries) != 3 {
+		t.Errorf("expected a second import to skip every row, got %+v", again)
+	}
+}
+
+func TestImport_RejectsLargeForms(t *testing.T) {
+	mux := http.NewServeMux()
+	routes(&memoryEntries{}, utc{}, authtest.Auth)(mux)
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, _ := form.CreateFormFile("file", "huge.csv")
+	part.Write(bytes.Repeat([]byte("2024-05-01,Breakfast,420\n"), maxUploadBytes/20))
+	form.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/food-entries/import", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	authtest.Sign(t, req, 4)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,121 @@
+package foodimport
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/coloradocollective/go-capstone-starter/internal/foodentries"
+)
+
+// Limits on the rows echoed back in a Result.
+const (
+	previewRows = 20
+	problemRows = 500
+)
+
+// Entries is the part of foodentries.Store an import needs.
+type Entries interface {
+	Create(ctx context.Context, entry foodentries.Entry) (foodentries.Entry, error)
+	Each(ctx context.Context, q foodentries.ExportQuery, fn func(foodentries.Entry) error) error
+}
+
+// Result summarises an import. Preview shows the first rows that would be
+// imported and Problems the rows with errors or duplicates.
+type Result struct {
+	Format     string `json:"format"`
+	Rows       int    `json:"rows"`
+	Valid      int    `json:"valid"`
+	Invalid    int    `json:"invalid"`
+	Duplicates int    `json:"duplicates"`
+	Imported   int    `json:"imported"`
+	Committed  bool   `json:"committed"`
+	Preview    []Row  `json:"preview"`
+	Problems   []Row  `json:"problems"`
+}
+
+// key identifies an entry for duplicate detection.
+func key(e foodentries.Entry) string {
+	return fmt.Sprintf("%s|%d|%s|%d", strings.ToLower(e.Name), e.ConsumedAt.Unix(), e.MealType, e.Calories)
+}
+
+// Prepare assigns the rows to userID and marks those that repeat an existing
+// entry or an earlier row.
+func Prepare(ctx context.Context, entries Entries, userID int, rows []Row) error {
+	var from, to time.Time
+	for i := range rows {
+		rows[i].Entry.UserID = userID
+		if rows[i].Error != "" {
+			continue
+		}
+		at := rows[i].Entry.ConsumedAt
+		if from.IsZero() || at.Before(from) {
+			from = at
+		}
+		if at.After(to) {
+			to = at
+		}
+	}
+	if from.IsZero() {
+		return nil
+	}
+
+	seen := map[string]bool{}
+	err := entries.Each(ctx, foodentries.ExportQuery{UserID: userID, From: from, To: to.Add(time.Second)}, func(e foodentries.Entry) error {
+		seen[key(e)] = true
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for i := range rows {
+		if rows[i].Error != "" {
+			continue
+		}
+		k := key(rows[i].Entry)
+		rows[i].Duplicate = seen[k]
+		seen[k] = true
+	}
+	return nil
+}
+
+// Commit creates the rows that are neither invalid nor duplicates. Each entry is
+// its own change with its own event, so an interrupted import keeps what it
+// created; running it again skips those rows as duplicates.
+func Commit(ctx context.Context, entries Entries, rows []Row) (int, error) {
+	imported := 0
+	for _, row := range rows {
+		if row.Error != "" || row.Duplicate {
+			continue
+		}
+		if _, err := entries.Create(ctx, row.Entry); err != nil {
+			return imported, fmt.Errorf("line %d: %w", row.Line, err)
+		}
+		imported++
+	}
+	return imported, nil
+}
+
+// Summarize counts the rows and picks the ones to show.
+func Summarize(format string, rows []Row) Result {
+	r := Result{Format: format, Rows: len(rows), Preview: []Row{}, Problems: []Row{}}
+	for _, row := range rows {
+		switch {
+		case row.Error != "":
+			r.Invalid++
+		case row.Duplicate:
+			r.Duplicates++
+		default:
+			r.Valid++
+			if len(r.Preview) < previewRows {
+				r.Preview = append(r.Preview, row)
+			}
+			continue
+		}
+		if len(r.Problems) < problemRows {
+			r.Problems = append(r.Problems, row)
+		}
+	}
+	return r
+}
@@ -0,0 +1,285 @@
+// Package foodimport reads food logs exported by other apps into food entries.
+package foodimport
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/coloradocollective/go-capstone-starter/internal/foodentries"
+)
+
+// Supported formats.
+const (
+	MyFitnessPal = "myfitnesspal"
+	Cronometer   = "cronometer"
+)
+
+// MaxRows bounds one import.
+const MaxRows = 20000
+
+var ErrUnknownFormat = errors.New("the file is not a MyFitnessPal or Cronometer CSV export")
+
+// Row is one parsed line. Error is set when the line cannot become an entry;
+// Duplicate when the entry already exists or appeared earlier in the file.
+type Row struct {
+	Line      int               `json:"line"`
+	Entry     foodentries.Entry `json:"entry"`
+	Error     string            `json:"error,omitempty"`
+	Duplicate bool              `json:"duplicate,omitempty"`
+}
+
+// mealTimes are used when an export has no time of day: MyFitnessPal only has
+// dates, and Cronometer times are optional.
+var mealTimes = map[string]time.Duration{
+	"breakfast": 8 * time.Hour,
+	"lunch":     12*time.Hour + 30*time.Minute,
+	"snack":     15 * time.Hour,
+	"dinner":    18*time.Hour + 30*time.Minute,
+}
+
+// atMealTime returns the usual time of the meal on day, read off the wall clock
+// so that it holds on days when daylight saving time starts or ends.
+func atMealTime(day time.Time, mealType string) time.Time {
+	since := mealTimes[mealType]
+	return time.Date(day.Year(), day.Month(), day.Day(), int(since/time.Hour), int(since%time.Hour/time.Minute), 0, 0, day.Location())
+}
+
+// mealType maps the meal names of other apps onto ours. Custom meals such as
+// MyFitnessPal's "Meal 5" and Cronometer's "Uncategorized" become snacks.
+func mealType(name string) string {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "breakfast":
+		return "breakfast"
+	case "lunch":
+		return "lunch"
+	case "dinner", "supper":
+		return "dinner"
+	default:
+		return "snack"
+	}
+}
+
+// columns finds values by header name, ignoring case.
+type columns map[string]int
+
+func newColumns(header []string) columns {
+	c := columns{}
+	for i, name := range header {
+		c[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	return c
+}
+
+func (c columns) has(names ...string) bool {
+	for _, n := range names {
+		if _, ok := c[strings.ToLower(n)]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (c columns) get(record []string, name string) string {
+	i, ok := c[strings.ToLower(name)]
+	if !ok || i >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[i])
+}
+
+// number reads a nutrient, rounded to a whole number; blanks are zero.
+func (c columns) number(record []string, name string) (int, error) {
+	raw := strings.ReplaceAll(c.get(record, name), ",", "")
+	if raw == "" {
+		return 0, nil
+	}
+	v, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s is not a number: %q", name, raw)
+	}
+	return int(math.Round(v)), nil
+}
+
+// Detect names the format of a header row.
+func Detect(header []string) (string, error) {
+	c := newColumns(header)
+	switch {
+	case c.has("Day", "Food Name", "Energy (kcal)"):
+		return Cronometer, nil
+	case c.has("Date", "Meal", "Calories"):
+		return MyFitnessPal, nil
+	}
+	return "", ErrUnknownFormat
+}
+
+// Parse reads an export. format may be empty to detect it from the header. Dates
+// without an offset are taken in loc. Lines that cannot be read are returned with
+// an error rather than failing the whole file.
+func Parse(r io.Reader, format string, loc *time.Location) (string, []Row, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	var parseErr *csv.ParseError
+	if err == io.EOF || errors.As(err, &parseErr) {
+		return "", nil, ErrUnknownFormat
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	if format == "" {
+		if format, err = Detect(header); err != nil {
+			return "", nil, err
+		}
+	}
+	var parseRecord func(columns, []string, *time.Location) (foodentries.Entry, error)
+	switch format {
+	case MyFitnessPal:
+		parseRecord = parseMyFitnessPal
+	case Cronometer:
+		parseRecord = parseCronometer
+	default:
+		return "", nil, ErrUnknownFormat
+	}
+
+	c := newColumns(header)
+	var rows []Row
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if len(rows) == MaxRows {
+			return "", nil, fmt.Errorf("the file has more than %d rows", MaxRows)
+		}
+		if err != nil && !errors.As(err, &parseErr) {
+			return "", nil, err
+		}
+		if err != nil {
+			rows = append(rows, Row{Line: parseErr.StartLine, Error: parseErr.Err.Error()})
+			continue
+		}
+		if blank(record) {
+			continue
+		}
+		row := Row{}
+		row.Line, _ = reader.FieldPos(0)
+		entry, err := parseRecord(c, record, loc)
+		if err == nil {
+			err = foodentries.Validate(entry)
+		}
+		row.Entry = entry
+		if err != nil {
+			row.Error = err.Error()
+		}
+		rows = append(rows, row)
+	}
+	return format, rows, nil
+}
+
+func blank(record []string) bool {
+	for _, v := range record {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func parseDate(raw string, loc *time.Location) (time.Time, error) {
+	for _, layout := range []string{time.DateOnly, "1/2/2006", "01/02/2006"} {
+		if t, err := time.ParseInLocation(layout, raw, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("date is not a date: %q", raw)
+}
+
+// nutrients reads the nutrient columns named in order calories, protein,
+// carbohydrates, fat, fiber, sugar.
+func nutrients(c columns, record []string, e *foodentries.Entry, names [6]string) error {
+	dest := []*int{&e.Calories, &e.Protein, &e.Carbohydrates, &e.Fat, &e.Fiber, &e.Sugar}
+	for i, name := range names {
+		v, err := c.number(record, name)
+		if err != nil {
+			return err
+		}
+		*dest[i] = v
+	}
+	return nil
+}
+
+// parseMyFitnessPal reads the "Nutrition" export, which has one row per meal and
+// day. The note, when there is one, names the entry.
+func parseMyFitnessPal(c columns, record []string, loc *time.Location) (foodentries.Entry, error) {
+	day, err := parseDate(c.get(record, "Date"), loc)
+	if err != nil {
+		return foodentries.Entry{}, err
+	}
+	meal := c.get(record, "Meal")
+	e := foodentries.Entry{
+		Name:     c.get(record, "Note"),
+		Portion:  1,
+		Unit:     "meal",
+		MealType: mealType(meal),
+	}
+	if e.Name == "" {
+		e.Name = "MyFitnessPal " + strings.ToLower(meal)
+	}
+	e.ConsumedAt = atMealTime(day, e.MealType)
+	err = nutrients(c, record, &e, [6]string{"Calories", "Protein (g)", "Carbohydrates (g)", "Fat (g)", "Fiber", "Sugar"})
+	return e, err
+}
+
+// parseCronometer reads the "Servings" export, which has one row per food.
+func parseCronometer(c columns, record []string, loc *time.Location) (foodentries.Entry, error) {
+	day, err := parseDate(c.get(record, "Day"), loc)
+	if err != nil {
+		return foodentries.Entry{}, err
+	}
+	e := foodentries.Entry{Name: c.get(record, "Food Name"), MealType: mealType(c.get(record, "Group"))}
+	e.Portion, e.Unit = amount(c.get(record, "Amount"))
+
+	e.ConsumedAt = atMealTime(day, e.MealType)
+	if raw := c.get(record, "Time"); raw != "" {
+		clock, err := parseClock(raw)
+		if err != nil {
+			return foodentries.Entry{}, err
+		}
+		e.ConsumedAt = time.Date(day.Year(), day.Month(), day.Day(), clock.Hour(), clock.Minute(), 0, 0, loc)
+	}
+	err = nutrients(c, record, &e, [6]string{"Energy (kcal)", "Protein (g)", "Carbs (g)", "Fat (g)", "Fiber (g)", "Sugars (g)"})
+	return e, err
+}
+
+func parseClock(raw string) (time.Time, error) {
+	for _, layout := range []string{"15:04", "15:04:05", "3:04 PM", "3:04PM"} {
+		if t, err := time.Parse(layout, strings.ToUpper(raw)); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("time is not a time of day: %q", raw)
+}
+
+// amount splits an amount such as "1.50 cup" into a whole-number portion and a
+// unit that fits food_entries.
+func amount(raw string) (int, string) {
+	number, unit, _ := strings.Cut(strings.TrimSpace(raw), " ")
+	portion := 1
+	if v, err := strconv.ParseFloat(number, 64); err == nil && v >= 0.5 {
+		portion = int(math.Round(v))
+	}
+	unit = strings.TrimSpace(unit)
+	if unit == "" {
+		unit = "serving"
+	}
+	if len(unit) > 20 {
+		unit = unit[:20]
+	}
+	return portion, unit
+}
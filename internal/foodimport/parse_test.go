@@ -0,0 +1,103 @@
+package foodimport
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func parseFile(t *testing.T, name string, loc *time.Location) (string, []Row) {
+	t.Helper()
+	f, err := os.Open("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	format, rows, err := Parse(f, "", loc)
+	if err != nil {
+		t.Fatalf("failed to parse %s: %v", name, err)
+	}
+	return format, rows
+}
+
+func TestParse_MyFitnessPal(t *testing.T) {
+	format, rows := parseFile(t, "myfitnesspal.csv", time.UTC)
+	if format != MyFitnessPal || len(rows) != 4 {
+		t.Fatalf("expected 4 MyFitnessPal rows, got %s %d", format, len(rows))
+	}
+
+	breakfast := rows[0].Entry
+	if breakfast.Name != "MyFitnessPal breakfast" || breakfast.Calories != 420 || breakfast.Protein != 18 || breakfast.Carbohydrates != 60 || breakfast.Sugar != 12 {
+		t.Errorf("unexpected breakfast %+v", breakfast)
+	}
+	if want := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC); !breakfast.ConsumedAt.Equal(want) {
+		t.Errorf("expected the default breakfast time, got %v", breakfast.ConsumedAt)
+	}
+	if dinner := rows[1].Entry; dinner.Name != "Lasagna night" || dinner.Calories != 1050 {
+		t.Errorf("expected the note to name the entry and 1,050 to parse, got %+v", dinner)
+	}
+	if rows[2].Entry.MealType != "snack" {
+		t.Errorf("expected custom meals to become snacks, got %s", rows[2].Entry.MealType)
+	}
+	if bad := rows[3]; bad.Line != 6 || !strings.Contains(bad.Error, "Calories") {
+		t.Errorf("expected line 6 to report Calories, got %+v", bad)
+	}
+}
+
+func TestParse_MealTimesOnDaylightSavingDays(t *testing.T) {
+	loc, err := time.LoadLocation("America/Denver")
+	if err != nil {
+		t.Fatal(err)
+	}
+	export := "Date,Meal,Calories,Fat (g),Carbohydrates (g),Fiber,Sugar,Protein (g),Note\n" +
+		"2024-03-10,Breakfast,400,10,50,5,10,20,\n" +
+		"2024-11-03,Dinner,800,30,90,8,12,40,\n"
+	_, rows, err := Parse(strings.NewReader(export), MyFitnessPal, loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := rows[0].Entry.ConsumedAt.In(loc); got.Hour() != 8 || got.Minute() != 0 {
+		t.Errorf("expected breakfast at 08:00 on the day DST starts, got %v", got)
+	}
+	if got := rows[1].Entry.ConsumedAt.In(loc); got.Hour() != 18 || got.Minute() != 30 {
+		t.Errorf("expected dinner at 18:30 on the day DST ends, got %v", got)
+	}
+}
+
+func TestParse_Cronometer(t *testing.T) {
+	loc, err := time.LoadLocation("America/Denver")
+	if err != nil {
+		t.Fatal(err)
+	}
+	format, rows := parseFile(t, "cronometer.csv", loc)
+	if format != Cronometer || len(rows) != 4 {
+		t.Fatalf("expected 4 Cronometer rows, got %s %d", format, len(rows))
+	}
+
+	oats := rows[0].Entry
+	if oats.Name != "Oats, Rolled" || oats.Portion != 1 || oats.Unit != "cup" || oats.Calories != 307 || oats.Fiber != 8 {
+		t.Errorf("unexpected oats %+v", oats)
+	}
+	if want := time.Date(2024, 5, 1, 13, 45, 0, 0, time.UTC); !oats.ConsumedAt.Equal(want) {
+		t.Errorf("expected 7:45 AM in Denver, got %v", oats.ConsumedAt.UTC())
+	}
+	if chicken := rows[1].Entry; chicken.Portion != 150 || chicken.Unit != "g" || chicken.ConsumedAt.In(loc).Hour() != 12 {
+		t.Errorf("unexpected chicken %+v", chicken)
+	}
+	if apple := rows[2].Entry; apple.Portion != 1 || apple.MealType != "snack" {
+		t.Errorf("expected a quarter apple to round up to one snack, got %+v", apple)
+	}
+	if !strings.Contains(rows[3].Error, "time") {
+		t.Errorf("expected an invalid time, got %+v", rows[3])
+	}
+}
+
+func TestParse_UnknownFormat(t *testing.T) {
+	if _, _, err := Parse(strings.NewReader("name,kcal\nApple,95\n"), "", time.UTC); err != ErrUnknownFormat {
+		t.Errorf("expected ErrUnknownFormat, got %v", err)
+	}
+	if _, _, err := Parse(strings.NewReader(""), "", time.UTC); err != ErrUnknownFormat {
+		t.Errorf("expected ErrUnknownFormat for an empty file, got %v", err)
+	}
+}
@@ -0,0 +1,127 @@
+package foodlog
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/coloradocollective/go-capstone-starter/internal/auth"
+	"github.com/coloradocollective/go-capstone-starter/internal/foodentries"
+	"github.com/coloradocollective/go-capstone-starter/internal/foods"
+	"github.com/coloradocollective/go-capstone-starter/internal/profile"
+)
+
+// maxTextLength keeps a description to what someone would type for one meal.
+const maxTextLength = 1000
+
+type handler struct {
+	parser    Parser
+	lookup    foods.FoodLookup
+	locations profile.Locations
+	now       func() time.Time
+}
+
+// Handlers registers the free-text logging endpoint. It only drafts entries; the
+// confirmed drafts are created together through POST /api/food-entries/batch.
+// lookup is shared with the foods endpoints, so both use one cache.
+func Handlers(db *sql.DB, lookup foods.FoodLookup, authenticator auth.Authenticator) func(mux *http.ServeMux) {
+	return routes(NewParserFromEnv(), lookup, profile.NewPostgresStore(db), authenticator)
+}
+
+func routes(parser Parser, lookup foods.FoodLookup, locations profile.Locations, authenticator auth.Authenticator) func(mux *http.ServeMux) {
+	h := &handler{parser: parser, lookup: lookup, locations: locations, now: time.Now}
+	return func(mux *http.ServeMux) {
+		mux.HandleFunc("POST /api/food-entries/parse", authenticator.Require(h.parse))
+	}
+}
+
+// ParseInput is the request body of the parse endpoint. A meal named in the text
+// wins over MealType, which wins over a guess from the time. ConsumedAt defaults
+// to now.
+type ParseInput struct {
+	Text       string `json:"text"`
+	MealType   string `json:"meal_type"`
+	ConsumedAt string `json:"consumed_at"`
+}
+
+// ParseResult lists one draft per item, in the order of the text.
+type ParseResult struct {
+	Parser string  `json:"parser"`
+	Items  []Draft `json:"items"`
+}
+
+func (h *handler) parse(w http.ResponseWriter, r *http.Request, userID int) {
+	var in ParseInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	in.Text = strings.TrimSpace(in.Text)
+	if in.Text == "" || len(in.Text) > maxTextLength {
+		http.Error(w, fmt.Sprintf("text is required and must be at most %d characters", maxTextLength), http.StatusBadRequest)
+		return
+	}
+	if in.MealType != "" && !slices.Contains(foodentries.MealTypes, in.MealType) {
+		http.Error(w, fmt.Sprintf("meal_type must be one of %v", foodentries.MealTypes), http.StatusBadRequest)
+		return
+	}
+	loc, err := h.locations.Location(r.Context(), userID)
+	if err != nil {
+		log.Printf("Failed to load time zone for user %d: %v", userID, err)
+		http.Error(w, "Failed to load time zone", http.StatusInternalServerError)
+		return
+	}
+	consumedAt := h.now().In(loc)
+	if in.ConsumedAt != "" {
+		if consumedAt, err = parseTime(in.ConsumedAt, loc); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	mealType := in.MealType
+	if mealType == "" {
+		mealType = mealAt(consumedAt)
+	}
+
+	items, err := h.parser.Parse(r.Context(), in.Text)
+	if err != nil {
+		log.Printf("Failed to parse food text with %s: %v", h.parser.Model(), err)
+		http.Error(w, "Failed to understand the text; try listing the foods separated by commas", http.StatusBadGateway)
+		return
+	}
+	if len(items) > foodentries.MaxBatchEntries {
+		http.Error(w, fmt.Sprintf("at most %d foods can be logged at once", foodentries.MaxBatchEntries), http.StatusBadRequest)
+		return
+	}
+
+	drafts, err := resolveAll(r.Context(), h.lookup, items, mealType, consumedAt)
+	if err != nil {
+		// The client has gone; there is no one to answer.
+		return
+	}
+	writeJSON(w, ParseResult{Parser: h.parser.Model(), Items: drafts})
+}
+
+// parseTime accepts the same forms as a food entry's consumed_at.
+func parseTime(raw string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t.In(loc), nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02T15:04:05", raw, loc); err == nil {
+		return t, nil
+	}
+	return time.Time{}, errors.New("consumed_at must be a timestamp such as 2024-05-01T08:30:00Z")
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
@@ -0,0 +1,161 @@
+package foodlog
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coloradocollective/go-capstone-starter/internal/auth/authtest"
+	"github.com/coloradocollective/go-capstone-starter/internal/foods"
+)
+
+type zone struct{ loc *time.Location }
+
+func (z zone) Location(context.Context, int) (*time.Location, error) { return z.loc, nil }
+
+func ptr(v float64) *float64 { return &v }
+
+// stubFoods answers per 100 g, with a serving weight for eggs and toast only.
+func stubFoods() *foods.StubProvider {
+	return &foods.StubProvider{ProviderName: foods.ProviderLocal, Foods: map[string]foods.Food{
+		"scrambled eggs": {Name: "Egg, scrambled", ServingQuantityG: ptr(61), Per100g: foods.Nutrients{Calories: ptr(148), Protein: ptr(10)}},
+		"toast":          {Name: "Toast", ServingQuantityG: ptr(30), Per100g: foods.Nutrients{Calories: ptr(290), Carbohydrates: ptr(50)}},
+		"butter":         {Name: "Butter", Per100g: foods.Nutrients{Calories: ptr(717), Fat: ptr(81)}},
+		"black coffee":   {Name: "Coffee", Per100g: foods.Nutrients{Calories: ptr(1)}},
+	}}
+}
+
+func parse(t *testing.T, lookup foods.FoodLookup, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	denver, err := time.LoadLocation("America/Denver")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	routes(NewRuleParser(), lookup, zone{denver}, authtest.Auth)(mux)
+	req := httptest.NewRequest(http.MethodPost, "/api/food-entries/parse", strings.NewReader(body))
+	authtest.Sign(t, req, 4)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	return rec
+}
+
+func decode(t *testing.T, rec *httptest.ResponseRecorder) ParseResult {
+	t.Helper()
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var result ParseResult
+	if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	return result
+}
+
+func TestParse(t *testing.T) {
+	rec := parse(t, foods.NewChain(stubFoods()), `{"text": "2 scrambled eggs, a slice of toast with 1 tbsp butter and black coffee for breakfast",
+		"consumed_at": "2024-05-01T14:30:00Z"}`)
+	result := decode(t, rec)
+	if result.Parser != "local-rules" || len(result.Items) != 4 {
+		t.Fatalf("unexpected result %+v", result)
+	}
+
+	eggs := result.Items[0]
+	if eggs.Grams != 122 || eggs.Provider != foods.ProviderLocal || eggs.Match != "Egg, scrambled" || eggs.Entry == nil {
+		t.Fatalf("unexpected draft %+v", eggs)
+	}
+	e := *eggs.Entry
+	if e.Name != "scrambled eggs" || e.Portion != 122 || e.Unit != "g" || e.Calories != 181 || e.Protein != 12 ||
+		e.MealType != "breakfast" || e.ConsumedAt != "2024-05-01T08:30:00-06:00" {
+		t.Errorf("unexpected entry %+v", e)
+	}
+	if butter := result.Items[2]; butter.Grams != 15 || butter.Entry.Fat != 12 || butter.Note == "" {
+		t.Errorf("expected a tablespoon converted by volume, got %+v", butter)
+	}
+	if coffee := result.Items[3]; coffee.Grams != 100 || !strings.Contains(coffee.Note, "assumed") {
+		t.Errorf("expected an assumed serving, got %+v", coffee)
+	}
+}
+
+func TestParse_MealAndUnknownFoods(t *testing.T) {
+	rec := parse(t, foods.NewChain(stubFoods()), `{"text": "toast and dragon fruit", "consumed_at": "2024-05-01T19:00:00"}`)
+	result := decode(t, rec)
+	if toast := result.Items[0]; toast.Entry == nil || toast.Entry.MealType != "dinner" {
+		t.Errorf("expected the meal to be guessed from 7 pm, got %+v", toast)
+	}
+	if fruit := result.Items[1]; fruit.Entry != nil || fruit.Error != "no nutrition found" {
+		t.Errorf("expected an unknown food to be left for the user, got %+v", fruit)
+	}
+
+	rec = parse(t, foods.NewChain(stubFoods()), `{"text": "toast", "meal_type": "snack", "consumed_at": "2024-05-01T19:00:00"}`)
+	if toast := decode(t, rec).Items[0]; toast.Entry.MealType != "snack" {
+		t.Errorf("expected the requested meal, got %+v", toast.Entry)
+	}
+
+	down := &foods.StubProvider{ProviderName: foods.ProviderNutritionix, Err: errors.New("quota exceeded")}
+	rec = parse(t, foods.NewChain(down), `{"text": "toast"}`)
+	if toast := decode(t, rec).Items[0]; toast.Error != "food lookup is unavailable" {
+		t.Errorf("expected the outage on the draft, got %+v", toast)
+	}
+}
+
+// stuckLookup never answers, and records how many lookups wait at once.
+type stuckLookup struct {
+	mu            sync.Mutex
+	waiting, most int
+}
+
+func (l *stuckLookup) Lookup(ctx context.Context, _ string) (foods.LookupResult, error) {
+	l.mu.Lock()
+	l.waiting++
+	l.most = max(l.most, l.waiting)
+	l.mu.Unlock()
+	<-ctx.Done()
+	l.mu.Lock()
+	l.waiting--
+	l.mu.Unlock()
+	return foods.LookupResult{}, ctx.Err()
+}
+
+func TestParse_BoundsLookups(t *testing.T) {
+	timeout := resolveTimeout
+	resolveTimeout = 50 * time.Millisecond
+	t.Cleanup(func() { resolveTimeout = timeout })
+
+	lookup := &stuckLookup{}
+	names := []string{"apple", "banana", "cherry", "date", "elderberry", "fig", "grape", "honeydew", "kiwi", "lime"}
+	result := decode(t, parse(t, lookup, `{"text": "`+strings.Join(names, ", ")+`"}`))
+	if len(result.Items) != len(names) {
+		t.Fatalf("expected a draft per food, got %+v", result.Items)
+	}
+	for i, item := range result.Items {
+		if item.Food != names[i] || item.Error != "food lookup timed out" {
+			t.Errorf("item %d: expected %s to time out in order, got %+v", i, names[i], item)
+		}
+	}
+	if lookup.most != resolveWorkers {
+		t.Errorf("expected at most %d lookups at once, got %d", resolveWorkers, lookup.most)
+	}
+}
+
+func TestParse_Rejects(t *testing.T) {
+	invalid := map[string]string{
+		"no text":   `{"text": "  "}`,
+		"long text": `{"text": "` + strings.Repeat("toast, ", 200) + `"}`,
+		"meal type": `{"text": "toast", "meal_type": "brunch"}`,
+		"bad time":  `{"text": "toast", "consumed_at": "yesterday"}`,
+		"too many":  `{"text": "` + strings.Repeat("egg, ", 51) + `"}`,
+		"not json":  `{`,
+	}
+	for name, body := range invalid {
+		if rec := parse(t, foods.NewChain(stubFoods()), body); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", name, rec.Code)
+		}
+	}
+}
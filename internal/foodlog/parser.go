@@ -0,0 +1,225 @@
+// Package foodlog turns a free-text description of a meal into food entries.
+package foodlog
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/coloradocollective/go-capstone-starter/internal/foodentries"
+	"github.com/sashabaranov/go-openai"
+)
+
+// Item is one food of a description, such as "2 scrambled eggs". Unit is empty
+// when Quantity counts the food itself.
+type Item struct {
+	Text     string  `json:"text"`
+	Quantity float64 `json:"quantity"`
+	Unit     string  `json:"unit"`
+	Food     string  `json:"food"`
+	MealType string  `json:"meal_type,omitempty"`
+}
+
+// Parser splits a description into items.
+type Parser interface {
+	Model() string
+	Parse(ctx context.Context, text string) ([]Item, error)
+}
+
+// NewParserFromEnv builds a Parser from FOOD_PARSER_BACKEND ("openai" or "local").
+// The OpenAI backend uses the chat model of MODEL_NAME, MODEL_BASE_URL and
+// OPENAI_API_KEY, like the analyzer. Without OPENAI_API_KEY it falls back to the
+// local parser, with a warning, rather than failing every request.
+func NewParserFromEnv() Parser {
+	switch os.Getenv("FOOD_PARSER_BACKEND") {
+	case "local":
+		return NewRuleParser()
+	default:
+		if os.Getenv("OPENAI_API_KEY") == "" {
+			log.Printf("Warning: OPENAI_API_KEY is not set; parsing food text with the local parser")
+			return NewRuleParser()
+		}
+		model := os.Getenv("MODEL_NAME")
+		if model == "" {
+			model = openai.GPT4oMini
+		}
+		return NewOpenAIParser(os.Getenv("OPENAI_API_KEY"), os.Getenv("MODEL_BASE_URL"), model)
+	}
+}
+
+// clean drops items without a food and fills in what a parser left out, so every
+// backend hands the same shape to the lookup.
+func clean(items []Item) []Item {
+	var cleaned []Item
+	for _, item := range items {
+		item.Food = strings.TrimSpace(item.Food)
+		if item.Food == "" {
+			continue
+		}
+		if item.Quantity <= 0 {
+			item.Quantity = 1
+		}
+		item.Unit = canonicalUnit(strings.ToLower(strings.TrimSpace(item.Unit)))
+		if !slices.Contains(foodentries.MealTypes, item.MealType) {
+			item.MealType = ""
+		}
+		if item.Text == "" {
+			item.Text = item.Food
+		}
+		cleaned = append(cleaned, item)
+	}
+	return cleaned
+}
+
+// OpenAIParser asks a chat model of an OpenAI-compatible API to split the text.
+type OpenAIParser struct {
+	client *openai.Client
+	model  string
+}
+
+func NewOpenAIParser(apiKey, baseURL, model string) *OpenAIParser {
+	cfg := openai.DefaultConfig(apiKey)
+	if baseURL != "" {
+		cfg.BaseURL = baseURL
+	}
+	return &OpenAIParser{client: openai.NewClientWithConfig(cfg), model: model}
+}
+
+func (p *OpenAIParser) Model() string { return p.model }
+
+const parsePrompt = `Split the user's description of what they ate into separate foods.
+Answer with JSON only, in the form {"items": [{"text": "...", "quantity": 1, "unit": "...", "food": "...", "meal_type": "..."}]}.
+- text: the words of the description the item came from.
+- quantity: a number; 1 when no amount is given; 0.5 for "half".
+- unit: one of g, kg, oz, lb, ml, l, cup, tbsp, tsp, slice, piece, bowl, glass, can, serving, or "" when the quantity counts the food itself ("2 eggs").
+- food: the food without the amount, singular, keeping words that change its nutrition ("scrambled egg", "black coffee").
+- meal_type: breakfast, lunch, dinner or snack when the description says so, otherwise "".
+Toppings and additions ("toast with butter") are separate items.`
+
+func (p *OpenAIParser) Parse(ctx context.Context, text string) ([]Item, error) {
+	resp, err := p.client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+		Model: p.model,
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: parsePrompt},
+			{Role: openai.ChatMessageRoleUser, Content: text},
+		},
+		ResponseFormat: &openai.ChatCompletionResponseFormat{Type: openai.ChatCompletionResponseFormatTypeJSONObject},
+		Temperature:    0,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Choices) == 0 {
+		return nil, errors.New("model response contained no choices")
+	}
+	var parsed struct {
+		Items []Item `json:"items"`
+	}
+	if err := json.Unmarshal([]byte(resp.Choices[0].Message.Content), &parsed); err != nil {
+		return nil, errors.New("model response was not the requested JSON")
+	}
+	return clean(parsed.Items), nil
+}
+
+// RuleParser is an offline stand-in for the model. It splits on commas and
+// joining words and reads a leading amount, so "mac and cheese" becomes two
+// items; it is meant for local development and tests.
+type RuleParser struct{}
+
+func NewRuleParser() RuleParser { return RuleParser{} }
+
+func (RuleParser) Model() string { return "local-rules" }
+
+var (
+	mealPattern      = regexp.MustCompile(`\b(?:for|at|with|as)\s+(?:my\s+|a\s+)?(breakfast|lunch|dinner|snack)\b`)
+	separatorPattern = regexp.MustCompile(`\s*(?:[,;&+]|\band\b|\bwith\b|\bplus\b)\s*`)
+	gluedUnitPattern = regexp.MustCompile(`^(\d+(?:\.\d+)?)(g|kg|oz|lb|ml|l)\b`)
+	amountPattern    = regexp.MustCompile(`^(\d+\s+\d+/\d+|\d+/\d+|\d+(?:\.\d+)?|a couple of|half an?|half|an?|one|two|three|four|five|six|seven|eight|nine|ten|some)\b\s*`)
+)
+
+var numberWords = map[string]float64{
+	"a": 1, "an": 1, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7, "eight": 8,
+	"nine": 9, "ten": 10, "half": 0.5, "half a": 0.5, "half an": 0.5, "a couple of": 2, "some": 1,
+}
+
+// units maps the spellings the rule parser and models use to one name per unit.
+var units = map[string]string{
+	"g": "g", "gram": "g", "grams": "g", "kg": "kg", "kilogram": "kg", "kilograms": "kg",
+	"oz": "oz", "ounce": "oz", "ounces": "oz", "lb": "lb", "lbs": "lb", "pound": "lb", "pounds": "lb",
+	"ml": "ml", "milliliter": "ml", "milliliters": "ml", "l": "l", "liter": "l", "liters": "l",
+	"cup": "cup", "cups": "cup", "tbsp": "tbsp", "tablespoon": "tbsp", "tablespoons": "tbsp",
+	"tsp": "tsp", "teaspoon": "tsp", "teaspoons": "tsp",
+	"slice": "slice", "slices": "slice", "piece": "piece", "pieces": "piece", "bowl": "bowl", "bowls": "bowl",
+	"glass": "glass", "glasses": "glass", "can": "can", "cans": "can", "serving": "serving", "servings": "serving",
+	"handful": "handful", "handfuls": "handful", "scoop": "scoop", "scoops": "scoop",
+}
+
+func canonicalUnit(unit string) string {
+	if canonical, ok := units[unit]; ok {
+		return canonical
+	}
+	return unit
+}
+
+func (RuleParser) Parse(_ context.Context, text string) ([]Item, error) {
+	text = strings.ToLower(strings.TrimSpace(text))
+	mealType := ""
+	if m := mealPattern.FindStringSubmatch(text); m != nil {
+		mealType = m[1]
+		text = mealPattern.ReplaceAllString(text, "")
+	}
+	text = strings.TrimRight(text, ".!? ")
+
+	var items []Item
+	for _, part := range separatorPattern.Split(text, -1) {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		item := Item{Text: part, Quantity: 1, MealType: mealType}
+		// "100g rice" reads like "100 g rice".
+		rest := gluedUnitPattern.ReplaceAllString(part, "$1 $2")
+		if m := amountPattern.FindStringSubmatch(rest); m != nil {
+			item.Quantity = amount(m[1])
+			rest = rest[len(m[0]):]
+		}
+		if word, after, _ := strings.Cut(rest, " "); units[word] != "" && after != "" {
+			item.Unit = units[word]
+			rest = strings.TrimPrefix(strings.TrimSpace(after), "of ")
+		}
+		item.Food = strings.TrimSpace(rest)
+		items = append(items, item)
+	}
+	return clean(items), nil
+}
+
+// amount reads a number, a fraction such as 1/2 or 1 1/2, or a number word.
+func amount(raw string) float64 {
+	if v, ok := numberWords[raw]; ok {
+		return v
+	}
+	whole, fraction, mixed := strings.Cut(raw, " ")
+	if !mixed {
+		whole, fraction = "", raw
+	}
+	var total float64
+	if whole != "" {
+		total, _ = strconv.ParseFloat(whole, 64)
+	}
+	if num, den, ok := strings.Cut(fraction, "/"); ok {
+		n, _ := strconv.ParseFloat(num, 64)
+		d, _ := strconv.ParseFloat(den, 64)
+		if d != 0 {
+			return total + n/d
+		}
+		return total
+	}
+	v, _ := strconv.ParseFloat(fraction, 64)
+	return total + v
+}
@@ -0,0 +1,82 @@
+package foodlog
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestRuleParser(t *testing.T) {
+	items, err := NewRuleParser().Parse(context.Background(), "2 scrambled eggs, a slice of toast with butter and black coffee for breakfast.")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []Item{
+		{Text: "2 scrambled eggs", Quantity: 2, Food: "scrambled eggs", MealType: "breakfast"},
+		{Text: "a slice of toast", Quantity: 1, Unit: "slice", Food: "toast", MealType: "breakfast"},
+		{Text: "butter", Quantity: 1, Food: "butter", MealType: "breakfast"},
+		{Text: "black coffee", Quantity: 1, Food: "black coffee", MealType: "breakfast"},
+	}
+	if !reflect.DeepEqual(items, want) {
+		t.Errorf("expected %+v, got %+v", want, items)
+	}
+}
+
+func TestRuleParser_Amounts(t *testing.T) {
+	cases := map[string]Item{
+		"150g greek yogurt":           {Quantity: 150, Unit: "g", Food: "greek yogurt"},
+		"1 1/2 cups of rice":          {Quantity: 1.5, Unit: "cup", Food: "rice"},
+		"half an avocado":             {Quantity: 0.5, Food: "avocado"},
+		"a couple of apples":          {Quantity: 2, Food: "apples"},
+		"3 tablespoons peanut butter": {Quantity: 3, Unit: "tbsp", Food: "peanut butter"},
+		"apple":                       {Quantity: 1, Food: "apple"},
+	}
+	for text, want := range cases {
+		items, _ := NewRuleParser().Parse(context.Background(), text)
+		if len(items) != 1 {
+			t.Errorf("%s: expected one item, got %+v", text, items)
+			continue
+		}
+		want.Text = text
+		if items[0] != want {
+			t.Errorf("%s: expected %+v, got %+v", text, want, items[0])
+		}
+	}
+}
+
+func TestOpenAIParser(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Model          string
+			ResponseFormat struct{ Type string } `json:"response_format"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Model != "test-model" || req.ResponseFormat.Type != "json_object" {
+			t.Errorf("unexpected request %+v", req)
+		}
+		content := `{"items": [
+			{"text": "2 scrambled eggs", "quantity": 2, "unit": "", "food": "scrambled egg", "meal_type": "breakfast"},
+			{"text": "a slice of toast", "quantity": 0, "unit": "Slices", "food": "toast", "meal_type": "brunch"},
+			{"text": "and", "quantity": 1, "unit": "", "food": " "}
+		]}`
+		json.NewEncoder(w).Encode(map[string]any{
+			"choices": []map[string]any{{"message": map[string]string{"role": "assistant", "content": content}}},
+		})
+	}))
+	defer server.Close()
+
+	items, err := NewOpenAIParser("key", server.URL, "test-model").Parse(context.Background(), "2 scrambled eggs and a slice of toast")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []Item{
+		{Text: "2 scrambled eggs", Quantity: 2, Food: "scrambled egg", MealType: "breakfast"},
+		{Text: "a slice of toast", Quantity: 1, Unit: "slice", Food: "toast"},
+	}
+	if !reflect.DeepEqual(items, want) {
+		t.Errorf("expected the model's items cleaned up to %+v, got %+v", want, items)
+	}
+}
@@ -0,0 +1,154 @@
+package foodlog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coloradocollective/go-capstone-starter/internal/foodentries"
+	"github.com/coloradocollective/go-capstone-starter/internal/foods"
+)
+
+// gramsPer converts weights exactly and volumes as if they were water, which is
+// close enough for drinks, soups and the spoonfuls of a meal.
+var gramsPer = map[string]float64{
+	"g": 1, "kg": 1000, "oz": 28.35, "lb": 453.6,
+	"ml": 1, "l": 1000, "cup": 240, "tbsp": 15, "tsp": 5,
+}
+
+var volumes = map[string]bool{"ml": true, "l": true, "cup": true, "tbsp": true, "tsp": true}
+
+// assumedServingG stands in for a serving whose weight the provider does not know.
+const assumedServingG = 100
+
+// Draft is an item with the entry it would create. Entry is nil, and Error set,
+// when the item's nutrition could not be found; the user can then fill it in or
+// leave it out before confirming.
+type Draft struct {
+	Item
+	Provider string             `json:"provider,omitempty"`
+	Match    string             `json:"match,omitempty"`
+	Grams    float64            `json:"grams,omitempty"`
+	Note     string             `json:"note,omitempty"`
+	Error    string             `json:"error,omitempty"`
+	Entry    *foodentries.Input `json:"entry,omitempty"`
+}
+
+// weight works out how many grams an item is, noting any assumption made.
+func weight(item Item, food foods.Food) (float64, string) {
+	if per, ok := gramsPer[item.Unit]; ok {
+		note := ""
+		if volumes[item.Unit] {
+			note = "volume converted as 1 ml = 1 g"
+		}
+		return item.Quantity * per, note
+	}
+	if food.ServingQuantityG != nil {
+		return item.Quantity * *food.ServingQuantityG, ""
+	}
+	return item.Quantity * assumedServingG, fmt.Sprintf("serving weight unknown; assumed %d g", assumedServingG)
+}
+
+func whole(v *float64) int {
+	if v == nil {
+		return 0
+	}
+	return int(math.Round(*v))
+}
+
+// Resolving limits: at most resolveWorkers lookups run at once, and items still
+// unresolved after resolveTimeout are drafted without nutrition.
+var (
+	resolveWorkers = 4
+	resolveTimeout = 15 * time.Second
+)
+
+// resolveAll drafts every item, keeping their order. It only fails when ctx ends.
+func resolveAll(ctx context.Context, lookup foods.FoodLookup, items []Item, mealType string, consumedAt time.Time) ([]Draft, error) {
+	lookupCtx, cancel := context.WithTimeout(ctx, resolveTimeout)
+	defer cancel()
+
+	drafts := make([]Draft, len(items))
+	slots := make(chan struct{}, resolveWorkers)
+	var wg sync.WaitGroup
+	for i, item := range items {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			d, err := resolve(lookupCtx, lookup, item, mealType, consumedAt)
+			if err != nil {
+				d = Draft{Item: item, Error: "food lookup timed out"}
+			}
+			drafts[i] = d
+		}()
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return drafts, nil
+}
+
+// resolve looks an item up and drafts its entry. Lookup failures are reported on
+// the draft so that one unknown food does not hold up the rest of the meal.
+func resolve(ctx context.Context, lookup foods.FoodLookup, item Item, mealType string, consumedAt time.Time) (Draft, error) {
+	d := Draft{Item: item}
+	result, err := lookup.Lookup(ctx, item.Food)
+	switch {
+	case ctx.Err() != nil:
+		return Draft{}, ctx.Err()
+	case errors.Is(err, foods.ErrNotFound):
+		d.Error = "no nutrition found"
+		return d, nil
+	case err != nil:
+		d.Error = "food lookup is unavailable"
+		return d, nil
+	}
+	if item.MealType != "" {
+		mealType = item.MealType
+	}
+
+	grams, note := weight(item, result.Food)
+	n := result.Per100g.Scale(grams)
+	name := item.Food
+	if len(name) > 255 {
+		name = strings.ToValidUTF8(name[:255], "")
+	}
+	d.Provider, d.Match, d.Grams, d.Note = result.Provider, result.Name, grams, note
+	d.Entry = &foodentries.Input{
+		Name:          name,
+		Portion:       int(math.Round(grams)),
+		Unit:          "g",
+		Calories:      whole(n.Calories),
+		Protein:       whole(n.Protein),
+		Carbohydrates: whole(n.Carbohydrates),
+		Fat:           whole(n.Fat),
+		Fiber:         whole(n.Fiber),
+		Sugar:         whole(n.Sugar),
+		MealType:      mealType,
+		ConsumedAt:    consumedAt.Format(time.RFC3339),
+	}
+	return d, nil
+}
+
+// mealAt guesses the meal of a time of day when the description does not say.
+func mealAt(t time.Time) string {
+	switch h := t.Hour(); {
+	case h >= 4 && h < 11:
+		return "breakfast"
+	case h >= 11 && h < 15:
+		return "lunch"
+	case h >= 17 && h < 22:
+		return "dinner"
+	default:
+		return "snack"
+	}
+}
@@ -0,0 +1,152 @@
+package foods
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"strings"
+
+	"github.com/coloradocollective/go-capstone-starter/internal/auth"
+)
+
+type handler struct {
+	products ProductStore
+	lookup   FoodLookup
+}
+
+// Handlers registers the food lookup endpoints. Barcodes are reference data, like
+// recipes, and need no sign-in; searches may reach a metered API, so they do.
+func Handlers(db *sql.DB, lookup FoodLookup, authenticator auth.Authenticator) func(mux *http.ServeMux) {
+	return routes(NewPostgresStore(db), lookup, authenticator)
+}
+
+func routes(products ProductStore, lookup FoodLookup, authenticator auth.Authenticator) func(mux *http.ServeMux) {
+	h := &handler{products: products, lookup: lookup}
+	return func(mux *http.ServeMux) {
+		mux.HandleFunc("GET /api/foods/barcode/{code}", h.barcode)
+		mux.Handle("GET /api/foods/lookup", authenticator.Require(h.search))
+	}
+}
+
+// EntryPrefill holds the food entry fields a food fills in, in the shape the
+// add-entry form posts.
+type EntryPrefill struct {
+	Name          string `json:"name"`
+	Portion       int    `json:"portion"`
+	Unit          string `json:"unit"`
+	Calories      int    `json:"calories"`
+	Protein       int    `json:"protein"`
+	Carbohydrates int    `json:"carbohydrates"`
+	Fat           int    `json:"fat"`
+	Fiber         int    `json:"fiber"`
+	Sugar         int    `json:"sugar"`
+}
+
+// Food is the response of a lookup. PerServing is nil when the serving weight is
+// unknown.
+type Food struct {
+	Barcode          string       `json:"barcode"`
+	Name             string       `json:"name"`
+	Brand            string       `json:"brand,omitempty"`
+	ServingSize      string       `json:"serving_size,omitempty"`
+	ServingQuantityG *float64     `json:"serving_quantity_g,omitempty"`
+	Per100g          Nutrients    `json:"per_100g"`
+	PerServing       *Nutrients   `json:"per_serving"`
+	Entry            EntryPrefill `json:"entry"`
+	Source           string       `json:"source"`
+	Image            string       `json:"image,omitempty"`
+}
+
+func whole(v *float64) int {
+	if v == nil {
+		return 0
+	}
+	return int(math.Round(*v))
+}
+
+// fill sets the entry's nutrients, rounded to whole units as entries store them.
+func (e *EntryPrefill) fill(n Nutrients) {
+	e.Calories, e.Protein, e.Carbohydrates = whole(n.Calories), whole(n.Protein), whole(n.Carbohydrates)
+	e.Fat, e.Fiber, e.Sugar = whole(n.Fat), whole(n.Fiber), whole(n.Sugar)
+}
+
+// NewFood presents a product, prefilling one serving when its weight is known
+// and 100 g otherwise.
+func NewFood(p Product) Food {
+	f := Food{
+		Barcode:          p.Barcode,
+		Name:             p.Name,
+		Brand:            p.Brand,
+		ServingSize:      p.ServingSize,
+		ServingQuantityG: p.ServingQuantityG,
+		Per100g:          p.Per100g.Scale(100),
+		Source:           p.Source,
+	}
+	entry, prefill := EntryPrefill{Name: p.Name, Portion: 100, Unit: "g"}, f.Per100g
+	if p.ServingQuantityG != nil {
+		serving := p.Per100g.Scale(*p.ServingQuantityG)
+		f.PerServing = &serving
+		entry, prefill = EntryPrefill{Name: p.Name, Portion: 1, Unit: "serving"}, serving
+	}
+	if p.Brand != "" && len(p.Brand)+len(p.Name) < 250 {
+		entry.Name = p.Brand + " " + p.Name
+	}
+	entry.fill(prefill)
+	if len(entry.Name) > 255 {
+		entry.Name = strings.ToValidUTF8(entry.Name[:255], "")
+	}
+	f.Entry = entry
+	return f
+}
+
+func (h *handler) barcode(w http.ResponseWriter, r *http.Request) {
+	code, err := NormalizeBarcode(r.PathValue("code"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	product, err := h.products.Product(r.Context(), code)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Product not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to look up barcode %s: %v", code, err)
+		http.Error(w, "Failed to look up barcode", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, NewFood(product))
+}
+
+// maxQueryLength keeps pasted text from being sent to the providers.
+const maxQueryLength = 200
+
+func (h *handler) search(w http.ResponseWriter, r *http.Request, _ int) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" || len(query) > maxQueryLength {
+		http.Error(w, "q is required and must be at most 200 characters", http.StatusBadRequest)
+		return
+	}
+	result, err := h.lookup.Lookup(r.Context(), query)
+	switch {
+	case errors.Is(err, ErrNotFound):
+		http.Error(w, "Food not found", http.StatusNotFound)
+	case errors.Is(err, ErrUnavailable):
+		http.Error(w, "Food lookup is unavailable; try again later", http.StatusBadGateway)
+	case err != nil:
+		log.Printf("Failed to look up %q: %v", query, err)
+		http.Error(w, "Failed to look up food", http.StatusInternalServerError)
+	default:
+		writeJSON(w, result)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
@@ -0,0 +1,98 @@
+package foods
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/coloradocollective/go-capstone-starter/internal/auth/authtest"
+)
+
+func lookup(t *testing.T, store ProductStore, code string) *httptest.ResponseRecorder {
+	t.Helper()
+	mux := http.NewServeMux()
+	routes(store, NewChain(NewLocalProvider(store)), authtest.Auth)(mux)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/foods/barcode/"+code, nil))
+	return rec
+}
+
+func TestBarcode(t *testing.T) {
+	store, _ := load(t, "products.csv", DumpCSV)
+
+	rec := lookup(t, store, "012345678905")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var food Food
+	if err := json.NewDecoder(rec.Body).Decode(&food); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if food.PerServing == nil || *food.PerServing.Protein != 10 || *food.Per100g.Protein != 25 {
+		t.Errorf("expected 10 g of protein per 40 g bar, got %+v", food)
+	}
+	want := EntryPrefill{Name: `Acme Peanut "Butter" Bar`, Portion: 1, Unit: "serving", Calories: 191, Protein: 10, Carbohydrates: 12, Fiber: 2, Sugar: 4}
+	if food.Entry != want {
+		t.Errorf("expected %+v, got %+v", want, food.Entry)
+	}
+
+	rec = lookup(t, store, "5000000000002")
+	json.NewDecoder(rec.Body).Decode(&food)
+	if food.PerServing != nil || food.Entry.Portion != 100 || food.Entry.Unit != "g" || food.Entry.Protein != 10 {
+		t.Errorf("expected a 100 g prefill without a serving weight, got %+v", food)
+	}
+
+	if rec := lookup(t, store, "0000000000000"); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", rec.Code)
+	}
+	if rec := lookup(t, store, "nutella"); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", rec.Code)
+	}
+}
+
+func search(t *testing.T, lookup FoodLookup, query string, signedIn bool) *httptest.ResponseRecorder {
+	t.Helper()
+	mux := http.NewServeMux()
+	routes(memoryProducts{}, lookup, authtest.Auth)(mux)
+	req := httptest.NewRequest(http.MethodGet, "/api/foods/lookup?q="+query, nil)
+	if signedIn {
+		authtest.Sign(t, req, 1)
+	}
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestLookup(t *testing.T) {
+	local := &StubProvider{ProviderName: ProviderLocal}
+	nix := &StubProvider{ProviderName: ProviderNutritionix, Foods: map[string]Food{"banana": {Name: "banana", Source: ProviderNutritionix}}}
+	chain := NewChain(local, nix)
+
+	rec := search(t, chain, "banana", true)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var result LookupResult
+	if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if result.Provider != ProviderNutritionix || result.Name != "banana" || result.Cached {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	if rec := search(t, chain, "banana", false); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without a token, got %d", rec.Code)
+	}
+	if rec := search(t, chain, "", true); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 without a query, got %d", rec.Code)
+	}
+	if rec := search(t, chain, "durian", true); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", rec.Code)
+	}
+	nix.Err = errors.New("quota exceeded")
+	if rec := search(t, chain, "banana", true); rec.Code != http.StatusBadGateway {
+		t.Errorf("expected 502 when a provider is down, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,245 @@
+package foods
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Provider names, in the default lookup order.
+const (
+	ProviderLocal         = "local"
+	ProviderOpenFoodFacts = SourceOpenFoodFacts
+	ProviderNutritionix   = "nutritionix"
+)
+
+// DefaultProviders is the chain used when FOOD_LOOKUP_PROVIDERS is unset: our own
+// products first, then the free API, then the metered one.
+var DefaultProviders = []string{ProviderLocal, ProviderOpenFoodFacts, ProviderNutritionix}
+
+// DefaultCacheTTL is how long a lookup result is reused.
+const DefaultCacheTTL = 24 * time.Hour
+
+// providerTimeout bounds each provider so a hanging API falls through to the next.
+const providerTimeout = 5 * time.Second
+
+var (
+	// ErrNotFound is returned by a provider that does not know the food.
+	ErrNotFound = errors.New("food not found")
+	// ErrUnavailable is returned by a lookup that found nothing because at least
+	// one provider failed.
+	ErrUnavailable = errors.New("food lookup unavailable")
+)
+
+// LookupProvider finds the nutrition of a food by name or barcode.
+type LookupProvider interface {
+	Name() string
+	// Lookup returns ErrNotFound when the provider has no match.
+	Lookup(ctx context.Context, query string) (Food, error)
+}
+
+// LookupResult is a food together with the provider that answered.
+type LookupResult struct {
+	Provider string `json:"provider"`
+	Cached   bool   `json:"cached"`
+	Food
+}
+
+// FoodLookup answers a query from one or more providers.
+type FoodLookup interface {
+	Lookup(ctx context.Context, query string) (LookupResult, error)
+}
+
+// Chain asks its providers in order and returns the first match.
+type Chain struct {
+	providers []LookupProvider
+}
+
+func NewChain(providers ...LookupProvider) *Chain {
+	return &Chain{providers: providers}
+}
+
+// Lookup returns ErrNotFound when every provider answered without a match, and
+// ErrUnavailable, wrapping the failures, when some could not answer at all.
+func (c *Chain) Lookup(ctx context.Context, query string) (LookupResult, error) {
+	var failures []error
+	for _, p := range c.providers {
+		providerCtx, cancel := context.WithTimeout(ctx, providerTimeout)
+		food, err := p.Lookup(providerCtx, query)
+		cancel()
+		if err == nil {
+			return LookupResult{Provider: p.Name(), Food: food}, nil
+		}
+		if ctx.Err() != nil {
+			return LookupResult{}, ctx.Err()
+		}
+		if !errors.Is(err, ErrNotFound) {
+			log.Printf("Food lookup provider %s failed: %v", p.Name(), err)
+			failures = append(failures, fmt.Errorf("%s: %w", p.Name(), err))
+		}
+	}
+	if len(failures) > 0 {
+		return LookupResult{}, fmt.Errorf("%w: %w", ErrUnavailable, errors.Join(failures...))
+	}
+	return LookupResult{}, ErrNotFound
+}
+
+// CachedLookup keeps lookup results in Redis. Misses are not cached, so a food
+// added to a provider shows up on the next search. Redis errors are logged and
+// the lookup goes to the providers.
+type CachedLookup struct {
+	next   FoodLookup
+	client *redis.Client
+	ttl    time.Duration
+}
+
+func NewCachedLookup(next FoodLookup, client *redis.Client, ttl time.Duration) *CachedLookup {
+	return &CachedLookup{next: next, client: client, ttl: ttl}
+}
+
+// cacheKey ignores case and spacing so "Greek  Yogurt" and "greek yogurt" share
+// an entry.
+func cacheKey(query string) string {
+	return "foods:lookup:" + strings.Join(strings.Fields(strings.ToLower(query)), " ")
+}
+
+func (c *CachedLookup) Lookup(ctx context.Context, query string) (LookupResult, error) {
+	key := cacheKey(query)
+	cached, err := c.client.Get(ctx, key).Bytes()
+	if err == nil {
+		var result LookupResult
+		if err := json.Unmarshal(cached, &result); err == nil {
+			result.Cached = true
+			return result, nil
+		}
+	} else if !errors.Is(err, redis.Nil) {
+		log.Printf("Failed to read food lookup cache: %v", err)
+	}
+
+	result, err := c.next.Lookup(ctx, query)
+	if err != nil {
+		return LookupResult{}, err
+	}
+	if data, err := json.Marshal(result); err == nil {
+		if err := c.client.Set(ctx, key, data, c.ttl).Err(); err != nil {
+			log.Printf("Failed to write food lookup cache: %v", err)
+		}
+	}
+	return result, nil
+}
+
+// StubProvider answers from a fixed map, for tests and offline development. It
+// returns Err, when set, for every query.
+type StubProvider struct {
+	ProviderName string
+	Foods        map[string]Food
+	Err          error
+	Calls        int
+}
+
+func (s *StubProvider) Name() string { return s.ProviderName }
+
+func (s *StubProvider) Lookup(_ context.Context, query string) (Food, error) {
+	s.Calls++
+	if s.Err != nil {
+		return Food{}, s.Err
+	}
+	food, ok := s.Foods[strings.ToLower(strings.TrimSpace(query))]
+	if !ok {
+		return Food{}, ErrNotFound
+	}
+	return food, nil
+}
+
+// LocalProvider answers from the products table.
+type LocalProvider struct {
+	products ProductStore
+}
+
+func NewLocalProvider(products ProductStore) *LocalProvider {
+	return &LocalProvider{products: products}
+}
+
+func (l *LocalProvider) Name() string { return ProviderLocal }
+
+// Lookup treats a query of digits as a barcode and anything else as a name.
+func (l *LocalProvider) Lookup(ctx context.Context, query string) (Food, error) {
+	var product Product
+	var err error
+	if barcode, invalid := NormalizeBarcode(query); invalid == nil {
+		product, err = l.products.Product(ctx, barcode)
+	} else {
+		product, err = l.products.Match(ctx, query)
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return Food{}, ErrNotFound
+	}
+	if err != nil {
+		return Food{}, err
+	}
+	return NewFood(product), nil
+}
+
+// ProvidersFromEnv builds the chain named by FOOD_LOOKUP_PROVIDERS, a comma-separated
+// list of local, openfoodfacts and nutritionix. Nutritionix reads NUTRITIONIX_APP_ID
+// and NUTRITIONIX_APP_KEY and is left out, with a warning, when they are unset.
+func ProvidersFromEnv(products ProductStore, client *http.Client) ([]LookupProvider, error) {
+	names := DefaultProviders
+	if raw := strings.TrimSpace(os.Getenv("FOOD_LOOKUP_PROVIDERS")); raw != "" {
+		names = strings.Split(raw, ",")
+	}
+	var providers []LookupProvider
+	for _, name := range names {
+		switch strings.TrimSpace(name) {
+		case ProviderLocal:
+			providers = append(providers, NewLocalProvider(products))
+		case ProviderOpenFoodFacts:
+			providers = append(providers, NewOpenFoodFactsProvider(client, os.Getenv("OPEN_FOOD_FACTS_URL")))
+		case ProviderNutritionix:
+			appID, appKey := os.Getenv("NUTRITIONIX_APP_ID"), os.Getenv("NUTRITIONIX_APP_KEY")
+			if appID == "" || appKey == "" {
+				log.Printf("Warning: NUTRITIONIX_APP_ID or NUTRITIONIX_APP_KEY is unset; skipping Nutritionix lookups")
+				continue
+			}
+			providers = append(providers, NewNutritionixProvider(client, os.Getenv("NUTRITIONIX_URL"), appID, appKey))
+		default:
+			return nil, fmt.Errorf("unknown food lookup provider %q", name)
+		}
+	}
+	return providers, nil
+}
+
+// LookupFromEnv builds the cached provider chain that the food search uses.
+func LookupFromEnv(products ProductStore, client *redis.Client) (FoodLookup, error) {
+	providers, err := ProvidersFromEnv(products, &http.Client{Timeout: 10 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+	ttl, err := CacheTTLFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	return NewCachedLookup(NewChain(providers...), client, ttl), nil
+}
+
+// CacheTTLFromEnv reads FOOD_LOOKUP_CACHE_TTL, a Go duration such as "12h".
+func CacheTTLFromEnv() (time.Duration, error) {
+	raw := os.Getenv("FOOD_LOOKUP_CACHE_TTL")
+	if raw == "" {
+		return DefaultCacheTTL, nil
+	}
+	ttl, err := time.ParseDuration(raw)
+	if err != nil || ttl <= 0 {
+		return 0, fmt.Errorf("invalid FOOD_LOOKUP_CACHE_TTL %q", raw)
+	}
+	return ttl, nil
+}
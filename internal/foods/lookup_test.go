@@ -0,0 +1,177 @@
+package foods
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestChain_FallsThrough(t *testing.T) {
+	local := &StubProvider{ProviderName: ProviderLocal}
+	off := &StubProvider{ProviderName: ProviderOpenFoodFacts, Err: errors.New("connection refused")}
+	nix := &StubProvider{ProviderName: ProviderNutritionix, Foods: map[string]Food{"apple": {Name: "apple"}}}
+	chain := NewChain(local, off, nix)
+
+	result, err := chain.Lookup(context.Background(), " Apple ")
+	if err != nil || result.Provider != ProviderNutritionix || result.Name != "apple" {
+		t.Fatalf("expected nutritionix to answer, got %+v, %v", result, err)
+	}
+	if local.Calls != 1 || off.Calls != 1 {
+		t.Errorf("expected each provider to be asked once, got %d and %d", local.Calls, off.Calls)
+	}
+
+	if _, err := chain.Lookup(context.Background(), "durian"); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("expected ErrUnavailable when a provider failed, got %v", err)
+	}
+	if _, err := NewChain(local, nix).Lookup(context.Background(), "durian"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestCachedLookup(t *testing.T) {
+	mr := miniredis.RunT(t)
+	stub := &StubProvider{ProviderName: ProviderLocal, Foods: map[string]Food{"greek yogurt": {Name: "Greek Yogurt"}}}
+	cached := NewCachedLookup(NewChain(stub), redis.NewClient(&redis.Options{Addr: mr.Addr()}), time.Hour)
+	ctx := context.Background()
+
+	first, err := cached.Lookup(ctx, "greek yogurt")
+	if err != nil || first.Cached {
+		t.Fatalf("expected an uncached answer, got %+v, %v", first, err)
+	}
+	second, err := cached.Lookup(ctx, "Greek  Yogurt")
+	if err != nil || !second.Cached || second.Provider != ProviderLocal || second.Name != "Greek Yogurt" {
+		t.Fatalf("expected the cached answer, got %+v, %v", second, err)
+	}
+	if stub.Calls != 1 {
+		t.Errorf("expected one provider call, got %d", stub.Calls)
+	}
+	if ttl := mr.TTL("foods:lookup:greek yogurt"); ttl != time.Hour {
+		t.Errorf("expected a one hour TTL, got %v", ttl)
+	}
+
+	cached.Lookup(ctx, "durian")
+	cached.Lookup(ctx, "durian")
+	if stub.Calls != 3 {
+		t.Errorf("expected misses not to be cached, got %d calls", stub.Calls)
+	}
+
+	mr.FastForward(time.Hour)
+	cached.Lookup(ctx, "greek yogurt")
+	if stub.Calls != 4 {
+		t.Errorf("expected an expired entry to be looked up again, got %d calls", stub.Calls)
+	}
+
+	mr.Close()
+	if result, err := cached.Lookup(ctx, "greek yogurt"); err != nil || result.Cached {
+		t.Errorf("expected lookups to work without Redis, got %+v, %v", result, err)
+	}
+}
+
+func TestLocalProvider(t *testing.T) {
+	store, _ := load(t, "products.csv", DumpCSV)
+	local := NewLocalProvider(store)
+
+	if food, err := local.Lookup(context.Background(), "012345678905"); err != nil || food.Barcode != "0012345678905" {
+		t.Errorf("expected a barcode match, got %+v, %v", food, err)
+	}
+	if food, err := local.Lookup(context.Background(), "nutella"); err != nil || food.Name != "Nutella" {
+		t.Errorf("expected a name match, got %+v, %v", food, err)
+	}
+	if _, err := local.Lookup(context.Background(), "durian"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestOpenFoodFactsProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("User-Agent") != userAgent {
+			t.Errorf("expected a user agent, got %q", r.Header.Get("User-Agent"))
+		}
+		switch r.URL.Path {
+		case "/api/v2/product/3017620422003.json":
+			w.Write([]byte(`{"status":1,"product":{"code":"3017620422003","product_name":"Nutella","serving_quantity":15,
+				"nutriments":{"energy-kcal_100g":539,"proteins_100g":6.3},"image_front_small_url":"https://img/nutella.jpg"}}`))
+		case "/cgi/search.pl":
+			if r.URL.Query().Get("search_terms") != "peanut butter" {
+				t.Errorf("unexpected search %q", r.URL.RawQuery)
+			}
+			// The first result has no nutrition and is passed over.
+			w.Write([]byte(`{"products":[{"code":"1111111111111","product_name":"Peanut Butter"},
+				{"code":"2222222222222","product_name":"Smooth Peanut Butter","nutriments":{"energy-kcal_100g":"588"}}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"status":0}`))
+		}
+	}))
+	defer server.Close()
+	off := NewOpenFoodFactsProvider(server.Client(), server.URL)
+
+	food, err := off.Lookup(context.Background(), "3017620422003")
+	if err != nil || food.Image != "https://img/nutella.jpg" || *food.PerServing.Calories != 80.9 {
+		t.Errorf("unexpected barcode lookup %+v, %v", food, err)
+	}
+	food, err = off.Lookup(context.Background(), "peanut butter")
+	if err != nil || food.Barcode != "2222222222222" || food.Entry.Calories != 588 {
+		t.Errorf("unexpected search %+v, %v", food, err)
+	}
+	if _, err := off.Lookup(context.Background(), "0000000000000"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestNutritionixProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("x-app-id") != "id" || r.Header.Get("x-app-key") != "key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"message":"unauthorized"}`))
+			return
+		}
+		var body struct{ Query string }
+		json.NewDecoder(r.Body).Decode(&body)
+		if body.Query != "2 eggs" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message":"We couldn't match any of your foods"}`))
+			return
+		}
+		w.Write([]byte(`{"foods":[{"food_name":"egg","serving_qty":2,"serving_unit":"large","serving_weight_grams":100,
+			"nf_calories":143,"nf_protein":12.56,"nf_total_fat":9.51,"nf_total_carbohydrate":0.72,"nf_sugars":0.37,
+			"photo":{"thumb":"https://img/egg.jpg"}}]}`))
+	}))
+	defer server.Close()
+
+	nix := NewNutritionixProvider(server.Client(), server.URL, "id", "key")
+	food, err := nix.Lookup(context.Background(), "2 eggs")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := EntryPrefill{Name: "egg", Portion: 1, Unit: "serving", Calories: 143, Protein: 13, Carbohydrates: 1, Fat: 10}
+	if food.Entry != want || food.ServingSize != "2 large" || *food.Per100g.Protein != 12.6 || food.Per100g.Fiber != nil {
+		t.Errorf("unexpected food %+v, entry %+v", food, food.Entry)
+	}
+	if _, err := nix.Lookup(context.Background(), "zzz"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if _, err := NewNutritionixProvider(server.Client(), server.URL, "id", "wrong").Lookup(context.Background(), "2 eggs"); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("expected a key error, got %v", err)
+	}
+}
+
+func TestProvidersFromEnv(t *testing.T) {
+	t.Setenv("FOOD_LOOKUP_PROVIDERS", "nutritionix, local")
+	t.Setenv("NUTRITIONIX_APP_ID", "")
+	providers, err := ProvidersFromEnv(memoryProducts{}, http.DefaultClient)
+	if err != nil || len(providers) != 1 || providers[0].Name() != ProviderLocal {
+		t.Errorf("expected nutritionix to be skipped without keys, got %v, %v", providers, err)
+	}
+	t.Setenv("FOOD_LOOKUP_PROVIDERS", "local,usda")
+	if _, err := ProvidersFromEnv(memoryProducts{}, http.DefaultClient); err == nil {
+		t.Error("expected an error for an unknown provider")
+	}
+}
@@ -0,0 +1,304 @@
+package foods
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Dump formats published by Open Food Facts.
+const (
+	DumpCSV   = "csv"   // tab-separated export, en.openfoodfacts.org.products.csv
+	DumpJSONL = "jsonl" // one product per line, openfoodfacts-products.jsonl
+)
+
+// SourceOpenFoodFacts marks products loaded from Open Food Facts.
+const SourceOpenFoodFacts = "openfoodfacts"
+
+// DefaultBatchSize is the number of products written per statement.
+const DefaultBatchSize = 500
+
+// kJPerKcal converts energy given only in kilojoules.
+const kJPerKcal = 4.184
+
+// offNutrients maps our nutrients to Open Food Facts nutriment keys.
+var offNutrients = []string{"energy-kcal_100g", "proteins_100g", "carbohydrates_100g", "fat_100g", "fiber_100g", "sugars_100g"}
+
+// csvNutrientColumns adds the kilojoule fallback to the columns read from the CSV dump.
+var csvNutrientColumns = append(append([]string{}, offNutrients...), "energy_100g")
+
+// offRecord is what the loader reads from either dump format.
+type offRecord struct {
+	code, name, brands, servingSize string
+	servingQuantity                 string
+	modified                        string
+	nutriments                      map[string]string
+}
+
+// product converts a record, returning false for ones not worth keeping: no valid
+// barcode, no name or no nutrition.
+func (r offRecord) product() (Product, bool) {
+	barcode, err := NormalizeBarcode(r.code)
+	name := strings.TrimSpace(r.name)
+	if err != nil || name == "" {
+		return Product{}, false
+	}
+	p := Product{
+		Barcode:     barcode,
+		Name:        name,
+		Brand:       firstBrand(r.brands),
+		ServingSize: strings.TrimSpace(r.servingSize),
+		Source:      SourceOpenFoodFacts,
+	}
+	if q, ok := number(r.servingQuantity); ok && q > 0 && q < 5000 {
+		p.ServingQuantityG = &q
+	}
+	if t, err := strconv.ParseInt(r.modified, 10, 64); err == nil && t > 0 {
+		p.ModifiedAt = time.Unix(t, 0).UTC()
+	}
+
+	fields := []**float64{&p.Per100g.Calories, &p.Per100g.Protein, &p.Per100g.Carbohydrates, &p.Per100g.Fat, &p.Per100g.Fiber, &p.Per100g.Sugar}
+	for i, key := range offNutrients {
+		v, ok := number(r.nutriments[key])
+		if i == 0 && !ok {
+			if kj, found := number(r.nutriments["energy_100g"]); found {
+				v, ok = kj/kJPerKcal, true
+			}
+		}
+		// Crowd-sourced values include typos; drop the impossible ones.
+		limit := 100.0
+		if i == 0 {
+			limit = 1000
+		}
+		if ok && v >= 0 && v <= limit {
+			*fields[i] = &v
+		}
+	}
+	if p.Per100g.Empty() {
+		return Product{}, false
+	}
+	return p, true
+}
+
+func firstBrand(brands string) string {
+	brand, _, _ := strings.Cut(brands, ",")
+	return strings.TrimSpace(brand)
+}
+
+func number(raw string) (float64, bool) {
+	v, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+	return v, err == nil
+}
+
+// maxCSVLine bounds one line of the CSV dump; a few products have very long
+// ingredient lists.
+const maxCSVLine = 16 << 20
+
+// readCSV streams the tab-separated dump. Despite its name it is not CSV: fields
+// are never quoted and hold no tabs or newlines, while quote characters occur
+// anywhere in them. Each line is therefore one record, split on tabs, and quotes
+// are taken literally.
+func readCSV(r io.Reader, fn func(offRecord) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 1<<20), maxCSVLine)
+	split := func() []string {
+		return strings.Split(strings.TrimSuffix(scanner.Text(), "\r"), "\t")
+	}
+
+	if !scanner.Scan() {
+		err := scanner.Err()
+		if err == nil {
+			err = io.EOF
+		}
+		return fmt.Errorf("failed to read the header: %w", err)
+	}
+	header := split()
+	index := map[string]int{}
+	for i, name := range header {
+		index[name] = i
+	}
+	if _, ok := index["code"]; !ok {
+		return errors.New("the header has no code column; is this an Open Food Facts CSV dump?")
+	}
+	get := func(record []string, name string) string {
+		if i, ok := index[name]; ok && i < len(record) {
+			return record[i]
+		}
+		return ""
+	}
+
+	for scanner.Scan() {
+		record := split()
+		rec := offRecord{
+			code:            get(record, "code"),
+			name:            get(record, "product_name"),
+			brands:          get(record, "brands"),
+			servingSize:     get(record, "serving_size"),
+			servingQuantity: get(record, "serving_quantity"),
+			modified:        get(record, "last_modified_t"),
+			nutriments:      map[string]string{},
+		}
+		for _, key := range csvNutrientColumns {
+			rec.nutriments[key] = get(record, key)
+		}
+		if err := fn(rec); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// flexible decodes the JSON dump's values, which are numbers or strings
+// depending on the product.
+type flexible string
+
+func (f *flexible) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*f = flexible(s)
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err == nil {
+		*f = flexible(n)
+		return nil
+	}
+	// Arrays, objects and nulls carry nothing we read.
+	*f = ""
+	return nil
+}
+
+type offJSON struct {
+	Code            flexible            `json:"code"`
+	ProductName     flexible            `json:"product_name"`
+	Brands          flexible            `json:"brands"`
+	ServingSize     flexible            `json:"serving_size"`
+	ServingQuantity flexible            `json:"serving_quantity"`
+	LastModified    flexible            `json:"last_modified_t"`
+	Nutriments      map[string]flexible `json:"nutriments"`
+	// Image is only requested from the API; the dumps leave it out.
+	Image flexible `json:"image_front_small_url"`
+}
+
+func (p offJSON) record() offRecord {
+	rec := offRecord{
+		code:            string(p.Code),
+		name:            string(p.ProductName),
+		brands:          string(p.Brands),
+		servingSize:     string(p.ServingSize),
+		servingQuantity: string(p.ServingQuantity),
+		modified:        string(p.LastModified),
+		nutriments:      map[string]string{},
+	}
+	for k, v := range p.Nutriments {
+		rec.nutriments[k] = string(v)
+	}
+	return rec
+}
+
+// readJSONL streams the JSON Lines dump, skipping lines that do not parse.
+func readJSONL(r io.Reader, fn func(offRecord) error) error {
+	reader := bufio.NewReaderSize(r, 1<<20)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(strings.TrimSpace(string(line))) > 0 {
+			var p offJSON
+			if json.Unmarshal(line, &p) == nil {
+				if err := fn(p.record()); err != nil {
+					return err
+				}
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// LoadStats counts the products of a dump. Stale products were valid but older
+// than the stored version, or than another row of the dump, which was kept.
+type LoadStats struct {
+	Read    int
+	Loaded  int
+	Stale   int
+	Skipped int
+}
+
+// LoadOpenFoodFacts streams a dump into store in batches. progress, if set, runs
+// after each batch.
+func LoadOpenFoodFacts(ctx context.Context, r io.Reader, format string, store ProductStore, batchSize int, progress func(LoadStats)) (LoadStats, error) {
+	var read func(io.Reader, func(offRecord) error) error
+	switch format {
+	case DumpCSV:
+		read = readCSV
+	case DumpJSONL:
+		read = readJSONL
+	default:
+		return LoadStats{}, fmt.Errorf("unknown dump format %q", format)
+	}
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+
+	var stats LoadStats
+	// Keyed by barcode: a statement cannot update the same row twice.
+	batch := map[string]Product{}
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		products := make([]Product, 0, len(batch))
+		for _, p := range batch {
+			products = append(products, p)
+		}
+		written, err := store.Upsert(ctx, products)
+		if err != nil {
+			return err
+		}
+		stats.Loaded += written
+		stats.Stale += len(products) - written
+		batch = map[string]Product{}
+		if progress != nil {
+			progress(stats)
+		}
+		return nil
+	}
+
+	err := read(r, func(rec offRecord) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		stats.Read++
+		p, ok := rec.product()
+		if !ok {
+			stats.Skipped++
+			return nil
+		}
+		// A barcode listed twice in one batch keeps its newer row, as Upsert would
+		// across batches; the other one is stale.
+		if queued, dup := batch[p.Barcode]; dup {
+			stats.Stale++
+			if p.ModifiedAt.Before(queued.ModifiedAt) {
+				return nil
+			}
+		}
+		batch[p.Barcode] = p
+		if len(batch) >= batchSize {
+			return flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return stats, err
+	}
+	return stats, flush()
+}
@@ -0,0 +1,174 @@
+package foods
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+type memoryProducts map[string]Product
+
+func (m memoryProducts) Product(_ context.Context, barcode string) (Product, error) {
+	p, ok := m[barcode]
+	if !ok {
+		return Product{}, sql.ErrNoRows
+	}
+	return p, nil
+}
+
+func (m memoryProducts) Match(_ context.Context, name string) (Product, error) {
+	var best Product
+	for _, p := range m {
+		if strings.Contains(strings.ToLower(p.Name), strings.ToLower(name)) && (best.Name == "" || len(p.Name) < len(best.Name)) {
+			best = p
+		}
+	}
+	if best.Name == "" {
+		return Product{}, sql.ErrNoRows
+	}
+	return best, nil
+}
+
+func (m memoryProducts) Upsert(_ context.Context, products []Product) (int, error) {
+	written := 0
+	for _, p := range products {
+		if stored, ok := m[p.Barcode]; ok && p.ModifiedAt.Before(stored.ModifiedAt) {
+			continue
+		}
+		m[p.Barcode] = p
+		written++
+	}
+	return written, nil
+}
+
+func load(t *testing.T, file, format string) (memoryProducts, LoadStats) {
+	t.Helper()
+	f, err := os.Open("testdata/" + file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	store := memoryProducts{}
+	batches := 0
+	stats, err := LoadOpenFoodFacts(context.Background(), f, format, store, 1, func(LoadStats) { batches++ })
+	if err != nil {
+		t.Fatalf("failed to load %s: %v", file, err)
+	}
+	if batches != stats.Loaded+stats.Stale {
+		t.Errorf("expected a progress call per batch of one, got %d for %+v", batches, stats)
+	}
+	return store, stats
+}
+
+func TestLoad_CSV(t *testing.T) {
+	store, stats := load(t, "products.csv", DumpCSV)
+	if stats.Read != 5 || stats.Loaded != 3 || stats.Skipped != 2 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	nutella := store["3017620422003"]
+	if nutella.Brand != "Ferrero" || *nutella.Per100g.Calories != 539 || nutella.Per100g.Fiber != nil || *nutella.ServingQuantityG != 15 {
+		t.Errorf("unexpected product %+v", nutella)
+	}
+	if nutella.ModifiedAt.Unix() != 1700000000 {
+		t.Errorf("unexpected modification time %v", nutella.ModifiedAt)
+	}
+
+	bar, ok := store["0012345678905"]
+	if !ok {
+		t.Fatal("expected the UPC-A code to be stored as EAN-13")
+	}
+	if bar.Name != `Peanut "Butter" Bar` || *bar.Per100g.Calories < 477 || *bar.Per100g.Calories > 479 {
+		t.Errorf("expected calories converted from 2000 kJ, got %+v", bar)
+	}
+	if crackers := store["5000000000002"]; crackers.Per100g.Calories != nil || *crackers.Per100g.Protein != 10 {
+		t.Errorf("expected the impossible calorie value to be dropped, got %+v", crackers.Per100g)
+	}
+}
+
+func TestLoad_CSVTakesQuotesLiterally(t *testing.T) {
+	dump := "code\tproduct_name\tenergy-kcal_100g\n" +
+		"3017620422003\t\"Bio\" Milk\t64\n" +
+		"5000000000002\tBread\t250\n" +
+		"4000000000000\tJam\t240\n"
+	store := memoryProducts{}
+	stats, err := LoadOpenFoodFacts(context.Background(), strings.NewReader(dump), DumpCSV, store, 10, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Read != 3 || stats.Loaded != 3 {
+		t.Fatalf("expected every line to be its own product, got %+v", stats)
+	}
+	if got := store["3017620422003"].Name; got != `"Bio" Milk` {
+		t.Errorf("expected the quotes to be kept, got %q", got)
+	}
+	if store["5000000000002"].Name != "Bread" || store["4000000000000"].Name != "Jam" {
+		t.Errorf("expected the lines after the quote to be read, got %+v", store)
+	}
+}
+
+func TestLoad_CountsStaleProducts(t *testing.T) {
+	newer := Product{Barcode: "3017620422003", Name: "Nutella", ModifiedAt: time.Unix(1800000000, 0).UTC()}
+	store := memoryProducts{newer.Barcode: newer}
+	f, err := os.Open("testdata/products.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	stats, err := LoadOpenFoodFacts(context.Background(), f, DumpCSV, store, 10, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Loaded != 2 || stats.Stale != 1 || store[newer.Barcode].ModifiedAt != newer.ModifiedAt {
+		t.Errorf("expected the older Nutella to be left out, got %+v", stats)
+	}
+}
+
+func TestLoad_KeepsTheNewerDuplicateInABatch(t *testing.T) {
+	dump := "code\tproduct_name\tenergy-kcal_100g\tlast_modified_t\n" +
+		"3017620422003\tNutella\t539\t1800000000\n" +
+		"3017620422003\tOld Nutella\t530\t1700000000\n" +
+		"5000000000002\tBread\t250\t1700000000\n" +
+		"5000000000002\tNew Bread\t260\t1800000000\n"
+	store := memoryProducts{}
+	stats, err := LoadOpenFoodFacts(context.Background(), strings.NewReader(dump), DumpCSV, store, 10, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Read != 4 || stats.Loaded != 2 || stats.Stale != 2 || stats.Skipped != 0 {
+		t.Errorf("expected the older rows to count as stale, got %+v", stats)
+	}
+	if store["3017620422003"].Name != "Nutella" || store["5000000000002"].Name != "New Bread" {
+		t.Errorf("expected the newer rows to be stored, got %+v", store)
+	}
+}
+
+func TestLoad_JSONL(t *testing.T) {
+	store, stats := load(t, "products.jsonl", DumpJSONL)
+	if stats.Read != 3 || stats.Loaded != 2 || stats.Skipped != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+	if p := store["3017620422003"]; *p.Per100g.Protein != 6.3 || *p.ServingQuantityG != 15 {
+		t.Errorf("expected string and number values to parse, got %+v", p)
+	}
+	if water := store["4000000000000"]; *water.Per100g.Calories != 0 || water.ServingQuantityG != nil {
+		t.Errorf("expected zero calories to be kept, got %+v", water)
+	}
+}
+
+func TestNormalizeBarcode(t *testing.T) {
+	cases := map[string]string{"012345678905": "0012345678905", "3017-6204 22003": "3017620422003", "96385074": "96385074"}
+	for raw, want := range cases {
+		if got, err := NormalizeBarcode(raw); err != nil || got != want {
+			t.Errorf("%s: expected %s, got %s, %v", raw, want, got, err)
+		}
+	}
+	for _, raw := range []string{"1234567", "12345abc90123", "123456789012345"} {
+		if _, err := NormalizeBarcode(raw); err != ErrInvalidBarcode {
+			t.Errorf("%s: expected ErrInvalidBarcode, got %v", raw, err)
+		}
+	}
+}
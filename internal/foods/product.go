@@ -0,0 +1,90 @@
+// Package foods looks up nutrition for foods that are not yet food entries.
+package foods
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Product is one row of products. Nutrients are per 100 g.
+type Product struct {
+	Barcode          string
+	Name             string
+	Brand            string
+	ServingSize      string
+	ServingQuantityG *float64
+	Per100g          Nutrients
+	Source           string
+	ModifiedAt       time.Time
+}
+
+// Nutrients are amounts in kcal or grams; nil when unknown.
+type Nutrients struct {
+	Calories      *float64 `json:"calories"`
+	Protein       *float64 `json:"protein"`
+	Carbohydrates *float64 `json:"carbohydrates"`
+	Fat           *float64 `json:"fat"`
+	Fiber         *float64 `json:"fiber"`
+	Sugar         *float64 `json:"sugar"`
+}
+
+func (n Nutrients) fields() []*float64 {
+	return []*float64{n.Calories, n.Protein, n.Carbohydrates, n.Fat, n.Fiber, n.Sugar}
+}
+
+// Empty reports whether no nutrient is known.
+func (n Nutrients) Empty() bool {
+	for _, v := range n.fields() {
+		if v != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Scale returns the nutrients of grams of food, rounded to one decimal.
+func (n Nutrients) Scale(grams float64) Nutrients {
+	scale := func(v *float64) *float64 {
+		if v == nil {
+			return nil
+		}
+		s := math.Round(*v*grams/100*10) / 10
+		return &s
+	}
+	return Nutrients{
+		Calories:      scale(n.Calories),
+		Protein:       scale(n.Protein),
+		Carbohydrates: scale(n.Carbohydrates),
+		Fat:           scale(n.Fat),
+		Fiber:         scale(n.Fiber),
+		Sugar:         scale(n.Sugar),
+	}
+}
+
+var ErrInvalidBarcode = errors.New("barcode must be 8 to 14 digits")
+
+// NormalizeBarcode strips spaces and dashes and stores 12-digit UPC-A codes as
+// the equivalent EAN-13, so scanners reporting either find the same product.
+func NormalizeBarcode(raw string) (string, error) {
+	code := strings.Map(func(r rune) rune {
+		if r == ' ' || r == '-' {
+			return -1
+		}
+		return r
+	}, raw)
+	if len(code) < 8 || len(code) > 14 {
+		return "", ErrInvalidBarcode
+	}
+	for _, r := range code {
+		if !unicode.IsDigit(r) {
+			return "", ErrInvalidBarcode
+		}
+	}
+	if len(code) == 12 {
+		code = "0" + code
+	}
+	return code, nil
+}
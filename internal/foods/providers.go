@@ -0,0 +1,199 @@
+package foods
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	defaultOpenFoodFactsURL = "https://world.openfoodfacts.org"
+	defaultNutritionixURL   = "https://trackapi.nutritionix.com"
+	// userAgent identifies us to Open Food Facts, which asks API clients for one.
+	userAgent = "go-capstone-starter/1.0 (food lookup)"
+	// searchPageSize is how many search results are checked for usable nutrition.
+	searchPageSize = 5
+)
+
+// offFields limits API responses to what offRecord reads.
+var offFields = "code,product_name,brands,serving_size,serving_quantity,last_modified_t,nutriments,image_front_small_url"
+
+// OpenFoodFactsProvider looks products up with the Open Food Facts API, by barcode
+// or by full-text search.
+type OpenFoodFactsProvider struct {
+	client  *http.Client
+	baseURL string
+}
+
+// NewOpenFoodFactsProvider uses the public API when baseURL is empty.
+func NewOpenFoodFactsProvider(client *http.Client, baseURL string) *OpenFoodFactsProvider {
+	if baseURL == "" {
+		baseURL = defaultOpenFoodFactsURL
+	}
+	return &OpenFoodFactsProvider{client: client, baseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+func (o *OpenFoodFactsProvider) Name() string { return ProviderOpenFoodFacts }
+
+func (o *OpenFoodFactsProvider) Lookup(ctx context.Context, query string) (Food, error) {
+	var candidates []offJSON
+	if barcode, err := NormalizeBarcode(query); err == nil {
+		var resp struct {
+			Status  int     `json:"status"`
+			Product offJSON `json:"product"`
+		}
+		u := o.baseURL + "/api/v2/product/" + barcode + ".json?fields=" + url.QueryEscape(offFields)
+		if err := o.get(ctx, u, &resp); err != nil {
+			return Food{}, err
+		}
+		if resp.Status == 1 {
+			candidates = append(candidates, resp.Product)
+		}
+	} else {
+		var resp struct {
+			Products []offJSON `json:"products"`
+		}
+		params := url.Values{
+			"search_terms":  {query},
+			"search_simple": {"1"},
+			"action":        {"process"},
+			"json":          {"1"},
+			"page_size":     {fmt.Sprint(searchPageSize)},
+			"fields":        {offFields},
+		}
+		if err := o.get(ctx, o.baseURL+"/cgi/search.pl?"+params.Encode(), &resp); err != nil {
+			return Food{}, err
+		}
+		candidates = resp.Products
+	}
+	for _, c := range candidates {
+		if p, ok := c.record().product(); ok {
+			food := NewFood(p)
+			food.Image = string(c.Image)
+			return food, nil
+		}
+	}
+	return Food{}, ErrNotFound
+}
+
+func (o *OpenFoodFactsProvider) get(ctx context.Context, u string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// The product endpoint answers unknown barcodes with 404 and a status of 0.
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("open food facts returned %s", resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// NutritionixProvider uses the Nutritionix natural language endpoint, which
+// answers for the amount in the query, such as "2 eggs".
+type NutritionixProvider struct {
+	client        *http.Client
+	baseURL       string
+	appID, appKey string
+}
+
+// NewNutritionixProvider uses the public API when baseURL is empty.
+func NewNutritionixProvider(client *http.Client, baseURL, appID, appKey string) *NutritionixProvider {
+	if baseURL == "" {
+		baseURL = defaultNutritionixURL
+	}
+	return &NutritionixProvider{client: client, baseURL: strings.TrimSuffix(baseURL, "/"), appID: appID, appKey: appKey}
+}
+
+func (n *NutritionixProvider) Name() string { return ProviderNutritionix }
+
+type nutritionixFood struct {
+	FoodName           string   `json:"food_name"`
+	BrandName          string   `json:"brand_name"`
+	ServingQty         float64  `json:"serving_qty"`
+	ServingUnit        string   `json:"serving_unit"`
+	ServingWeightGrams *float64 `json:"serving_weight_grams"`
+	Calories           *float64 `json:"nf_calories"`
+	Protein            *float64 `json:"nf_protein"`
+	Carbohydrates      *float64 `json:"nf_total_carbohydrate"`
+	Fat                *float64 `json:"nf_total_fat"`
+	Fiber              *float64 `json:"nf_dietary_fiber"`
+	Sugar              *float64 `json:"nf_sugars"`
+	Photo              struct {
+		Thumb string `json:"thumb"`
+	} `json:"photo"`
+}
+
+func (n *NutritionixProvider) Lookup(ctx context.Context, query string) (Food, error) {
+	body, _ := json.Marshal(map[string]string{"query": query})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.baseURL+"/v2/natural/nutrients", bytes.NewReader(body))
+	if err != nil {
+		return Food{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-app-id", n.appID)
+	req.Header.Set("x-app-key", n.appKey)
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return Food{}, err
+	}
+	defer resp.Body.Close()
+	// Nutritionix answers 404 when it cannot match any food in the query.
+	if resp.StatusCode == http.StatusNotFound {
+		return Food{}, ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		// Quota and key errors carry a short message worth logging.
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return Food{}, fmt.Errorf("nutritionix returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	var parsed struct {
+		Foods []nutritionixFood `json:"foods"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return Food{}, err
+	}
+	if len(parsed.Foods) == 0 {
+		return Food{}, ErrNotFound
+	}
+	return parsed.Foods[0].food()
+}
+
+// food converts the amount Nutritionix answered for into per-100 g values, so it
+// presents like any other product with the queried amount as its serving.
+func (f nutritionixFood) food() (Food, error) {
+	grams := f.ServingWeightGrams
+	if grams == nil || *grams <= 0 {
+		return Food{}, fmt.Errorf("nutritionix gave no serving weight for %q", f.FoodName)
+	}
+	served := Nutrients{Calories: f.Calories, Protein: f.Protein, Carbohydrates: f.Carbohydrates, Fat: f.Fat, Fiber: f.Fiber, Sugar: f.Sugar}
+	p := Product{
+		Name:             f.FoodName,
+		Brand:            f.BrandName,
+		ServingSize:      strings.TrimSpace(fmt.Sprintf("%g %s", f.ServingQty, f.ServingUnit)),
+		ServingQuantityG: grams,
+		// Scaling a serving to 100 g is the inverse of Scale(grams).
+		Per100g: served.Scale(100 * 100 / *grams),
+		Source:  ProviderNutritionix,
+	}
+	food := NewFood(p)
+	// Report the serving exactly as given rather than round-tripped through 100 g.
+	served = served.Scale(100)
+	food.PerServing = &served
+	food.Entry.fill(served)
+	food.Image = f.Photo.Thumb
+	return food, nil
+}
@@ -0,0 +1,149 @@
+package foods
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ProductStore reads and loads products.
+type ProductStore interface {
+	// Product returns sql.ErrNoRows for unknown barcodes.
+	Product(ctx context.Context, barcode string) (Product, error)
+	// Match returns the product that best matches name. Only products whose name
+	// contains name are considered. Among the ones most similar to it, it prefers
+	// an exact name, then a name starting with name, then the shortest. It returns
+	// sql.ErrNoRows when no name is close enough.
+	Match(ctx context.Context, name string) (Product, error)
+	// Upsert inserts products or replaces them by barcode, and returns how many
+	// it wrote; products older than the stored version are left out.
+	Upsert(ctx context.Context, products []Product) (int, error)
+}
+
+type PostgresStore struct {
+	db *sql.DB
+}
+
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// Match limits; see PostgresStore.Match. The threshold is pg_trgm's default.
+const (
+	matchThreshold  = 0.3
+	matchCandidates = 50
+)
+
+const productColumns = `barcode, name, brand, serving_size, serving_quantity_g, calories_100g, protein_100g,
+	carbohydrates_100g, fat_100g, fiber_100g, sugar_100g, source, source_modified_at`
+
+func scanProduct(row *sql.Row) (Product, error) {
+	var p Product
+	var serving, calories, protein, carbohydrates, fat, fiber, sugar sql.NullFloat64
+	var modified sql.NullTime
+	err := row.Scan(&p.Barcode, &p.Name, &p.Brand, &p.ServingSize, &serving, &calories, &protein,
+		&carbohydrates, &fat, &fiber, &sugar, &p.Source, &modified)
+	if err != nil {
+		return Product{}, err
+	}
+	p.ServingQuantityG = floatOrNil(serving)
+	p.Per100g = Nutrients{
+		Calories:      floatOrNil(calories),
+		Protein:       floatOrNil(protein),
+		Carbohydrates: floatOrNil(carbohydrates),
+		Fat:           floatOrNil(fat),
+		Fiber:         floatOrNil(fiber),
+		Sugar:         floatOrNil(sugar),
+	}
+	p.ModifiedAt = modified.Time
+	return p, nil
+}
+
+func (s *PostgresStore) Product(ctx context.Context, barcode string) (Product, error) {
+	return scanProduct(s.db.QueryRowContext(ctx, `SELECT `+productColumns+` FROM products WHERE barcode = $1;`, barcode))
+}
+
+// Match bounds the work for common words: the trigram index finds the names at
+// least matchThreshold similar to name, and only the matchCandidates most similar
+// are ranked. The shortest is usually the plainest product ("Greek Yogurt" over
+// "Greek Yogurt Bar").
+func (s *PostgresStore) Match(ctx context.Context, name string) (Product, error) {
+	name = strings.TrimSpace(name)
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(name)
+
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return Product{}, err
+	}
+	defer tx.Rollback()
+	// The % operator reads its threshold from this setting, for this transaction only.
+	_, err = tx.ExecContext(ctx, `SELECT set_config('pg_trgm.similarity_threshold', $1, true);`,
+		strconv.FormatFloat(matchThreshold, 'f', -1, 64))
+	if err != nil {
+		return Product{}, err
+	}
+	return scanProduct(tx.QueryRowContext(ctx, `
+	SELECT `+productColumns+` FROM (
+		SELECT * FROM products
+		WHERE name % $2 AND name ILIKE $1
+		ORDER BY similarity(name, $2) DESC
+		LIMIT $4
+	) candidates
+	ORDER BY lower(name) = lower($2) DESC, name ILIKE $3 DESC, length(name), barcode
+	LIMIT 1;
+	`, "%"+escaped+"%", name, escaped+"%", matchCandidates))
+}
+
+func floatOrNil(v sql.NullFloat64) *float64 {
+	if !v.Valid {
+		return nil
+	}
+	return &v.Float64
+}
+
+const productParams = 13
+
+// Upsert writes a batch in one statement. A product is only replaced by a version
+// at least as recent, so loading an older dump does not undo a newer one.
+func (s *PostgresStore) Upsert(ctx context.Context, products []Product) (int, error) {
+	if len(products) == 0 {
+		return 0, nil
+	}
+	var values []string
+	args := make([]any, 0, len(products)*productParams)
+	for i, p := range products {
+		placeholders := make([]string, productParams)
+		for j := range placeholders {
+			placeholders[j] = "$" + strconv.Itoa(i*productParams+j+1)
+		}
+		values = append(values, "("+strings.Join(placeholders, ", ")+")")
+		var modified *time.Time
+		if !p.ModifiedAt.IsZero() {
+			modified = &p.ModifiedAt
+		}
+		n := p.Per100g
+		args = append(args, p.Barcode, p.Name, p.Brand, p.ServingSize, p.ServingQuantityG, n.Calories, n.Protein,
+			n.Carbohydrates, n.Fat, n.Fiber, n.Sugar, p.Source, modified)
+	}
+	result, err := s.db.ExecContext(ctx, `
+	INSERT INTO products (barcode, name, brand, serving_size, serving_quantity_g, calories_100g, protein_100g,
+		carbohydrates_100g, fat_100g, fiber_100g, sugar_100g, source, source_modified_at)
+	VALUES `+strings.Join(values, ", ")+`
+	ON CONFLICT (barcode) DO UPDATE SET
+		name = EXCLUDED.name, brand = EXCLUDED.brand, serving_size = EXCLUDED.serving_size,
+		serving_quantity_g = EXCLUDED.serving_quantity_g, calories_100g = EXCLUDED.calories_100g,
+		protein_100g = EXCLUDED.protein_100g, carbohydrates_100g = EXCLUDED.carbohydrates_100g,
+		fat_100g = EXCLUDED.fat_100g, fiber_100g = EXCLUDED.fiber_100g, sugar_100g = EXCLUDED.sugar_100g,
+		source = EXCLUDED.source, source_modified_at = EXCLUDED.source_modified_at, imported_at = NOW()
+	WHERE products.source_modified_at IS NULL OR EXCLUDED.source_modified_at IS NULL
+		OR EXCLUDED.source_modified_at >= products.source_modified_at;
+	`, args...)
+	if err != nil {
+		return 0, err
+	}
+	// Rows the WHERE clause kept as they were are not counted.
+	written, err := result.RowsAffected()
+	return int(written), err
+}
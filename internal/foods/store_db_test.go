@@ -0,0 +1,51 @@
+package foods
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/coloradocollective/go-capstone-starter/internal/dbtest"
+)
+
+func TestPostgresStore_Match(t *testing.T) {
+	db := dbtest.Open(t)
+	store := NewPostgresStore(db)
+	ctx := context.Background()
+	calories := 100.0
+	products := []Product{
+		{Barcode: "2990000000011", Name: "Quibblefruit Yogurt Bar"},
+		{Barcode: "2990000000028", Name: "Quibblefruit Yogurt"},
+		{Barcode: "2990000000035", Name: "Wild quibblefruit yogurt drink with honey"},
+		{Barcode: "2990000000042", Name: "Frozen Quibblefruit"},
+	}
+	var barcodes []string
+	for i := range products {
+		products[i].Per100g.Calories = &calories
+		products[i].Source = SourceOpenFoodFacts
+		barcodes = append(barcodes, products[i].Barcode)
+	}
+	t.Cleanup(func() {
+		for _, barcode := range barcodes {
+			db.Exec(`DELETE FROM products WHERE barcode = $1`, barcode)
+		}
+	})
+	if written, err := store.Upsert(ctx, products); err != nil || written != len(products) {
+		t.Fatalf("expected %d products written, got %d, %v", len(products), written, err)
+	}
+
+	for query, want := range map[string]string{
+		"quibblefruit yogurt": "Quibblefruit Yogurt",
+		"Quibblefruit":        "Quibblefruit Yogurt",
+		"quibblefruit yog":    "Quibblefruit Yogurt",
+	} {
+		p, err := store.Match(ctx, query)
+		if err != nil || p.Name != want {
+			t.Errorf("%q: expected %q, got %q, %v", query, want, p.Name, err)
+		}
+	}
+	if _, err := store.Match(ctx, "quibblefruit sorbet"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected sql.ErrNoRows when no name contains the query, got %v", err)
+	}
+}
@@ -0,0 +1,192 @@
+package goals
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/coloradocollective/go-capstone-starter/internal/auth"
+	"github.com/coloradocollective/go-capstone-starter/internal/foodentries"
+	"github.com/coloradocollective/go-capstone-starter/internal/profile"
+)
+
+// maxCalories rejects targets that can only be typos.
+const maxCalories = 20000
+
+// Profiles reads what goals need from user profiles.
+type Profiles interface {
+	profile.Locations
+	Body(ctx context.Context, userID int) (profile.Body, error)
+}
+
+type handler struct {
+	store    Store
+	profiles Profiles
+}
+
+// Handlers registers the goal endpoints. Dates are days in the user's time zone.
+func Handlers(db *sql.DB, authenticator auth.Authenticator) func(mux *http.ServeMux) {
+	return routes(NewPostgresStore(db), profile.NewPostgresStore(db), authenticator)
+}
+
+func routes(store Store, profiles Profiles, authenticator auth.Authenticator) func(mux *http.ServeMux) {
+	h := &handler{store: store, profiles: profiles}
+	return func(mux *http.ServeMux) {
+		mux.HandleFunc("GET /api/goals", authenticator.Require(h.list))
+		mux.HandleFunc("PUT /api/goals", authenticator.Require(h.save))
+		mux.HandleFunc("DELETE /api/goals/{id}", authenticator.Require(h.delete))
+		mux.HandleFunc("GET /api/goals/progress", authenticator.Require(h.progress))
+		mux.HandleFunc("GET /api/goals/suggestion", authenticator.Require(h.suggest))
+		mux.HandleFunc("POST /api/goals/suggestion", authenticator.Require(h.acceptSuggestion))
+	}
+}
+
+// goalInput is the body of PUT /api/goals. meal_type is omitted for a whole-day
+// goal and effective_from defaults to today.
+type goalInput struct {
+	MealType      string `json:"meal_type"`
+	EffectiveFrom string `json:"effective_from"`
+	Calories      int    `json:"calories"`
+	Protein       *int   `json:"protein"`
+	Carbohydrates *int   `json:"carbohydrates"`
+	Fat           *int   `json:"fat"`
+}
+
+// Validate checks a goal and fills in its effective date, taking today in loc.
+func Validate(g Goal, loc *time.Location) (Goal, error) {
+	if g.MealType != "" && !slices.Contains(foodentries.MealTypes, g.MealType) {
+		return Goal{}, fmt.Errorf("meal_type must be empty or one of %v", foodentries.MealTypes)
+	}
+	if g.EffectiveFrom == "" {
+		g.EffectiveFrom = time.Now().In(loc).Format(time.DateOnly)
+	} else if _, err := time.Parse(time.DateOnly, g.EffectiveFrom); err != nil {
+		return Goal{}, errors.New("effective_from must be a date such as 2024-05-01")
+	}
+	if g.Calories <= 0 || g.Calories > maxCalories {
+		return Goal{}, fmt.Errorf("calories must be between 1 and %d", maxCalories)
+	}
+	for _, macro := range []*int{g.Protein, g.Carbohydrates, g.Fat} {
+		if macro != nil && (*macro < 0 || *macro > maxCalories) {
+			return Goal{}, fmt.Errorf("protein, carbohydrates and fat must be between 0 and %d", maxCalories)
+		}
+	}
+	return g, nil
+}
+
+func (h *handler) location(w http.ResponseWriter, r *http.Request, userID int) (*time.Location, bool) {
+	loc, err := h.profiles.Location(r.Context(), userID)
+	if err != nil {
+		log.Printf("Failed to load time zone for user %d: %v", userID, err)
+		http.Error(w, "Failed to load time zone", http.StatusInternalServerError)
+		return nil, false
+	}
+	return loc, true
+}
+
+func (h *handler) list(w http.ResponseWriter, r *http.Request, userID int) {
+	goals, err := h.store.Goals(r.Context(), userID)
+	if err != nil {
+		log.Printf("Failed to load goals for user %d: %v", userID, err)
+		http.Error(w, "Failed to load goals", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, goals)
+}
+
+func (h *handler) save(w http.ResponseWriter, r *http.Request, userID int) {
+	var in goalInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	loc, ok := h.location(w, r, userID)
+	if !ok {
+		return
+	}
+	goal, err := Validate(Goal{
+		UserID:        userID,
+		MealType:      in.MealType,
+		EffectiveFrom: in.EffectiveFrom,
+		Calories:      in.Calories,
+		Protein:       in.Protein,
+		Carbohydrates: in.Carbohydrates,
+		Fat:           in.Fat,
+	}, loc)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	saved, err := h.store.Save(r.Context(), goal)
+	if err != nil {
+		log.Printf("Failed to save goal for user %d: %v", userID, err)
+		http.Error(w, "Failed to save goal", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, saved)
+}
+
+func (h *handler) delete(w http.ResponseWriter, r *http.Request, userID int) {
+	goalID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid goal id", http.StatusBadRequest)
+		return
+	}
+	err = h.store.Delete(r.Context(), userID, goalID)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Goal not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to delete goal %d: %v", goalID, err)
+		http.Error(w, "Failed to delete goal", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// progress serves GET /api/goals/progress?date=YYYY-MM-DD, defaulting to today.
+func (h *handler) progress(w http.ResponseWriter, r *http.Request, userID int) {
+	loc, ok := h.location(w, r, userID)
+	if !ok {
+		return
+	}
+	day := time.Now().In(loc)
+	if raw := r.URL.Query().Get("date"); raw != "" {
+		var err error
+		if day, err = time.ParseInLocation(time.DateOnly, raw, loc); err != nil {
+			http.Error(w, "date must be a date such as 2024-05-01", http.StatusBadRequest)
+			return
+		}
+	}
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, loc)
+	date := start.Format(time.DateOnly)
+
+	active, err := h.store.Active(r.Context(), userID, date)
+	if err != nil {
+		log.Printf("Failed to load goals for user %d: %v", userID, err)
+		http.Error(w, "Failed to load goals", http.StatusInternalServerError)
+		return
+	}
+	consumed, err := h.store.Consumed(r.Context(), userID, start, start.AddDate(0, 0, 1))
+	if err != nil {
+		log.Printf("Failed to sum food entries for user %d: %v", userID, err)
+		http.Error(w, "Failed to load progress", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, progress(date, loc.String(), active, consumed))
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
@@ -0,0 +1,184 @@
+package goals
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/coloradocollective/go-capstone-starter/internal/auth/authtest"
+	"github.com/coloradocollective/go-capstone-starter/internal/foodentries"
+	"github.com/coloradocollective/go-capstone-starter/internal/profile"
+)
+
+type memoryStore struct {
+	goals   []Goal
+	entries []foodentries.Entry
+	nextID  int
+}
+
+func (s *memoryStore) Goals(_ context.Context, userID int) ([]Goal, error) {
+	var goals []Goal
+	for _, g := range s.goals {
+		if g.UserID == userID {
+			goals = append(goals, g)
+		}
+	}
+	return goals, nil
+}
+
+func (s *memoryStore) Save(_ context.Context, goal Goal) (Goal, error) {
+	for i, g := range s.goals {
+		if g.UserID == goal.UserID && g.MealType == goal.MealType && g.EffectiveFrom == goal.EffectiveFrom {
+			goal.ID = g.ID
+			s.goals[i] = goal
+			return goal, nil
+		}
+	}
+	s.nextID++
+	goal.ID = s.nextID
+	s.goals = append(s.goals, goal)
+	return goal, nil
+}
+
+func (s *memoryStore) Delete(_ context.Context, userID, goalID int) error {
+	for i, g := range s.goals {
+		if g.ID == goalID && g.UserID == userID {
+			s.goals = append(s.goals[:i], s.goals[i+1:]...)
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func (s *memoryStore) Active(_ context.Context, userID int, date string) ([]Goal, error) {
+	latest := map[string]Goal{}
+	for _, g := range s.goals {
+		if g.UserID == userID && g.EffectiveFrom <= date && g.EffectiveFrom > latest[g.MealType].EffectiveFrom {
+			latest[g.MealType] = g
+		}
+	}
+	var active []Goal
+	for _, g := range latest {
+		active = append(active, g)
+	}
+	sort.Slice(active, func(i, j int) bool { return active[i].MealType < active[j].MealType })
+	return active, nil
+}
+
+func (s *memoryStore) Consumed(_ context.Context, userID int, start, end time.Time) (map[string]foodentries.NutrientTotals, error) {
+	consumed := map[string]foodentries.NutrientTotals{}
+	for _, e := range s.entries {
+		if e.UserID != userID || e.ConsumedAt.Before(start) || !e.ConsumedAt.Before(end) {
+			continue
+		}
+		consumed[e.MealType] = add(consumed[e.MealType], foodentries.NutrientTotals{
+			Calories: e.Calories, Protein: e.Protein, Carbohydrates: e.Carbohydrates, Fat: e.Fat,
+		})
+	}
+	return consumed, nil
+}
+
+// profiles puts everyone in UTC with the same body data.
+type profiles struct {
+	body profile.Body
+}
+
+func (p profiles) Location(context.Context, int) (*time.Location, error) { return time.UTC, nil }
+
+func (p profiles) Body(context.Context, int) (profile.Body, error) { return p.body, nil }
+
+func newServer(store Store) *http.ServeMux {
+	return newProfiledServer(store, profiles{})
+}
+
+func newProfiledServer(store Store, p profiles) *http.ServeMux {
+	mux := http.NewServeMux()
+	routes(store, p, authtest.Auth)(mux)
+	return mux
+}
+
+func TestSave(t *testing.T) {
+	store := &memoryStore{}
+	mux := newServer(store)
+
+	rec := authtest.Request(t, mux, http.MethodPut, "/api/goals", 4, `{"effective_from": "2024-05-01", "calories": 2000, "protein": 120}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	authtest.Request(t, mux, http.MethodPut, "/api/goals", 4, `{"effective_from": "2024-05-01", "calories": 2200}`)
+	if len(store.goals) != 1 || store.goals[0].Calories != 2200 || store.goals[0].Protein != nil {
+		t.Errorf("expected the goal for the same date to be replaced, got %+v", store.goals)
+	}
+
+	authtest.Request(t, mux, http.MethodPut, "/api/goals", 4, `{"calories": 1800}`)
+	if today := time.Now().UTC().Format(time.DateOnly); store.goals[1].EffectiveFrom != today {
+		t.Errorf("expected the goal to take effect today, got %s", store.goals[1].EffectiveFrom)
+	}
+
+	invalid := map[string]string{
+		"meal type": `{"meal_type": "brunch", "calories": 500}`,
+		"date":      `{"effective_from": "May 1", "calories": 500}`,
+		"calories":  `{"calories": 0}`,
+		"macro":     `{"calories": 500, "fat": -1}`,
+		"not json":  `{`,
+	}
+	for name, body := range invalid {
+		if rec := authtest.Request(t, mux, http.MethodPut, "/api/goals", 4, body); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", name, rec.Code)
+		}
+	}
+	if rec := authtest.Request(t, mux, http.MethodDelete, "/api/goals/1", 5, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 deleting another user's goal, got %d", rec.Code)
+	}
+}
+
+func TestProgress(t *testing.T) {
+	protein := 100
+	store := &memoryStore{
+		goals: []Goal{
+			{ID: 1, UserID: 4, EffectiveFrom: "2024-04-01", Calories: 2500},
+			{ID: 2, UserID: 4, EffectiveFrom: "2024-05-01", Calories: 2000, Protein: &protein},
+			{ID: 3, UserID: 4, EffectiveFrom: "2024-06-01", Calories: 1800},
+			{ID: 4, UserID: 4, MealType: "breakfast", EffectiveFrom: "2024-01-01", Calories: 400},
+		},
+		entries: []foodentries.Entry{
+			{UserID: 4, MealType: "breakfast", Calories: 500, Protein: 20, ConsumedAt: time.Date(2024, 5, 10, 8, 0, 0, 0, time.UTC)},
+			{UserID: 4, MealType: "dinner", Calories: 1000, Protein: 60, ConsumedAt: time.Date(2024, 5, 10, 19, 0, 0, 0, time.UTC)},
+			{UserID: 4, MealType: "dinner", Calories: 900, ConsumedAt: time.Date(2024, 5, 11, 19, 0, 0, 0, time.UTC)},
+			{UserID: 5, MealType: "dinner", Calories: 900, ConsumedAt: time.Date(2024, 5, 10, 19, 0, 0, 0, time.UTC)},
+		},
+	}
+	rec := authtest.Request(t, newServer(store), http.MethodGet, "/api/goals/progress?date=2024-05-10", 4, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var p Progress
+	if err := json.NewDecoder(rec.Body).Decode(&p); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+
+	if p.Daily == nil || p.Daily.Goal.ID != 2 {
+		t.Fatalf("expected the goal effective from May 1, got %+v", p.Daily)
+	}
+	want := Target{Target: 2000, Consumed: 1500, Remaining: 500, Percent: 75}
+	if got := p.Daily.Targets["calories"]; got != want {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+	if got := p.Daily.Targets["protein"]; got.Consumed != 80 || got.Remaining != 20 {
+		t.Errorf("unexpected protein progress %+v", got)
+	}
+	if _, ok := p.Daily.Targets["fat"]; ok {
+		t.Error("expected no fat target")
+	}
+	breakfast := p.Meals["breakfast"].Targets["calories"]
+	if breakfast.Remaining != -100 || breakfast.Percent != 125 {
+		t.Errorf("expected breakfast to be over its goal, got %+v", breakfast)
+	}
+	if _, ok := p.Meals["dinner"]; ok {
+		t.Error("expected no progress for meals without a goal")
+	}
+}
@@ -0,0 +1,85 @@
+package goals
+
+import (
+	"math"
+
+	"github.com/coloradocollective/go-capstone-starter/internal/foodentries"
+)
+
+// Target compares one nutrient with its goal. Remaining is negative once the goal
+// is exceeded.
+type Target struct {
+	Target    int     `json:"target"`
+	Consumed  int     `json:"consumed"`
+	Remaining int     `json:"remaining"`
+	Percent   float64 `json:"percent"`
+}
+
+// GoalProgress is the progress towards one goal.
+type GoalProgress struct {
+	Goal     Goal                       `json:"goal"`
+	Consumed foodentries.NutrientTotals `json:"consumed"`
+	Targets  map[string]Target          `json:"targets"`
+}
+
+// Progress is the response of the progress endpoint. Daily is nil when the user
+// has no whole-day goal in effect; Meals holds the meal types that have one.
+type Progress struct {
+	Date     string                     `json:"date"`
+	TimeZone string                     `json:"time_zone"`
+	Consumed foodentries.NutrientTotals `json:"consumed"`
+	Daily    *GoalProgress              `json:"daily"`
+	Meals    map[string]GoalProgress    `json:"meals"`
+}
+
+func target(goal, consumed int) Target {
+	t := Target{Target: goal, Consumed: consumed, Remaining: goal - consumed}
+	if goal > 0 {
+		t.Percent = math.Round(float64(consumed)/float64(goal)*1000) / 10
+	}
+	return t
+}
+
+func goalProgress(g Goal, consumed foodentries.NutrientTotals) GoalProgress {
+	p := GoalProgress{Goal: g, Consumed: consumed, Targets: map[string]Target{
+		"calories": target(g.Calories, consumed.Calories),
+	}}
+	if g.Protein != nil {
+		p.Targets["protein"] = target(*g.Protein, consumed.Protein)
+	}
+	if g.Carbohydrates != nil {
+		p.Targets["carbohydrates"] = target(*g.Carbohydrates, consumed.Carbohydrates)
+	}
+	if g.Fat != nil {
+		p.Targets["fat"] = target(*g.Fat, consumed.Fat)
+	}
+	return p
+}
+
+func add(a, b foodentries.NutrientTotals) foodentries.NutrientTotals {
+	return foodentries.NutrientTotals{
+		Calories:      a.Calories + b.Calories,
+		Protein:       a.Protein + b.Protein,
+		Carbohydrates: a.Carbohydrates + b.Carbohydrates,
+		Fat:           a.Fat + b.Fat,
+		Fiber:         a.Fiber + b.Fiber,
+		Sugar:         a.Sugar + b.Sugar,
+	}
+}
+
+// progress compares a day's entries, summed by meal type, with the goals active that day.
+func progress(date, timeZone string, active []Goal, consumed map[string]foodentries.NutrientTotals) Progress {
+	p := Progress{Date: date, TimeZone: timeZone, Meals: map[string]GoalProgress{}}
+	for _, totals := range consumed {
+		p.Consumed = add(p.Consumed, totals)
+	}
+	for _, g := range active {
+		if g.MealType == "" {
+			daily := goalProgress(g, p.Consumed)
+			p.Daily = &daily
+			continue
+		}
+		p.Meals[g.MealType] = goalProgress(g, consumed[g.MealType])
+	}
+	return p
+}
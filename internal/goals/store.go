@@ -0,0 +1,144 @@
+package goals
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/coloradocollective/go-capstone-starter/internal/foodentries"
+)
+
+// Goal is one row of nutrition_goals. MealType is empty for a whole-day goal, and
+// nil macros have no target.
+type Goal struct {
+	ID            int    `json:"id"`
+	UserID        int    `json:"user_id"`
+	MealType      string `json:"meal_type,omitempty"`
+	EffectiveFrom string `json:"effective_from"`
+	Calories      int    `json:"calories"`
+	Protein       *int   `json:"protein,omitempty"`
+	Carbohydrates *int   `json:"carbohydrates,omitempty"`
+	Fat           *int   `json:"fat,omitempty"`
+}
+
+// Store persists goals and sums the food entries they are compared with.
+type Store interface {
+	Goals(ctx context.Context, userID int) ([]Goal, error)
+	// Save creates a goal, or replaces the one with the same meal type and date.
+	Save(ctx context.Context, goal Goal) (Goal, error)
+	Delete(ctx context.Context, userID, goalID int) error
+	// Active returns the goals in effect on date, at most one per meal type.
+	Active(ctx context.Context, userID int, date string) ([]Goal, error)
+	// Consumed sums the user's entries in [start, end) by meal type.
+	Consumed(ctx context.Context, userID int, start, end time.Time) (map[string]foodentries.NutrientTotals, error)
+}
+
+type PostgresStore struct {
+	db *sql.DB
+}
+
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+const goalColumns = `id, user_id, meal_type, to_char(effective_from, 'YYYY-MM-DD'), calories, protein, carbohydrates, fat`
+
+func scanGoal(row interface{ Scan(...any) error }) (Goal, error) {
+	var g Goal
+	var protein, carbohydrates, fat sql.NullInt64
+	err := row.Scan(&g.ID, &g.UserID, &g.MealType, &g.EffectiveFrom, &g.Calories, &protein, &carbohydrates, &fat)
+	g.Protein, g.Carbohydrates, g.Fat = intOrNil(protein), intOrNil(carbohydrates), intOrNil(fat)
+	return g, err
+}
+
+func intOrNil(v sql.NullInt64) *int {
+	if !v.Valid {
+		return nil
+	}
+	n := int(v.Int64)
+	return &n
+}
+
+func scanGoals(rows *sql.Rows, err error) ([]Goal, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	goals := []Goal{}
+	for rows.Next() {
+		g, err := scanGoal(rows)
+		if err != nil {
+			return nil, err
+		}
+		goals = append(goals, g)
+	}
+	return goals, rows.Err()
+}
+
+func (s *PostgresStore) Goals(ctx context.Context, userID int) ([]Goal, error) {
+	return scanGoals(s.db.QueryContext(ctx, `
+	SELECT `+goalColumns+` FROM nutrition_goals
+	WHERE user_id = $1
+	ORDER BY effective_from DESC, meal_type;
+	`, userID))
+}
+
+func (s *PostgresStore) Save(ctx context.Context, g Goal) (Goal, error) {
+	return scanGoal(s.db.QueryRowContext(ctx, `
+	INSERT INTO nutrition_goals (user_id, meal_type, effective_from, calories, protein, carbohydrates, fat)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	ON CONFLICT (user_id, meal_type, effective_from) DO UPDATE SET
+		calories = EXCLUDED.calories, protein = EXCLUDED.protein, carbohydrates = EXCLUDED.carbohydrates,
+		fat = EXCLUDED.fat, updated_at = NOW()
+	RETURNING `+goalColumns+`;
+	`, g.UserID, g.MealType, g.EffectiveFrom, g.Calories, g.Protein, g.Carbohydrates, g.Fat))
+}
+
+// Delete removes a goal owned by userID; it returns sql.ErrNoRows for other users' goals.
+func (s *PostgresStore) Delete(ctx context.Context, userID, goalID int) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM nutrition_goals WHERE id = $1 AND user_id = $2;`, goalID, userID)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (s *PostgresStore) Active(ctx context.Context, userID int, date string) ([]Goal, error) {
+	return scanGoals(s.db.QueryContext(ctx, `
+	SELECT DISTINCT ON (meal_type) `+goalColumns+` FROM nutrition_goals
+	WHERE user_id = $1 AND effective_from <= $2
+	ORDER BY meal_type, effective_from DESC;
+	`, userID, date))
+}
+
+func (s *PostgresStore) Consumed(ctx context.Context, userID int, start, end time.Time) (map[string]foodentries.NutrientTotals, error) {
+	rows, err := s.db.QueryContext(ctx, `
+	SELECT meal_type, SUM(calories), SUM(protein), SUM(carbohydrates), SUM(fat), SUM(fiber), SUM(sugar)
+	FROM food_entries
+	WHERE user_id = $1 AND consumed_at >= $2 AND consumed_at < $3
+	GROUP BY meal_type;
+	`, userID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	consumed := map[string]foodentries.NutrientTotals{}
+	for rows.Next() {
+		var mealType string
+		var t foodentries.NutrientTotals
+		if err := rows.Scan(&mealType, &t.Calories, &t.Protein, &t.Carbohydrates, &t.Fat, &t.Fiber, &t.Sugar); err != nil {
+			return nil, err
+		}
+		consumed[mealType] = t
+	}
+	return consumed, rows.Err()
+}
@@ -0,0 +1,70 @@
+package goals
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/coloradocollective/go-capstone-starter/internal/dbtest"
+)
+
+func TestPostgresStore_Goals(t *testing.T) {
+	db := dbtest.Open(t)
+	userID := dbtest.UserID(t, db, "food_entries")
+	store := NewPostgresStore(db)
+	ctx := context.Background()
+	protein := 120
+
+	for _, g := range []Goal{
+		{UserID: userID, EffectiveFrom: "2024-04-01", Calories: 2200},
+		{UserID: userID, EffectiveFrom: "2024-05-01", Calories: 2000, Protein: &protein},
+		{UserID: userID, EffectiveFrom: "2024-05-01", Calories: 1900, Protein: &protein},
+		{UserID: userID, MealType: "breakfast", EffectiveFrom: "2024-06-01", Calories: 500},
+	} {
+		if _, err := store.Save(ctx, g); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	all, err := store.Goals(ctx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 3 {
+		t.Fatalf("expected the second save for 2024-05-01 to replace the first, got %+v", all)
+	}
+
+	active, err := store.Active(ctx, userID, "2024-05-15")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(active) != 1 || active[0].Calories != 1900 || active[0].Protein == nil || *active[0].Protein != 120 || active[0].Fat != nil {
+		t.Fatalf("expected the 2024-05-01 day goal, got %+v", active)
+	}
+
+	for _, e := range []struct {
+		meal     string
+		calories int
+		at       time.Time
+	}{
+		{"breakfast", 300, time.Date(2024, 5, 15, 8, 0, 0, 0, time.UTC)},
+		{"breakfast", 200, time.Date(2024, 5, 15, 9, 0, 0, 0, time.UTC)},
+		{"dinner", 700, time.Date(2024, 5, 15, 19, 0, 0, 0, time.UTC)},
+		{"dinner", 900, time.Date(2024, 5, 16, 19, 0, 0, 0, time.UTC)},
+	} {
+		_, err := db.Exec(`
+		INSERT INTO food_entries (user_id, name, portion, unit, calories, protein, carbohydrates, fat, fiber, sugar, meal_type, consumed_at)
+		VALUES ($1, 'meal', 1, 'serving', $2, 10, 0, 0, 0, 0, $3, $4);
+		`, userID, e.calories, e.meal, e.at)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	day := time.Date(2024, 5, 15, 0, 0, 0, 0, time.UTC)
+	consumed, err := store.Consumed(ctx, userID, day, day.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(consumed) != 2 || consumed["breakfast"].Calories != 500 || consumed["breakfast"].Protein != 20 || consumed["dinner"].Calories != 700 {
+		t.Fatalf("unexpected totals %+v", consumed)
+	}
+}
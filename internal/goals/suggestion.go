@@ -0,0 +1,112 @@
+package goals
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/coloradocollective/go-capstone-starter/internal/energy"
+	"github.com/coloradocollective/go-capstone-starter/internal/profile"
+)
+
+// suggestions computes the suggestion for each objective, or only the one given.
+// ok is false once an error has been written.
+func (h *handler) suggestions(w http.ResponseWriter, r *http.Request, userID int, objective string) ([]energy.Suggestion, *time.Location, bool) {
+	loc, ok := h.location(w, r, userID)
+	if !ok {
+		return nil, nil, false
+	}
+	body, err := h.profiles.Body(r.Context(), userID)
+	if err != nil {
+		log.Printf("Failed to load profile for user %d: %v", userID, err)
+		http.Error(w, "Failed to load profile", http.StatusInternalServerError)
+		return nil, nil, false
+	}
+	person, err := body.Person(time.Now().In(loc))
+	if errors.Is(err, profile.ErrIncompleteBody) {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return nil, nil, false
+	}
+	if err != nil {
+		log.Printf("Invalid profile for user %d: %v", userID, err)
+		http.Error(w, "Failed to load profile", http.StatusInternalServerError)
+		return nil, nil, false
+	}
+
+	objectives := []string{energy.Lose, energy.Maintain, energy.Gain}
+	if objective != "" {
+		objectives = []string{objective}
+	}
+	var suggestions []energy.Suggestion
+	for _, o := range objectives {
+		s, err := energy.Suggest(person, o)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return nil, nil, false
+		}
+		suggestions = append(suggestions, s)
+	}
+	return suggestions, loc, true
+}
+
+// suggest serves GET /api/goals/suggestion[?objective=lose|maintain|gain]. It answers
+// 422 while the profile lacks the body data the estimate needs.
+func (h *handler) suggest(w http.ResponseWriter, r *http.Request, userID int) {
+	suggestions, _, ok := h.suggestions(w, r, userID, r.URL.Query().Get("objective"))
+	if !ok {
+		return
+	}
+	writeJSON(w, suggestions)
+}
+
+type acceptInput struct {
+	Objective     string `json:"objective"`
+	EffectiveFrom string `json:"effective_from"`
+}
+
+// acceptedSuggestion is the response of accepting a suggestion.
+type acceptedSuggestion struct {
+	Goal       Goal              `json:"goal"`
+	Suggestion energy.Suggestion `json:"suggestion"`
+}
+
+// acceptSuggestion serves POST /api/goals/suggestion, saving the suggestion for an
+// objective as the user's whole-day goal.
+func (h *handler) acceptSuggestion(w http.ResponseWriter, r *http.Request, userID int) {
+	var in acceptInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if in.Objective == "" {
+		http.Error(w, energy.ErrUnknownObjective.Error(), http.StatusBadRequest)
+		return
+	}
+	suggestions, loc, ok := h.suggestions(w, r, userID, in.Objective)
+	if !ok {
+		return
+	}
+	s := suggestions[0]
+	goal, err := Validate(Goal{
+		UserID:        userID,
+		EffectiveFrom: in.EffectiveFrom,
+		Calories:      s.Calories,
+		Protein:       &s.Protein,
+		Carbohydrates: &s.Carbohydrates,
+		Fat:           &s.Fat,
+	}, loc)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	saved, err := h.store.Save(r.Context(), goal)
+	if err != nil {
+		log.Printf("Failed to save goal for user %d: %v", userID, err)
+		http.Error(w, "Failed to save goal", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, acceptedSuggestion{Goal: saved, Suggestion: s})
+}
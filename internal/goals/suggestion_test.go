@@ -0,0 +1,51 @@
+package goals
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/coloradocollective/go-capstone-starter/internal/energy"
+	"github.com/coloradocollective/go-capstone-starter/internal/profile"
+
+	"github.com/coloradocollective/go-capstone-starter/internal/auth/authtest"
+)
+
+func TestSuggestion(t *testing.T) {
+	height, weight := 180.0, 80.0
+	body := profile.Body{Sex: "male", BirthDate: "1980-01-01", HeightCm: &height, WeightKg: &weight, ActivityLevel: "moderate"}
+	store := &memoryStore{}
+	mux := newProfiledServer(store, profiles{body: body})
+
+	rec := authtest.Request(t, mux, http.MethodGet, "/api/goals/suggestion", 4, "")
+	var suggestions []energy.Suggestion
+	if err := json.NewDecoder(rec.Body).Decode(&suggestions); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if len(suggestions) != 3 || suggestions[0].Objective != energy.Lose || suggestions[0].Calories >= suggestions[2].Calories {
+		t.Fatalf("expected lose, maintain and gain suggestions, got %+v", suggestions)
+	}
+
+	rec = authtest.Request(t, mux, http.MethodPost, "/api/goals/suggestion", 4, `{"objective": "lose", "effective_from": "2024-06-01"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if len(store.goals) != 1 {
+		t.Fatalf("expected the suggestion to be saved as a goal")
+	}
+	g := store.goals[0]
+	if g.MealType != "" || g.EffectiveFrom != "2024-06-01" || g.Calories != suggestions[0].Calories || g.Protein == nil || *g.Protein != 160 {
+		t.Errorf("unexpected goal %+v", g)
+	}
+
+	if rec := authtest.Request(t, mux, http.MethodPost, "/api/goals/suggestion", 4, `{"objective": "bulk"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown objective, got %d", rec.Code)
+	}
+}
+
+func TestSuggestion_IncompleteProfile(t *testing.T) {
+	mux := newServer(&memoryStore{})
+	if rec := authtest.Request(t, mux, http.MethodGet, "/api/goals/suggestion", 4, ""); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected 422 without body data, got %d", rec.Code)
+	}
+}
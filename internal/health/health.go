@@ -0,0 +1,86 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// Check probes one dependency.
+type Check func(ctx context.Context) error
+
+// Result is the outcome of one check. The endpoints are unauthenticated, so
+// failures are logged rather than returned.
+type Result struct {
+	Status string `json:"status"`
+}
+
+// Report is the body of the health endpoints.
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+const (
+	StatusOK   = "ok"
+	StatusDown = "down"
+)
+
+// checkTimeout keeps a hanging dependency from hanging the health check.
+const checkTimeout = 2 * time.Second
+
+// Handlers registers GET /api/health, which runs every check, and
+// GET /api/health/{name} for a single one. Both answer 503 when a check fails.
+func Handlers(checks map[string]Check) func(mux *http.ServeMux) {
+	return func(mux *http.ServeMux) {
+		mux.HandleFunc("GET /api/health", func(w http.ResponseWriter, r *http.Request) {
+			writeReport(w, run(r.Context(), checks))
+		})
+		mux.HandleFunc("GET /api/health/{name}", func(w http.ResponseWriter, r *http.Request) {
+			name := r.PathValue("name")
+			check, ok := checks[name]
+			if !ok {
+				http.Error(w, "Unknown health check", http.StatusNotFound)
+				return
+			}
+			writeReport(w, run(r.Context(), map[string]Check{name: check}))
+		})
+	}
+}
+
+func run(ctx context.Context, checks map[string]Check) Report {
+	report := Report{Status: StatusOK, Checks: make(map[string]Result, len(checks))}
+	names := make([]string, 0, len(checks))
+	for name := range checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		checkCtx, cancel := context.WithTimeout(ctx, checkTimeout)
+		err := checks[name](checkCtx)
+		cancel()
+
+		result := Result{Status: StatusOK}
+		if err != nil {
+			log.Printf("Health check %s failed: %v", name, err)
+			result.Status = StatusDown
+			report.Status = StatusDown
+		}
+		report.Checks[name] = result
+	}
+	return report
+}
+
+func writeReport(w http.ResponseWriter, report Report) {
+	w.Header().Set("Content-Type", "application/json")
+	if report.Status != StatusOK {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
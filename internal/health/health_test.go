@@ -0,0 +1,48 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func serve(checks map[string]Check, path string) (*httptest.ResponseRecorder, Report) {
+	mux := http.NewServeMux()
+	Handlers(checks)(mux)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	var report Report
+	_ = json.Unmarshal(rec.Body.Bytes(), &report)
+	return rec, report
+}
+
+func TestHealth(t *testing.T) {
+	checks := map[string]Check{
+		"database": func(context.Context) error { return nil },
+		"cache":    func(context.Context) error { return errors.New("dial tcp 10.0.0.7:6379: connection refused") },
+	}
+
+	rec, report := serve(checks, "/api/health")
+	if rec.Code != http.StatusServiceUnavailable || report.Status != StatusDown {
+		t.Fatalf("expected 503 down, got %d %+v", rec.Code, report)
+	}
+	if report.Checks["cache"].Status != StatusDown {
+		t.Fatalf("unexpected cache result: %+v", report.Checks["cache"])
+	}
+	if strings.Contains(rec.Body.String(), "10.0.0.7") {
+		t.Fatalf("expected the error to stay out of the response, got %s", rec.Body.String())
+	}
+
+	rec, report = serve(checks, "/api/health/database")
+	if rec.Code != http.StatusOK || report.Status != StatusOK || len(report.Checks) != 1 {
+		t.Fatalf("expected only the healthy database check, got %d %+v", rec.Code, report)
+	}
+
+	if rec, _ := serve(checks, "/api/health/queue"); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown check, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,229 @@
+package measurements
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/coloradocollective/go-capstone-starter/internal/auth"
+	"github.com/coloradocollective/go-capstone-starter/internal/profile"
+)
+
+// Trend windows in days.
+const (
+	DefaultTrendDays = 28
+	MaxTrendDays     = 365
+)
+
+// warmupDays of earlier weigh-ins settle the trend before the window starts.
+const warmupDays = 60
+
+// defaultListDays is the span listed when no dates are given.
+const defaultListDays = 90
+
+type handler struct {
+	store     Store
+	locations profile.Locations
+}
+
+// Handlers registers the measurement endpoints. Dates are days in the user's time zone.
+func Handlers(db *sql.DB, authenticator auth.Authenticator) func(mux *http.ServeMux) {
+	return routes(NewPostgresStore(db), profile.NewPostgresStore(db), authenticator)
+}
+
+func routes(store Store, locations profile.Locations, authenticator auth.Authenticator) func(mux *http.ServeMux) {
+	h := &handler{store: store, locations: locations}
+	return func(mux *http.ServeMux) {
+		mux.HandleFunc("GET /api/measurements", authenticator.Require(h.list))
+		mux.HandleFunc("PUT /api/measurements", authenticator.Require(h.save))
+		mux.HandleFunc("DELETE /api/measurements/{id}", authenticator.Require(h.delete))
+		mux.HandleFunc("GET /api/measurements/trend", authenticator.Require(h.trend))
+	}
+}
+
+func (h *handler) today(w http.ResponseWriter, r *http.Request, userID int) (time.Time, *time.Location, bool) {
+	loc, err := h.locations.Location(r.Context(), userID)
+	if err != nil {
+		log.Printf("Failed to load time zone for user %d: %v", userID, err)
+		http.Error(w, "Failed to load time zone", http.StatusInternalServerError)
+		return time.Time{}, nil, false
+	}
+	now := time.Now().In(loc)
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc), loc, true
+}
+
+// validate checks a measurement against plausible human ranges.
+func validate(m Measurement, today time.Time) error {
+	day, err := time.ParseInLocation(time.DateOnly, m.MeasuredOn, today.Location())
+	if err != nil {
+		return errors.New("measured_on must be a date such as 2024-05-01")
+	}
+	if day.After(today) {
+		return errors.New("measured_on must not be in the future")
+	}
+	if !m.sets() && !slices.ContainsFunc(m.Clear, func(name string) bool { return name != "note" }) {
+		return errors.New("at least one measurement is required")
+	}
+	if m.WeightKg != nil && (*m.WeightKg < 20 || *m.WeightKg > 500) {
+		return errors.New("weight_kg must be between 20 and 500")
+	}
+	if m.BodyFatPercent != nil && (*m.BodyFatPercent < 2 || *m.BodyFatPercent > 70) {
+		return errors.New("body_fat_percent must be between 2 and 70")
+	}
+	for _, c := range []*float64{m.WaistCm, m.HipsCm, m.ChestCm, m.NeckCm} {
+		if c != nil && (*c < 10 || *c > 300) {
+			return errors.New("circumferences must be between 10 and 300 cm")
+		}
+	}
+	if len(m.Note) > 500 {
+		return errors.New("note must be at most 500 characters")
+	}
+	return nil
+}
+
+// list serves GET /api/measurements?from=&to=, defaulting to the last 90 days.
+func (h *handler) list(w http.ResponseWriter, r *http.Request, userID int) {
+	today, _, ok := h.today(w, r, userID)
+	if !ok {
+		return
+	}
+	from, to := r.URL.Query().Get("from"), r.URL.Query().Get("to")
+	if to == "" {
+		to = today.Format(time.DateOnly)
+	}
+	if from == "" {
+		from = today.AddDate(0, 0, 1-defaultListDays).Format(time.DateOnly)
+	}
+	for _, d := range []string{from, to} {
+		if _, err := time.Parse(time.DateOnly, d); err != nil {
+			http.Error(w, "from and to must be dates such as 2024-05-01", http.StatusBadRequest)
+			return
+		}
+	}
+
+	measurements, err := h.store.Measurements(r.Context(), userID, from, to)
+	if err != nil {
+		log.Printf("Failed to load measurements for user %d: %v", userID, err)
+		http.Error(w, "Failed to load measurements", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, measurements)
+}
+
+// save serves PUT /api/measurements, adding the measures sent to those already
+// saved for the same day. A measure or note sent as null is cleared, so a
+// mistyped measure can be removed without deleting the day. measured_on
+// defaults to today.
+func (h *handler) save(w http.ResponseWriter, r *http.Request, userID int) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	var m Measurement
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(body, &m) != nil || json.Unmarshal(body, &fields) != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	// A measure sent as null is cleared; one left out keeps its saved value.
+	for _, name := range clearable {
+		if raw, ok := fields[name]; ok && string(raw) == "null" {
+			m.Clear = append(m.Clear, name)
+		}
+	}
+	today, _, ok := h.today(w, r, userID)
+	if !ok {
+		return
+	}
+	m.ID, m.UserID = 0, userID
+	if m.MeasuredOn == "" {
+		m.MeasuredOn = today.Format(time.DateOnly)
+	}
+	if err := validate(m, today); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	saved, err := h.store.Save(r.Context(), m)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "No measurements to clear on "+m.MeasuredOn, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to save measurements for user %d: %v", userID, err)
+		http.Error(w, "Failed to save measurements", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, saved)
+}
+
+func (h *handler) delete(w http.ResponseWriter, r *http.Request, userID int) {
+	measurementID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid measurement id", http.StatusBadRequest)
+		return
+	}
+	err = h.store.Delete(r.Context(), userID, measurementID)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Measurement not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to delete measurement %d: %v", measurementID, err)
+		http.Error(w, "Failed to delete measurement", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// trend serves GET /api/measurements/trend?days=N, analysing the last N days
+// including today.
+func (h *handler) trend(w http.ResponseWriter, r *http.Request, userID int) {
+	today, loc, ok := h.today(w, r, userID)
+	if !ok {
+		return
+	}
+	window := DefaultTrendDays
+	if raw := r.URL.Query().Get("days"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < minTrendSpanDays || n > MaxTrendDays {
+			http.Error(w, fmt.Sprintf("days must be between %d and %d", minTrendSpanDays, MaxTrendDays), http.StatusBadRequest)
+			return
+		}
+		window = n
+	}
+	start := today.AddDate(0, 0, 1-window)
+	end := today.AddDate(0, 0, 1)
+	from, to := start.Format(time.DateOnly), today.Format(time.DateOnly)
+
+	measurements, err := h.store.Measurements(r.Context(), userID, start.AddDate(0, 0, -warmupDays).Format(time.DateOnly), to)
+	if err != nil {
+		log.Printf("Failed to load measurements for user %d: %v", userID, err)
+		http.Error(w, "Failed to load measurements", http.StatusInternalServerError)
+		return
+	}
+	calories, err := h.store.DailyCalories(r.Context(), userID, start, end, loc)
+	if err != nil {
+		log.Printf("Failed to sum food entries for user %d: %v", userID, err)
+		http.Error(w, "Failed to load intake", http.StatusInternalServerError)
+		return
+	}
+	a := analyze(from, to, measurements, calories)
+	a.TimeZone = loc.String()
+	writeJSON(w, a)
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
@@ -0,0 +1,166 @@
+package measurements
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/coloradocollective/go-capstone-starter/internal/auth/authtest"
+)
+
+type memoryStore struct {
+	measurements []Measurement
+	calories     map[string]int
+	nextID       int
+}
+
+func (s *memoryStore) Measurements(_ context.Context, userID int, from, to string) ([]Measurement, error) {
+	var found []Measurement
+	for _, m := range s.measurements {
+		if m.UserID == userID && m.MeasuredOn >= from && m.MeasuredOn <= to {
+			found = append(found, m)
+		}
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].MeasuredOn < found[j].MeasuredOn })
+	return found, nil
+}
+
+func (s *memoryStore) Save(_ context.Context, m Measurement) (Measurement, error) {
+	for i, existing := range s.measurements {
+		if existing.UserID == m.UserID && existing.MeasuredOn == m.MeasuredOn {
+			keep := func(set, saved *float64) *float64 {
+				if set != nil {
+					return set
+				}
+				return saved
+			}
+			existing.WeightKg, existing.BodyFatPercent = keep(m.WeightKg, existing.WeightKg), keep(m.BodyFatPercent, existing.BodyFatPercent)
+			existing.WaistCm, existing.HipsCm = keep(m.WaistCm, existing.WaistCm), keep(m.HipsCm, existing.HipsCm)
+			existing.ChestCm, existing.NeckCm = keep(m.ChestCm, existing.ChestCm), keep(m.NeckCm, existing.NeckCm)
+			if m.Note != "" {
+				existing.Note = m.Note
+			}
+			for _, name := range m.Clear {
+				switch name {
+				case "weight_kg":
+					existing.WeightKg = nil
+				case "waist_cm":
+					existing.WaistCm = nil
+				case "note":
+					existing.Note = ""
+				}
+			}
+			s.measurements[i] = existing
+			return existing, nil
+		}
+	}
+	if !m.sets() {
+		return Measurement{}, sql.ErrNoRows
+	}
+	m.Clear = nil
+	s.nextID++
+	m.ID = s.nextID
+	s.measurements = append(s.measurements, m)
+	return m, nil
+}
+
+func (s *memoryStore) Delete(_ context.Context, userID, measurementID int) error {
+	for i, m := range s.measurements {
+		if m.ID == measurementID && m.UserID == userID {
+			s.measurements = append(s.measurements[:i], s.measurements[i+1:]...)
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func (s *memoryStore) DailyCalories(context.Context, int, time.Time, time.Time, *time.Location) (map[string]int, error) {
+	return s.calories, nil
+}
+
+type utc struct{}
+
+func (utc) Location(context.Context, int) (*time.Location, error) { return time.UTC, nil }
+
+func newServer(store Store) *http.ServeMux {
+	mux := http.NewServeMux()
+	routes(store, utc{}, authtest.Auth)(mux)
+	return mux
+}
+
+func TestSave(t *testing.T) {
+	store := &memoryStore{}
+	mux := newServer(store)
+
+	rec := authtest.Request(t, mux, http.MethodPut, "/api/measurements", 4, `{"measured_on": "2024-05-01", "weight_kg": 80.4, "waist_cm": 90}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	authtest.Request(t, mux, http.MethodPut, "/api/measurements", 4, `{"measured_on": "2024-05-01", "weight_kg": 80.2}`)
+	if len(store.measurements) != 1 || *store.measurements[0].WeightKg != 80.2 || store.measurements[0].WaistCm == nil {
+		t.Errorf("expected the new weight and the earlier waist, got %+v", store.measurements)
+	}
+	rec = authtest.Request(t, mux, http.MethodPut, "/api/measurements", 4, `{"measured_on": "2024-05-01", "waist_cm": null}`)
+	if rec.Code != http.StatusOK || store.measurements[0].WaistCm != nil || *store.measurements[0].WeightKg != 80.2 {
+		t.Errorf("expected the waist to be cleared and the weight kept, got %d %+v", rec.Code, store.measurements)
+	}
+	if rec := authtest.Request(t, mux, http.MethodPut, "/api/measurements", 4, `{"measured_on": "2024-04-01", "waist_cm": null}`); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 clearing a day without measurements, got %d", rec.Code)
+	}
+	authtest.Request(t, mux, http.MethodPut, "/api/measurements", 4, `{"weight_kg": 80}`)
+	if today := time.Now().UTC().Format(time.DateOnly); store.measurements[1].MeasuredOn != today {
+		t.Errorf("expected today's date, got %s", store.measurements[1].MeasuredOn)
+	}
+
+	tomorrow := time.Now().UTC().AddDate(0, 0, 1).Format(time.DateOnly)
+	invalid := map[string]string{
+		"nothing":  `{"measured_on": "2024-05-01", "note": "forgot the scale"}`,
+		"weight":   `{"weight_kg": 8000}`,
+		"waist":    `{"waist_cm": 1}`,
+		"date":     `{"measured_on": "May 1", "weight_kg": 80}`,
+		"future":   `{"measured_on": "` + tomorrow + `", "weight_kg": 80}`,
+		"not json": `{`,
+	}
+	for name, body := range invalid {
+		if rec := authtest.Request(t, mux, http.MethodPut, "/api/measurements", 4, body); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", name, rec.Code)
+		}
+	}
+	if rec := authtest.Request(t, mux, http.MethodDelete, "/api/measurements/1", 5, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 deleting another user's measurements, got %d", rec.Code)
+	}
+}
+
+func TestTrend(t *testing.T) {
+	store := &memoryStore{calories: map[string]int{}}
+	today := time.Now().UTC()
+	for i := 0; i < 60; i++ {
+		day := today.AddDate(0, 0, -i).Format(time.DateOnly)
+		kg := 80 + 0.05*float64(i)
+		store.measurements = append(store.measurements, Measurement{UserID: 4, MeasuredOn: day, WeightKg: &kg})
+		store.calories[day] = 2200
+	}
+
+	rec := authtest.Request(t, newServer(store), http.MethodGet, "/api/measurements/trend?days=14", 4, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var a Analysis
+	if err := json.NewDecoder(rec.Body).Decode(&a); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if len(a.Points) != 14 || a.To != today.Format(time.DateOnly) || a.TimeZone != "UTC" {
+		t.Errorf("unexpected window %s to %s with %d points", a.From, a.To, len(a.Points))
+	}
+	if a.WeeklyRateKg == nil || *a.WeeklyRateKg >= 0 || a.EstimatedTDEE == nil || *a.EstimatedTDEE <= 2200 {
+		t.Errorf("expected a loss and a TDEE above intake, got %+v", a)
+	}
+
+	if rec := authtest.Request(t, newServer(store), http.MethodGet, "/api/measurements/trend?days=3", 4, ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a window shorter than a week, got %d", rec.Code)
+	}
+}
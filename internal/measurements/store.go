@@ -0,0 +1,177 @@
+package measurements
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Measurement is one row of body_measurements. Every measure is optional, but at
+// least one must be set.
+type Measurement struct {
+	ID             int      `json:"id"`
+	UserID         int      `json:"user_id"`
+	MeasuredOn     string   `json:"measured_on"`
+	WeightKg       *float64 `json:"weight_kg,omitempty"`
+	BodyFatPercent *float64 `json:"body_fat_percent,omitempty"`
+	WaistCm        *float64 `json:"waist_cm,omitempty"`
+	HipsCm         *float64 `json:"hips_cm,omitempty"`
+	ChestCm        *float64 `json:"chest_cm,omitempty"`
+	NeckCm         *float64 `json:"neck_cm,omitempty"`
+	Note           string   `json:"note,omitempty"`
+	// Clear names the measures, by JSON name, and "note" that Save empties; they
+	// are sent as null.
+	Clear []string `json:"-"`
+}
+
+// clearable are the JSON names of the fields a save may clear.
+var clearable = []string{"weight_kg", "body_fat_percent", "waist_cm", "hips_cm", "chest_cm", "neck_cm", "note"}
+
+// sets reports whether m sets any measure.
+func (m Measurement) sets() bool {
+	return m.WeightKg != nil || m.BodyFatPercent != nil || m.WaistCm != nil || m.HipsCm != nil || m.ChestCm != nil || m.NeckCm != nil
+}
+
+// Store persists measurements and sums the intake they are compared with.
+type Store interface {
+	// Measurements returns the user's measurements from one date to another,
+	// inclusive, oldest first.
+	Measurements(ctx context.Context, userID int, from, to string) ([]Measurement, error)
+	// Save creates the day's measurements, or adds the measures that are set to
+	// them; measures left nil, and an empty note, keep their saved values unless
+	// named in Clear. A save that only clears returns sql.ErrNoRows when the day
+	// has no measurements.
+	Save(ctx context.Context, m Measurement) (Measurement, error)
+	Delete(ctx context.Context, userID, measurementID int) error
+	// DailyCalories sums the user's entries in [start, end) by day in loc.
+	DailyCalories(ctx context.Context, userID int, start, end time.Time, loc *time.Location) (map[string]int, error)
+}
+
+type PostgresStore struct {
+	db *sql.DB
+}
+
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+const measurementColumns = `id, user_id, to_char(measured_on, 'YYYY-MM-DD'), weight_kg, body_fat_percent, waist_cm, hips_cm, chest_cm, neck_cm, note`
+
+func scanMeasurement(row interface{ Scan(...any) error }) (Measurement, error) {
+	var m Measurement
+	var weight, bodyFat, waist, hips, chest, neck sql.NullFloat64
+	err := row.Scan(&m.ID, &m.UserID, &m.MeasuredOn, &weight, &bodyFat, &waist, &hips, &chest, &neck, &m.Note)
+	m.WeightKg, m.BodyFatPercent = floatOrNil(weight), floatOrNil(bodyFat)
+	m.WaistCm, m.HipsCm, m.ChestCm, m.NeckCm = floatOrNil(waist), floatOrNil(hips), floatOrNil(chest), floatOrNil(neck)
+	return m, err
+}
+
+func floatOrNil(v sql.NullFloat64) *float64 {
+	if !v.Valid {
+		return nil
+	}
+	return &v.Float64
+}
+
+func (s *PostgresStore) Measurements(ctx context.Context, userID int, from, to string) ([]Measurement, error) {
+	rows, err := s.db.QueryContext(ctx, `
+	SELECT `+measurementColumns+` FROM body_measurements
+	WHERE user_id = $1 AND measured_on >= $2 AND measured_on <= $3
+	ORDER BY measured_on;
+	`, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	measurements := []Measurement{}
+	for rows.Next() {
+		m, err := scanMeasurement(rows)
+		if err != nil {
+			return nil, err
+		}
+		measurements = append(measurements, m)
+	}
+	return measurements, rows.Err()
+}
+
+// clearOr keeps the saved value of column unless the save sets it, or clears it
+// by naming it in $10.
+func clearOr(column string) string {
+	return column + ` = CASE WHEN '` + column + `' = ANY($10) THEN NULL ELSE COALESCE(EXCLUDED.` + column + `, body_measurements.` + column + `) END`
+}
+
+func (s *PostgresStore) Save(ctx context.Context, m Measurement) (Measurement, error) {
+	if !m.sets() {
+		// Only clearing: there is no day to create.
+		return scanMeasurement(s.db.QueryRowContext(ctx, `
+		UPDATE body_measurements SET
+			weight_kg = CASE WHEN 'weight_kg' = ANY($4) THEN NULL ELSE weight_kg END,
+			body_fat_percent = CASE WHEN 'body_fat_percent' = ANY($4) THEN NULL ELSE body_fat_percent END,
+			waist_cm = CASE WHEN 'waist_cm' = ANY($4) THEN NULL ELSE waist_cm END,
+			hips_cm = CASE WHEN 'hips_cm' = ANY($4) THEN NULL ELSE hips_cm END,
+			chest_cm = CASE WHEN 'chest_cm' = ANY($4) THEN NULL ELSE chest_cm END,
+			neck_cm = CASE WHEN 'neck_cm' = ANY($4) THEN NULL ELSE neck_cm END,
+			note = CASE WHEN 'note' = ANY($4) THEN '' ELSE COALESCE(NULLIF($3, ''), note) END,
+			updated_at = NOW()
+		WHERE user_id = $1 AND measured_on = $2
+		RETURNING `+measurementColumns+`;
+		`, m.UserID, m.MeasuredOn, m.Note, pq.Array(m.Clear)))
+	}
+	return scanMeasurement(s.db.QueryRowContext(ctx, `
+	INSERT INTO body_measurements (user_id, measured_on, weight_kg, body_fat_percent, waist_cm, hips_cm, chest_cm, neck_cm, note)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	ON CONFLICT (user_id, measured_on) DO UPDATE SET
+		`+clearOr("weight_kg")+`,
+		`+clearOr("body_fat_percent")+`,
+		`+clearOr("waist_cm")+`,
+		`+clearOr("hips_cm")+`,
+		`+clearOr("chest_cm")+`,
+		`+clearOr("neck_cm")+`,
+		note = CASE WHEN 'note' = ANY($10) THEN '' ELSE COALESCE(NULLIF(EXCLUDED.note, ''), body_measurements.note) END,
+		updated_at = NOW()
+	RETURNING `+measurementColumns+`;
+	`, m.UserID, m.MeasuredOn, m.WeightKg, m.BodyFatPercent, m.WaistCm, m.HipsCm, m.ChestCm, m.NeckCm, m.Note, pq.Array(m.Clear)))
+}
+
+// Delete removes measurements owned by userID; it returns sql.ErrNoRows for other users'.
+func (s *PostgresStore) Delete(ctx context.Context, userID, measurementID int) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM body_measurements WHERE id = $1 AND user_id = $2;`, measurementID, userID)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (s *PostgresStore) DailyCalories(ctx context.Context, userID int, start, end time.Time, loc *time.Location) (map[string]int, error) {
+	rows, err := s.db.QueryContext(ctx, `
+	SELECT to_char((consumed_at AT TIME ZONE $4)::date, 'YYYY-MM-DD') AS day, SUM(calories)
+	FROM food_entries
+	WHERE user_id = $1 AND consumed_at >= $2 AND consumed_at < $3
+	GROUP BY day;
+	`, userID, start, end, loc.String())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	calories := map[string]int{}
+	for rows.Next() {
+		var day string
+		var total int
+		if err := rows.Scan(&day, &total); err != nil {
+			return nil, err
+		}
+		calories[day] = total
+	}
+	return calories, rows.Err()
+}
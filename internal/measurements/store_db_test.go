@@ -0,0 +1,53 @@
+package measurements
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/coloradocollective/go-capstone-starter/internal/dbtest"
+)
+
+func TestPostgresStore_PartialSavesOnTheSameDay(t *testing.T) {
+	db := dbtest.Open(t)
+	userID := dbtest.UserID(t, db)
+	store := NewPostgresStore(db)
+	ctx := context.Background()
+	weight, waist, laterWeight := 80.4, 90.0, 80.1
+
+	if _, err := store.Save(ctx, Measurement{UserID: userID, MeasuredOn: "2024-05-01", WeightKg: &weight, Note: "morning"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	saved, err := store.Save(ctx, Measurement{UserID: userID, MeasuredOn: "2024-05-01", WaistCm: &waist})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if saved.WeightKg == nil || *saved.WeightKg != weight || saved.WaistCm == nil || *saved.WaistCm != waist || saved.Note != "morning" {
+		t.Fatalf("expected the waist to be added to the morning weight, got %+v", saved)
+	}
+
+	saved, err = store.Save(ctx, Measurement{UserID: userID, MeasuredOn: "2024-05-01", WeightKg: &laterWeight})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if *saved.WeightKg != laterWeight || saved.WaistCm == nil {
+		t.Fatalf("expected the weight to be updated and the waist kept, got %+v", saved)
+	}
+
+	saved, err = store.Save(ctx, Measurement{UserID: userID, MeasuredOn: "2024-05-01", Clear: []string{"waist_cm", "note"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if saved.WaistCm != nil || saved.Note != "" || *saved.WeightKg != laterWeight {
+		t.Fatalf("expected the waist and note to be cleared and the weight kept, got %+v", saved)
+	}
+	if _, err := store.Save(ctx, Measurement{UserID: userID, MeasuredOn: "2024-04-01", Clear: []string{"waist_cm"}}); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("expected no rows clearing an empty day, got %v", err)
+	}
+
+	all, err := store.Measurements(ctx, userID, "2024-05-01", "2024-05-01")
+	if err != nil || len(all) != 1 {
+		t.Fatalf("expected one row for the day, got %+v %v", all, err)
+	}
+}
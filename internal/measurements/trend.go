@@ -0,0 +1,119 @@
+package measurements
+
+import (
+	"math"
+	"time"
+)
+
+// trendSmoothing is how much a day's weigh-in moves the trend. At 0.1 the trend
+// mostly ignores day-to-day water swings yet follows a real change within weeks.
+const trendSmoothing = 0.1
+
+// kcalPerKg is the approximate energy content of a kilogram of body weight change.
+const kcalPerKg = 7700
+
+// Minimums for a meaningful rate and TDEE.
+const (
+	minTrendSpanDays = 7
+	minLoggedDays    = 7
+)
+
+// Point is a weigh-in and the trend after it.
+type Point struct {
+	Date     string  `json:"date"`
+	WeightKg float64 `json:"weight_kg"`
+	TrendKg  float64 `json:"trend_kg"`
+}
+
+// Analysis relates the weight trend to logged intake over a window of days.
+// Estimates are nil when there is too little data; Note then says why.
+type Analysis struct {
+	From          string   `json:"from"`
+	To            string   `json:"to"`
+	TimeZone      string   `json:"time_zone"`
+	Points        []Point  `json:"points"`
+	WeeklyRateKg  *float64 `json:"weekly_rate_kg"`
+	LoggedDays    int      `json:"logged_days"`
+	AverageIntake *int     `json:"average_intake"`
+	EstimatedTDEE *int     `json:"estimated_tdee"`
+	Note          string   `json:"note,omitempty"`
+}
+
+func days(from, to string) float64 {
+	a, _ := time.Parse(time.DateOnly, from)
+	b, _ := time.Parse(time.DateOnly, to)
+	return math.Round(b.Sub(a).Hours() / 24)
+}
+
+func round(v float64, places int) float64 {
+	p := math.Pow(10, float64(places))
+	return math.Round(v*p) / p
+}
+
+// smooth computes an exponential moving average of the weigh-ins, oldest first.
+// A gap of n days counts as n daily steps towards the next weigh-in, so sparse
+// logging does not make the trend jumpier.
+func smooth(measurements []Measurement) []Point {
+	points := []Point{}
+	var trend float64
+	var last string
+	for _, m := range measurements {
+		if m.WeightKg == nil {
+			continue
+		}
+		w := *m.WeightKg
+		if last == "" {
+			trend = w
+		} else {
+			alpha := 1 - math.Pow(1-trendSmoothing, days(last, m.MeasuredOn))
+			trend += alpha * (w - trend)
+		}
+		last = m.MeasuredOn
+		points = append(points, Point{Date: m.MeasuredOn, WeightKg: w, TrendKg: round(trend, 2)})
+	}
+	return points
+}
+
+// analyze smooths measurements, which may start before from to warm the trend up,
+// and estimates the weekly rate and the TDEE that explains it:
+//
+//	TDEE = average daily intake - daily trend change * kcalPerKg
+//
+// The estimate assumes the days with entries were logged completely.
+func analyze(from, to string, measurements []Measurement, dailyCalories map[string]int) Analysis {
+	a := Analysis{From: from, To: to, Points: []Point{}}
+	for _, p := range smooth(measurements) {
+		if p.Date >= from && p.Date <= to {
+			a.Points = append(a.Points, p)
+		}
+	}
+
+	total := 0
+	for day, calories := range dailyCalories {
+		if day >= from && day <= to {
+			a.LoggedDays++
+			total += calories
+		}
+	}
+	if a.LoggedDays > 0 {
+		average := int(math.Round(float64(total) / float64(a.LoggedDays)))
+		a.AverageIntake = &average
+	}
+
+	if len(a.Points) < 2 || days(a.Points[0].Date, a.Points[len(a.Points)-1].Date) < minTrendSpanDays {
+		a.Note = "log your weight over at least a week to see a rate"
+		return a
+	}
+	first, last := a.Points[0], a.Points[len(a.Points)-1]
+	perDay := (last.TrendKg - first.TrendKg) / days(first.Date, last.Date)
+	rate := round(perDay*7, 2)
+	a.WeeklyRateKg = &rate
+
+	if a.LoggedDays < minLoggedDays {
+		a.Note = "log food on at least a week of days to estimate TDEE"
+		return a
+	}
+	tdee := int(math.Round(float64(*a.AverageIntake) - perDay*kcalPerKg))
+	a.EstimatedTDEE = &tdee
+	return a
+}
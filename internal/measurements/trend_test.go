@@ -0,0 +1,69 @@
+package measurements
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func weighIn(date string, kg float64) Measurement {
+	return Measurement{MeasuredOn: date, WeightKg: &kg}
+}
+
+func TestSmooth(t *testing.T) {
+	points := smooth([]Measurement{
+		weighIn("2024-05-01", 80),
+		{MeasuredOn: "2024-05-02"}, // waist only
+		weighIn("2024-05-02", 81),
+		weighIn("2024-05-04", 80),
+	})
+	if len(points) != 3 {
+		t.Fatalf("expected 3 weigh-ins, got %+v", points)
+	}
+	if points[0].TrendKg != 80 || points[1].TrendKg != 80.1 {
+		t.Errorf("expected the trend to move a tenth of the way, got %+v", points[:2])
+	}
+	// Two days at 10% each close 19% of the gap: 80.1 - 0.19*0.1.
+	if points[2].TrendKg != 80.08 {
+		t.Errorf("expected a two-day step to 80.08, got %v", points[2].TrendKg)
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	var measurements []Measurement
+	calories := map[string]int{}
+	start := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 90; i++ {
+		day := start.AddDate(0, 0, i).Format(time.DateOnly)
+		// Losing 0.1 kg a day while eating 2000 kcal means a TDEE of about 2770.
+		measurements = append(measurements, weighIn(day, 90-0.1*float64(i)))
+		calories[day] = 2000
+	}
+
+	a := analyze("2024-06-02", "2024-06-29", measurements, calories)
+	if len(a.Points) != 28 || a.LoggedDays != 28 || *a.AverageIntake != 2000 {
+		t.Fatalf("unexpected window %d points, %d logged days", len(a.Points), a.LoggedDays)
+	}
+	if rate := *a.WeeklyRateKg; rate > -0.69 || rate < -0.71 {
+		t.Errorf("expected a rate of about -0.7 kg a week, got %v", rate)
+	}
+	if tdee := *a.EstimatedTDEE; tdee < 2750 || tdee > 2790 {
+		t.Errorf("expected a TDEE of about 2770, got %d", tdee)
+	}
+}
+
+func TestAnalyze_TooLittleData(t *testing.T) {
+	calories := map[string]int{}
+	for i := 1; i <= 3; i++ {
+		calories[fmt.Sprintf("2024-05-0%d", i)] = 1800
+	}
+	a := analyze("2024-05-01", "2024-05-28", []Measurement{weighIn("2024-05-01", 80), weighIn("2024-05-03", 79.5)}, calories)
+	if a.WeeklyRateKg != nil || a.EstimatedTDEE != nil || a.Note == "" {
+		t.Errorf("expected no estimates for two days of weigh-ins, got %+v", a)
+	}
+
+	a = analyze("2024-05-01", "2024-05-28", []Measurement{weighIn("2024-05-01", 80), weighIn("2024-05-20", 79.5)}, calories)
+	if a.WeeklyRateKg == nil || a.EstimatedTDEE != nil {
+		t.Errorf("expected a rate but no TDEE for three logged days, got %+v", a)
+	}
+}
@@ -0,0 +1,55 @@
+package messaging
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Broker is a backend that can both publish and subscribe.
+type Broker interface {
+	Publisher
+	Subscriber
+}
+
+// Backend names accepted in MESSAGING_BACKEND.
+const (
+	BackendGCP    = "gcp"
+	BackendRedis  = "redis"
+	BackendMemory = "memory"
+)
+
+// NewFromEnv picks the broker from MESSAGING_BACKEND: "gcp" uses Pub/Sub in
+// GCP_PROJECT_ID, "redis" uses Redis Streams at MESSAGING_REDIS_URL and "memory"
+// an in-process broker. Without MESSAGING_BACKEND it uses gcp when GCP_PROJECT_ID
+// is set and memory otherwise, so local runs need no cloud credentials.
+func NewFromEnv(ctx context.Context) (Broker, error) {
+	backend := os.Getenv("MESSAGING_BACKEND")
+	if backend == "" {
+		backend = BackendMemory
+		if os.Getenv("GCP_PROJECT_ID") != "" {
+			backend = BackendGCP
+		}
+	}
+
+	switch backend {
+	case BackendGCP:
+		projectID := os.Getenv("GCP_PROJECT_ID")
+		if projectID == "" {
+			return nil, fmt.Errorf("MESSAGING_BACKEND=gcp requires GCP_PROJECT_ID")
+		}
+		return NewGCPBroker(ctx, projectID)
+	case BackendRedis:
+		opts, err := redis.ParseURL(os.Getenv("MESSAGING_REDIS_URL"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid MESSAGING_REDIS_URL: %w", err)
+		}
+		return NewRedisBroker(redis.NewClient(opts)), nil
+	case BackendMemory:
+		return NewMemoryBroker(), nil
+	default:
+		return nil, fmt.Errorf("unknown MESSAGING_BACKEND %q", backend)
+	}
+}
@@ -0,0 +1,82 @@
+package messaging
+
+import (
+	"context"
+	"sync"
+
+	"cloud.google.com/go/pubsub"
+)
+
+// GCPBroker publishes and subscribes through Google Cloud Pub/Sub. Topics are
+// published to with message ordering enabled; subscriptions must also be created
+// with message ordering enabled for Pub/Sub to deliver each ordering key in order.
+type GCPBroker struct {
+	client *pubsub.Client
+
+	mu     sync.Mutex
+	topics map[string]*pubsub.Topic
+}
+
+func NewGCPBroker(ctx context.Context, projectID string) (*GCPBroker, error) {
+	client, err := pubsub.NewClient(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	return &GCPBroker{client: client, topics: make(map[string]*pubsub.Topic)}, nil
+}
+
+// Client exposes the underlying client for code that still talks to Pub/Sub directly.
+func (b *GCPBroker) Client() *pubsub.Client {
+	return b.client
+}
+
+// topic returns the publishing handle of name. Handles are kept, as ordering is a
+// setting of the handle and each one batches its own messages.
+func (b *GCPBroker) topic(name string) *pubsub.Topic {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	t, ok := b.topics[name]
+	if !ok {
+		t = b.client.Topic(name)
+		t.EnableMessageOrdering = true
+		b.topics[name] = t
+	}
+	return t
+}
+
+func (b *GCPBroker) Publish(ctx context.Context, topic string, msg Message) error {
+	t := b.topic(topic)
+	result := t.Publish(ctx, &pubsub.Message{Data: msg.Data, Attributes: msg.Attributes, OrderingKey: msg.OrderingKey})
+	_, err := result.Get(ctx)
+	if err != nil && msg.OrderingKey != "" {
+		// Pub/Sub pauses a key after a failed publish so later messages cannot
+		// overtake it; the caller retries in order, so the key is resumed.
+		t.ResumePublish(msg.OrderingKey)
+	}
+	return err
+}
+
+// Subscribe receives from an existing Pub/Sub subscription; topic is implied by the subscription.
+func (b *GCPBroker) Subscribe(ctx context.Context, _ string, subscription string, handler Handler) error {
+	return b.client.Subscription(subscription).Receive(ctx, func(ctx context.Context, m *pubsub.Message) {
+		msg := Message{ID: m.ID, Data: m.Data, Attributes: m.Attributes, OrderingKey: m.OrderingKey}
+		if m.DeliveryAttempt != nil {
+			msg.Attempt = *m.DeliveryAttempt
+		}
+		if err := handler(ctx, msg); err != nil {
+			m.Nack()
+			return
+		}
+		m.Ack()
+	})
+}
+
+func (b *GCPBroker) Close() error {
+	b.mu.Lock()
+	for _, t := range b.topics {
+		t.Stop()
+	}
+	b.mu.Unlock()
+	return b.client.Close()
+}
@@ -0,0 +1,173 @@
+package messaging
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultMaxAttempts is how often the in-memory broker delivers a message before dead-lettering it.
+const DefaultMaxAttempts = 5
+
+// DefaultMaxPending is how many messages the in-memory broker keeps per topic
+// while the topic has no subscription.
+const DefaultMaxPending = 1000
+
+// MemoryBroker is an in-process broker for local development and tests. Every
+// subscription on a topic gets its own copy of each message. Messages published
+// before a topic has any subscription are kept for the first one, up to
+// MaxPending; beyond that the oldest are dropped, as a process that publishes
+// without subscribing would otherwise grow without bound. Nacked messages are
+// redelivered after RetryDelay until MaxAttempts is reached, then moved to the
+// dead letters. Each subscription handles one message at a time in publish order,
+// but a redelivery can overtake later messages of its ordering key.
+type MemoryBroker struct {
+	MaxAttempts int
+	MaxPending  int
+	RetryDelay  time.Duration
+
+	mu            sync.Mutex
+	nextID        int
+	pending       map[string][]Message
+	subscriptions map[string]map[string]chan Message
+	deadLetters   []Message
+	dropped       map[string]int
+	inFlight      sync.WaitGroup
+}
+
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{
+		MaxAttempts:   DefaultMaxAttempts,
+		MaxPending:    DefaultMaxPending,
+		RetryDelay:    100 * time.Millisecond,
+		pending:       make(map[string][]Message),
+		dropped:       make(map[string]int),
+		subscriptions: make(map[string]map[string]chan Message),
+	}
+}
+
+const memoryQueueSize = 1024
+
+func (b *MemoryBroker) Publish(_ context.Context, topic string, msg Message) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.nextID++
+	msg.ID, msg.Attempt = strconv.Itoa(b.nextID), 0
+	subs := b.subscriptions[topic]
+	if len(subs) == 0 {
+		// A kept message counts as in flight from now, so Drain cannot start
+		// waiting before a subscription that is about to pick it up.
+		b.inFlight.Add(1)
+		pending := append(b.pending[topic], msg)
+		if over := len(pending) - b.MaxPending; over > 0 {
+			if b.dropped[topic] == 0 {
+				log.Printf("Warning: topic %s has no subscription; dropping its oldest undelivered messages beyond %d", topic, b.MaxPending)
+			}
+			b.dropped[topic] += over
+			b.inFlight.Add(-over)
+			pending = append([]Message(nil), pending[over:]...)
+		}
+		b.pending[topic] = pending
+		return nil
+	}
+	for _, queue := range subs {
+		b.enqueue(queue, msg)
+	}
+	return nil
+}
+
+// enqueue hands msg to a subscription queue, keeping publish order while the
+// queue has room and never blocking the publisher.
+func (b *MemoryBroker) enqueue(queue chan Message, msg Message) {
+	b.inFlight.Add(1)
+	b.send(queue, msg)
+}
+
+// send is enqueue for a message already counted in flight.
+func (b *MemoryBroker) send(queue chan Message, msg Message) {
+	select {
+	case queue <- msg:
+	default:
+		go func() {
+			queue <- msg
+		}()
+	}
+}
+
+func (b *MemoryBroker) Subscribe(ctx context.Context, topic, subscription string, handler Handler) error {
+	queue := b.subscription(topic, subscription)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg := <-queue:
+			msg.Attempt++
+			if err := handler(ctx, msg); err != nil {
+				b.retry(queue, msg)
+			}
+			b.inFlight.Done()
+		}
+	}
+}
+
+func (b *MemoryBroker) subscription(topic, subscription string) chan Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.subscriptions[topic] == nil {
+		b.subscriptions[topic] = make(map[string]chan Message)
+	}
+	queue, ok := b.subscriptions[topic][subscription]
+	if !ok {
+		queue = make(chan Message, memoryQueueSize)
+		b.subscriptions[topic][subscription] = queue
+		for _, msg := range b.pending[topic] {
+			b.send(queue, msg)
+		}
+		delete(b.pending, topic)
+	}
+	return queue
+}
+
+func (b *MemoryBroker) retry(queue chan Message, msg Message) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if msg.Attempt >= b.MaxAttempts {
+		b.deadLetters = append(b.deadLetters, msg)
+		return
+	}
+	b.inFlight.Add(1)
+	go func() {
+		time.Sleep(b.RetryDelay)
+		queue <- msg
+	}()
+}
+
+// DeadLetters returns the messages that exhausted their delivery attempts.
+func (b *MemoryBroker) DeadLetters() []Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]Message(nil), b.deadLetters...)
+}
+
+// Dropped returns how many messages of topic were dropped for want of a subscription.
+func (b *MemoryBroker) Dropped(topic string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.dropped[topic]
+}
+
+// Drain waits until every published message has been acknowledged or
+// dead-lettered. Messages kept for a topic's first subscription are counted, so
+// Drain does not return while a topic has kept messages and no subscription.
+func (b *MemoryBroker) Drain() {
+	b.inFlight.Wait()
+}
+
+func (b *MemoryBroker) Close() error {
+	return nil
+}
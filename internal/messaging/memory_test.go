@@ -0,0 +1,169 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestBroker() *MemoryBroker {
+	broker := NewMemoryBroker()
+	broker.RetryDelay = time.Millisecond
+	broker.MaxAttempts = 3
+	return broker
+}
+
+func TestMemoryBroker_DeliversToEverySubscription(t *testing.T) {
+	broker := newTestBroker()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mu sync.Mutex
+	received := map[string][]string{}
+	subscribe := func(name string) {
+		go broker.Subscribe(ctx, "recipes", name, func(_ context.Context, msg Message) error {
+			mu.Lock()
+			defer mu.Unlock()
+			received[name] = append(received[name], string(msg.Data))
+			return nil
+		})
+	}
+	subscribe("analyzer")
+	subscribe("search")
+	time.Sleep(10 * time.Millisecond)
+
+	_ = broker.Publish(ctx, "recipes", Message{Data: []byte("one")})
+	_ = broker.Publish(ctx, "recipes", Message{Data: []byte("two")})
+	broker.Drain()
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, name := range []string{"analyzer", "search"} {
+		if len(received[name]) != 2 || received[name][0] != "one" || received[name][1] != "two" {
+			t.Fatalf("expected %s to receive both messages in order, got %v", name, received[name])
+		}
+	}
+}
+
+func TestMemoryBroker_KeepsMessagesUntilFirstSubscription(t *testing.T) {
+	broker := newTestBroker()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_ = broker.Publish(ctx, "recipes", Message{Data: []byte("early")})
+
+	got := make(chan string, 1)
+	go broker.Subscribe(ctx, "recipes", "analyzer", func(_ context.Context, msg Message) error {
+		got <- string(msg.Data)
+		return nil
+	})
+
+	select {
+	case data := <-got:
+		if data != "early" {
+			t.Fatalf("expected early message, got %q", data)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for message published before subscribing")
+	}
+}
+
+func TestMemoryBroker_DrainWaitsForKeptMessages(t *testing.T) {
+	broker := newTestBroker()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_ = broker.Publish(ctx, "recipes", Message{Data: []byte("early")})
+	var mu sync.Mutex
+	var received []string
+	go broker.Subscribe(ctx, "recipes", "analyzer", func(_ context.Context, msg Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, string(msg.Data))
+		return nil
+	})
+	broker.Drain()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(received) != 1 || received[0] != "early" {
+		t.Fatalf("expected Drain to wait for the kept message, got %v", received)
+	}
+}
+
+func TestMemoryBroker_CapsMessagesWithoutSubscription(t *testing.T) {
+	broker := newTestBroker()
+	broker.MaxPending = 2
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for _, data := range []string{"one", "two", "three"} {
+		_ = broker.Publish(ctx, "entries", Message{Data: []byte(data)})
+	}
+	if dropped := broker.Dropped("entries"); dropped != 1 {
+		t.Fatalf("expected 1 dropped message, got %d", dropped)
+	}
+
+	got := make(chan string, 3)
+	go broker.Subscribe(ctx, "entries", "late", func(_ context.Context, msg Message) error {
+		got <- string(msg.Data)
+		return nil
+	})
+	for _, want := range []string{"two", "three"} {
+		select {
+		case data := <-got:
+			if data != want {
+				t.Fatalf("expected %q, got %q", want, data)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("timed out waiting for %q", want)
+		}
+	}
+}
+
+func TestMemoryBroker_RedeliversUntilAcked(t *testing.T) {
+	broker := newTestBroker()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var attempts []int
+	go broker.Subscribe(ctx, "recipes", "analyzer", func(_ context.Context, msg Message) error {
+		attempts = append(attempts, msg.Attempt)
+		if msg.Attempt < 2 {
+			return errors.New("temporary failure")
+		}
+		return nil
+	})
+	time.Sleep(10 * time.Millisecond)
+
+	_ = broker.Publish(ctx, "recipes", Message{Data: []byte("retry me")})
+	broker.Drain()
+
+	if len(attempts) != 2 || attempts[1] != 2 {
+		t.Fatalf("expected two attempts, got %v", attempts)
+	}
+	if len(broker.DeadLetters()) != 0 {
+		t.Fatalf("expected no dead letters, got %v", broker.DeadLetters())
+	}
+}
+
+func TestMemoryBroker_DeadLettersAfterMaxAttempts(t *testing.T) {
+	broker := newTestBroker()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go broker.Subscribe(ctx, "recipes", "analyzer", func(context.Context, Message) error {
+		return errors.New("permanent failure")
+	})
+	time.Sleep(10 * time.Millisecond)
+
+	_ = broker.Publish(ctx, "recipes", Message{Data: []byte("poison")})
+	broker.Drain()
+
+	dead := broker.DeadLetters()
+	if len(dead) != 1 || dead[0].Attempt != 3 {
+		t.Fatalf("expected one dead letter after 3 attempts, got %+v", dead)
+	}
+}
@@ -0,0 +1,35 @@
+package messaging
+
+import (
+	"context"
+)
+
+// Message is a payload to publish, or a single delivery of one. ID and Attempt
+// are set on delivery and ignored by Publish.
+type Message struct {
+	ID         string
+	Data       []byte
+	Attributes map[string]string
+	// OrderingKey groups messages that subscribers must receive in publish
+	// order; messages with different keys, or none, may be reordered.
+	OrderingKey string
+	// Attempt is the 1-based delivery attempt, or 0 when the backend does not track it.
+	Attempt int
+}
+
+// Handler processes a message. Returning nil acknowledges it; returning an error
+// negatively acknowledges it so the backend redelivers it later.
+type Handler func(ctx context.Context, msg Message) error
+
+// Publisher sends payloads to a topic.
+type Publisher interface {
+	Publish(ctx context.Context, topic string, msg Message) error
+	Close() error
+}
+
+// Subscriber delivers messages published to a topic to a named subscription.
+// Subscribe blocks until ctx is cancelled or the subscription fails.
+type Subscriber interface {
+	Subscribe(ctx context.Context, topic, subscription string, handler Handler) error
+	Close() error
+}
@@ -0,0 +1,248 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisBroker publishes to Redis Streams, one stream per topic, and maps every
+// subscription to a consumer group. Nacked messages stay pending in the group and
+// are reclaimed once they have been idle for ClaimAfter; after MaxAttempts
+// deliveries they are copied to the "<topic>:dead" stream and acknowledged. A
+// message is not handled while an earlier one with its ordering key is pending
+// in the group; it stays pending itself and is reclaimed after it.
+type RedisBroker struct {
+	MaxAttempts int
+	ClaimAfter  time.Duration
+	// BlockFor bounds how long a read waits for new messages, and so how quickly
+	// Subscribe notices a cancelled context.
+	BlockFor time.Duration
+	// Consumer names this process within each consumer group.
+	Consumer string
+
+	client *redis.Client
+}
+
+func NewRedisBroker(client *redis.Client) *RedisBroker {
+	hostname, _ := os.Hostname()
+	return &RedisBroker{
+		MaxAttempts: DefaultMaxAttempts,
+		ClaimAfter:  30 * time.Second,
+		BlockFor:    2 * time.Second,
+		Consumer:    fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+		client:      client,
+	}
+}
+
+const redisReadCount = 10
+
+// redisOrderWindow is how many earlier pending messages are checked for one with
+// the same ordering key.
+const redisOrderWindow = 100
+
+func (b *RedisBroker) Publish(ctx context.Context, topic string, msg Message) error {
+	encoded, err := json.Marshal(msg.Attributes)
+	if err != nil {
+		return err
+	}
+	values := map[string]any{"data": msg.Data, "attributes": encoded}
+	if msg.OrderingKey != "" {
+		values["ordering_key"] = msg.OrderingKey
+	}
+	return b.client.XAdd(ctx, &redis.XAddArgs{Stream: topic, Values: values}).Err()
+}
+
+// Subscribe creates the consumer group on first use, starting from the beginning
+// of the stream so messages published before the first subscription are delivered.
+func (b *RedisBroker) Subscribe(ctx context.Context, topic, subscription string, handler Handler) error {
+	err := b.client.XGroupCreateMkStream(ctx, topic, subscription, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return err
+	}
+
+	for ctx.Err() == nil {
+		if err := b.reclaim(ctx, topic, subscription, handler); err != nil {
+			return b.stopped(ctx, err)
+		}
+
+		streams, err := b.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    subscription,
+			Consumer: b.Consumer,
+			Streams:  []string{topic, ">"},
+			Count:    redisReadCount,
+			Block:    b.BlockFor,
+		}).Result()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			return b.stopped(ctx, err)
+		}
+		for _, stream := range streams {
+			for _, m := range stream.Messages {
+				waiting, err := b.waiting(ctx, topic, subscription, m)
+				if err != nil {
+					return b.stopped(ctx, err)
+				}
+				if waiting {
+					continue
+				}
+				if err := b.deliver(ctx, topic, subscription, m, 1, handler); err != nil {
+					return b.stopped(ctx, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// stopped hides errors caused by the subscriber's own cancellation.
+func (b *RedisBroker) stopped(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+// reclaim redelivers messages that stayed pending for ClaimAfter, whichever
+// consumer they were last delivered to, and dead-letters those out of attempts.
+// Messages still waiting for an earlier one of their ordering key are left
+// unclaimed, so waiting does not use up their attempts.
+func (b *RedisBroker) reclaim(ctx context.Context, topic, subscription string, handler Handler) error {
+	pending, err := b.client.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream: topic,
+		Group:  subscription,
+		Idle:   b.ClaimAfter,
+		Start:  "-",
+		End:    "+",
+		Count:  redisReadCount,
+	}).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, p := range pending {
+		if p.RetryCount >= int64(b.MaxAttempts) {
+			if err := b.deadLetter(ctx, topic, subscription, p.ID); err != nil {
+				return err
+			}
+			continue
+		}
+		entries, err := b.client.XRange(ctx, topic, p.ID, p.ID).Result()
+		if err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			continue
+		}
+		waiting, err := b.waiting(ctx, topic, subscription, entries[0])
+		if err != nil {
+			return err
+		}
+		if waiting {
+			continue
+		}
+		claimed, err := b.client.XClaim(ctx, &redis.XClaimArgs{
+			Stream:   topic,
+			Group:    subscription,
+			Consumer: b.Consumer,
+			MinIdle:  b.ClaimAfter,
+			Messages: []string{p.ID},
+		}).Result()
+		if err != nil {
+			return err
+		}
+		for _, m := range claimed {
+			if err := b.deliver(ctx, topic, subscription, m, int(p.RetryCount)+1, handler); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// waiting reports whether an earlier message with the ordering key of m is
+// pending in the group, so m has to wait for it.
+func (b *RedisBroker) waiting(ctx context.Context, topic, subscription string, m redis.XMessage) (bool, error) {
+	key, _ := m.Values["ordering_key"].(string)
+	if key == "" {
+		return false, nil
+	}
+	earlier, err := b.client.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream: topic,
+		Group:  subscription,
+		Start:  "-",
+		End:    m.ID,
+		Count:  redisOrderWindow,
+	}).Result()
+	if errors.Is(err, redis.Nil) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	for _, p := range earlier {
+		if p.ID == m.ID {
+			continue
+		}
+		entries, err := b.client.XRange(ctx, topic, p.ID, p.ID).Result()
+		if err != nil {
+			return false, err
+		}
+		for _, e := range entries {
+			if k, _ := e.Values["ordering_key"].(string); k == key {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// deliver hands one stream entry to handler and acknowledges it on success.
+// Only Redis errors are returned; a nack leaves the entry pending.
+func (b *RedisBroker) deliver(ctx context.Context, topic, subscription string, m redis.XMessage, attempt int, handler Handler) error {
+	msg := Message{ID: m.ID, Attempt: attempt}
+	msg.OrderingKey, _ = m.Values["ordering_key"].(string)
+	if data, ok := m.Values["data"].(string); ok {
+		msg.Data = []byte(data)
+	}
+	if attributes, ok := m.Values["attributes"].(string); ok {
+		_ = json.Unmarshal([]byte(attributes), &msg.Attributes)
+	}
+
+	if err := handler(ctx, msg); err != nil {
+		return nil
+	}
+	return b.client.XAck(ctx, topic, subscription, m.ID).Err()
+}
+
+func (b *RedisBroker) deadLetter(ctx context.Context, topic, subscription, id string) error {
+	entries, err := b.client.XRange(ctx, topic, id, id).Result()
+	if err != nil {
+		return err
+	}
+	for _, m := range entries {
+		values := map[string]any{"subscription": subscription, "id": m.ID}
+		for k, v := range m.Values {
+			values[k] = v
+		}
+		if err := b.client.XAdd(ctx, &redis.XAddArgs{Stream: topic + ":dead", Values: values}).Err(); err != nil {
+			return err
+		}
+	}
+	return b.client.XAck(ctx, topic, subscription, id).Err()
+}
+
+func (b *RedisBroker) Close() error {
+	return b.client.Close()
+}
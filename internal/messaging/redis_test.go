@@ -0,0 +1,177 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestRedisBroker(t *testing.T) (*RedisBroker, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	broker := NewRedisBroker(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+	broker.MaxAttempts = 3
+	broker.ClaimAfter = time.Millisecond
+	broker.BlockFor = 10 * time.Millisecond
+	t.Cleanup(func() { broker.Close() })
+	return broker, mr
+}
+
+func TestRedisBroker_DeliversMessagesPublishedBeforeSubscribing(t *testing.T) {
+	broker, _ := newTestRedisBroker(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := broker.Publish(ctx, "recipes", Message{Data: []byte("one"), Attributes: map[string]string{"type": "recipe.ingested"}}); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+
+	got := make(chan Message, 1)
+	go broker.Subscribe(ctx, "recipes", "analyzer", func(_ context.Context, msg Message) error {
+		got <- msg
+		return nil
+	})
+
+	select {
+	case msg := <-got:
+		if string(msg.Data) != "one" || msg.Attributes["type"] != "recipe.ingested" || msg.Attempt != 1 {
+			t.Fatalf("unexpected message: %+v", msg)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("message was not delivered")
+	}
+}
+
+func TestRedisBroker_RedeliversNackedMessages(t *testing.T) {
+	broker, _ := newTestRedisBroker(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_ = broker.Publish(ctx, "recipes", Message{Data: []byte("flaky")})
+
+	var mu sync.Mutex
+	var attempts []int
+	done := make(chan struct{})
+	go broker.Subscribe(ctx, "recipes", "analyzer", func(_ context.Context, msg Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts = append(attempts, msg.Attempt)
+		if len(attempts) == 1 {
+			return errors.New("try again")
+		}
+		close(done)
+		return nil
+	})
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("message was not redelivered")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(attempts) != 2 || attempts[0] != 1 || attempts[1] != 2 {
+		t.Fatalf("expected attempts [1 2], got %v", attempts)
+	}
+}
+
+func TestRedisBroker_DeadLettersAfterMaxAttempts(t *testing.T) {
+	broker, mr := newTestRedisBroker(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_ = broker.Publish(ctx, "recipes", Message{Data: []byte("poison")})
+	go broker.Subscribe(ctx, "recipes", "analyzer", func(context.Context, Message) error {
+		return errors.New("always fails")
+	})
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if entries, err := mr.Stream("recipes:dead"); err == nil && len(entries) == 1 {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("message was not dead-lettered")
+}
+
+func TestRedisBroker_KeepsOrderWithinAnOrderingKey(t *testing.T) {
+	broker, _ := newTestRedisBroker(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for _, m := range []Message{
+		{Data: []byte("a1"), OrderingKey: "user-1"},
+		{Data: []byte("a2"), OrderingKey: "user-1"},
+		{Data: []byte("b1"), OrderingKey: "user-2"},
+	} {
+		if err := broker.Publish(ctx, "entries", m); err != nil {
+			t.Fatalf("publish failed: %v", err)
+		}
+	}
+
+	var mu sync.Mutex
+	var handled []string
+	failed := false
+	done := make(chan struct{})
+	go broker.Subscribe(ctx, "entries", "search", func(_ context.Context, msg Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if string(msg.Data) == "a1" && !failed {
+			failed = true
+			return errors.New("try again")
+		}
+		handled = append(handled, string(msg.Data)+"/"+msg.OrderingKey)
+		if len(handled) == 3 {
+			close(done)
+		}
+		return nil
+	})
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("messages were not all handled")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{"b1/user-2", "a1/user-1", "a2/user-1"}
+	for i := range want {
+		if handled[i] != want[i] {
+			t.Fatalf("expected a2 to wait for the retried a1, got %v", handled)
+		}
+	}
+}
+
+func TestNewFromEnv(t *testing.T) {
+	t.Setenv("GCP_PROJECT_ID", "")
+	t.Setenv("MESSAGING_BACKEND", "")
+	broker, err := NewFromEnv(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := broker.(*MemoryBroker); !ok {
+		t.Fatalf("expected the in-memory broker by default, got %T", broker)
+	}
+
+	t.Setenv("MESSAGING_BACKEND", "redis")
+	t.Setenv("MESSAGING_REDIS_URL", "redis://localhost:6379/0")
+	broker, err = NewFromEnv(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := broker.(*RedisBroker); !ok {
+		t.Fatalf("expected the Redis broker, got %T", broker)
+	}
+	broker.Close()
+
+	t.Setenv("MESSAGING_BACKEND", "kafka")
+	if _, err := NewFromEnv(context.Background()); err == nil {
+		t.Fatal("expected an error for an unknown backend")
+	}
+}
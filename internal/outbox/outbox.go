@@ -0,0 +1,174 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+// Event is a message to publish once the transaction that wrote it commits.
+type Event struct {
+	Topic string
+	Type  string
+	// OrderingKey groups events that must be published in the order they were written.
+	OrderingKey string
+	Payload     any
+}
+
+// UserKey is the ordering key for events about one user.
+func UserKey(userID int) string {
+	return "user-" + strconv.Itoa(userID)
+}
+
+// Write adds event to the outbox within tx, so it is published if and only if tx commits.
+func Write(ctx context.Context, tx *sql.Tx, event Event) error {
+	payload, err := json.Marshal(event.Payload)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `
+	INSERT INTO outbox (topic, event_type, ordering_key, payload)
+	VALUES ($1, $2, $3, $4);
+	`, event.Topic, event.Type, event.OrderingKey, payload)
+	return err
+}
+
+// Record is a stored outbox row waiting to be published.
+type Record struct {
+	ID          int64
+	Topic       string
+	Type        string
+	OrderingKey string
+	Payload     []byte
+	Attempts    int
+	// Due is false while the row waits out the backoff after a failed attempt.
+	Due bool
+}
+
+// Store is what the relay needs from the outbox table.
+type Store interface {
+	// Lock reports whether this process may relay; only one relay publishes at a time.
+	Lock(ctx context.Context) (bool, error)
+	// Pending returns up to limit undelivered rows in id order within each
+	// ordering key. Keys share the batch fairly, so one busy or failing key does
+	// not hold back the others.
+	Pending(ctx context.Context, limit int) ([]Record, error)
+	MarkDelivered(ctx context.Context, id int64) error
+	MarkFailed(ctx context.Context, id int64, cause error, retryAt time.Time) error
+	// MarkDeadLettered gives up on a row, so the later rows of its key can go ahead.
+	MarkDeadLettered(ctx context.Context, id int64, cause error) error
+}
+
+// outboxLockID is the Postgres advisory lock held by the active relay.
+const outboxLockID = 7_340_035
+
+// PostgresStore reads the outbox table. It holds a dedicated connection while it
+// owns the relay lock, since advisory locks belong to a session.
+type PostgresStore struct {
+	db   *sql.DB
+	conn *sql.Conn
+}
+
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+func (s *PostgresStore) Lock(ctx context.Context) (bool, error) {
+	if s.conn != nil {
+		if err := s.conn.PingContext(ctx); err == nil {
+			return true, nil
+		}
+		// The session and its lock are gone; try again on a new connection.
+		s.conn.Close()
+		s.conn = nil
+	}
+
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return false, err
+	}
+	var locked bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, outboxLockID).Scan(&locked); err != nil || !locked {
+		conn.Close()
+		return false, err
+	}
+	s.conn = conn
+	return true, nil
+}
+
+// Pending takes the keys whose oldest waiting row is due, oldest first, and then
+// their waiting rows round-robin: every key's first row, then every key's second,
+// and so on. Keys whose oldest row is backing off are left out entirely.
+func (s *PostgresStore) Pending(ctx context.Context, limit int) ([]Record, error) {
+	rows, err := s.conn.QueryContext(ctx, `
+	WITH heads AS (
+		SELECT DISTINCT ON (ordering_key) ordering_key, id, next_attempt_at
+		FROM outbox
+		WHERE delivered_at IS NULL AND dead_lettered_at IS NULL
+		ORDER BY ordering_key, id
+	), ready AS (
+		SELECT ordering_key FROM heads
+		WHERE next_attempt_at <= NOW()
+		ORDER BY id
+		LIMIT $1
+	)
+	SELECT o.id, o.topic, o.event_type, o.ordering_key, o.payload, o.attempts, o.next_attempt_at <= NOW()
+	FROM ready r
+	CROSS JOIN LATERAL (
+		SELECT *, row_number() OVER (ORDER BY id) AS position
+		FROM outbox
+		WHERE ordering_key = r.ordering_key AND delivered_at IS NULL AND dead_lettered_at IS NULL
+		ORDER BY id
+		LIMIT $1
+	) o
+	ORDER BY o.position, o.id
+	LIMIT $1;
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []Record
+	for rows.Next() {
+		var r Record
+		if err := rows.Scan(&r.ID, &r.Topic, &r.Type, &r.OrderingKey, &r.Payload, &r.Attempts, &r.Due); err != nil {
+			return nil, err
+		}
+		records = append(records, r)
+	}
+	return records, rows.Err()
+}
+
+func (s *PostgresStore) MarkDelivered(ctx context.Context, id int64) error {
+	_, err := s.conn.ExecContext(ctx, `UPDATE outbox SET delivered_at = NOW() WHERE id = $1`, id)
+	return err
+}
+
+func (s *PostgresStore) MarkFailed(ctx context.Context, id int64, cause error, retryAt time.Time) error {
+	_, err := s.conn.ExecContext(ctx, `
+	UPDATE outbox SET attempts = attempts + 1, last_error = $2, next_attempt_at = $3
+	WHERE id = $1;
+	`, id, cause.Error(), retryAt)
+	return err
+}
+
+func (s *PostgresStore) MarkDeadLettered(ctx context.Context, id int64, cause error) error {
+	_, err := s.conn.ExecContext(ctx, `
+	UPDATE outbox SET attempts = attempts + 1, last_error = $2, dead_lettered_at = NOW()
+	WHERE id = $1;
+	`, id, cause.Error())
+	return err
+}
+
+// Close releases the relay lock.
+func (s *PostgresStore) Close() error {
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
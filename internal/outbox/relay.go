@@ -0,0 +1,132 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/coloradocollective/go-capstone-starter/internal/events"
+	"github.com/coloradocollective/go-capstone-starter/internal/messaging"
+)
+
+// Relay publishes pending outbox rows. Delivery is at-least-once: a row is marked
+// delivered only after Publish succeeds, so a crash in between publishes it again.
+// Consumers can deduplicate on the outbox_id attribute. Rows holding an event
+// envelope also carry its attributes, as events.Publish would set them. Rows sharing an ordering key
+// are published strictly in id order; a failing row holds back the later rows of its
+// key, while other keys carry on. After MaxAttempts failures the row is dead-lettered
+// and its key moves on; dead-lettered rows keep their last error and can be
+// re-emitted with cmd/replay.
+type Relay struct {
+	BatchSize   int
+	Interval    time.Duration
+	MinBackoff  time.Duration
+	MaxBackoff  time.Duration
+	MaxAttempts int
+
+	store     Store
+	publisher messaging.Publisher
+}
+
+// DefaultMaxAttempts is how often the relay tries a row before dead-lettering it;
+// with the default backoff that is about half an hour.
+const DefaultMaxAttempts = 12
+
+func NewRelay(store Store, publisher messaging.Publisher) *Relay {
+	return &Relay{
+		BatchSize:   100,
+		Interval:    time.Second,
+		MinBackoff:  time.Second,
+		MaxBackoff:  5 * time.Minute,
+		MaxAttempts: DefaultMaxAttempts,
+		store:       store,
+		publisher:   publisher,
+	}
+}
+
+// Run relays until ctx is cancelled.
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+	for {
+		if _, err := r.RelayOnce(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("Outbox relay failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RelayOnce publishes one batch of pending rows and returns how many were delivered.
+func (r *Relay) RelayOnce(ctx context.Context) (int, error) {
+	locked, err := r.store.Lock(ctx)
+	if err != nil || !locked {
+		return 0, err
+	}
+
+	records, err := r.store.Pending(ctx, r.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	blocked := make(map[string]bool)
+	for _, record := range records {
+		if blocked[record.OrderingKey] {
+			continue
+		}
+		if !record.Due {
+			blocked[record.OrderingKey] = true
+			continue
+		}
+
+		msg := messaging.Message{Data: record.Payload, Attributes: attributes(record), OrderingKey: record.OrderingKey}
+		if err := r.publisher.Publish(ctx, record.Topic, msg); err != nil {
+			blocked[record.OrderingKey] = true
+			log.Printf("Failed to publish outbox row %d (attempt %d): %v", record.ID, record.Attempts+1, err)
+			if record.Attempts+1 >= r.MaxAttempts {
+				log.Printf("Dead-lettering outbox row %d of %s after %d attempts", record.ID, record.OrderingKey, record.Attempts+1)
+				if err := r.store.MarkDeadLettered(ctx, record.ID, err); err != nil {
+					return delivered, err
+				}
+				continue
+			}
+			if err := r.store.MarkFailed(ctx, record.ID, err, time.Now().Add(r.backoff(record.Attempts))); err != nil {
+				return delivered, err
+			}
+			continue
+		}
+		if err := r.store.MarkDelivered(ctx, record.ID); err != nil {
+			return delivered, err
+		}
+		delivered++
+	}
+	return delivered, nil
+}
+
+// attributes are the message attributes of record: those of its event envelope,
+// if it holds one, plus its type and outbox ID.
+func attributes(record Record) map[string]string {
+	attrs := map[string]string{}
+	var env events.Envelope
+	if err := json.Unmarshal(record.Payload, &env); err == nil && env.Type != "" {
+		attrs = events.Attributes(env)
+	}
+	attrs["type"] = record.Type
+	attrs["outbox_id"] = strconv.FormatInt(record.ID, 10)
+	return attrs
+}
+
+// backoff doubles the wait after every failed attempt, up to MaxBackoff.
+func (r *Relay) backoff(attempts int) time.Duration {
+	wait := r.MinBackoff
+	for i := 0; i < attempts && wait < r.MaxBackoff; i++ {
+		wait *= 2
+	}
+	return min(wait, r.MaxBackoff)
+}
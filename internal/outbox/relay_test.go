@@ -0,0 +1,183 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"maps"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/coloradocollective/go-capstone-starter/internal/events"
+	"github.com/coloradocollective/go-capstone-starter/internal/messaging"
+)
+
+type fakeStore struct {
+	records      []Record
+	delivered    []int64
+	deadLettered []int64
+}
+
+func (s *fakeStore) Lock(context.Context) (bool, error) { return true, nil }
+
+func (s *fakeStore) Pending(_ context.Context, limit int) ([]Record, error) {
+	var pending []Record
+	for _, r := range s.records {
+		if !s.isDelivered(r.ID) && !slices.Contains(s.deadLettered, r.ID) && len(pending) < limit {
+			pending = append(pending, r)
+		}
+	}
+	return pending, nil
+}
+
+func (s *fakeStore) isDelivered(id int64) bool {
+	for _, d := range s.delivered {
+		if d == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *fakeStore) MarkDelivered(_ context.Context, id int64) error {
+	s.delivered = append(s.delivered, id)
+	return nil
+}
+
+func (s *fakeStore) MarkFailed(_ context.Context, id int64, _ error, _ time.Time) error {
+	for i := range s.records {
+		if s.records[i].ID == id {
+			s.records[i].Attempts++
+		}
+	}
+	return nil
+}
+
+func (s *fakeStore) MarkDeadLettered(_ context.Context, id int64, _ error) error {
+	s.deadLettered = append(s.deadLettered, id)
+	return nil
+}
+
+type fakePublisher struct {
+	failing    map[string]bool
+	published  []string
+	keys       []string
+	attributes []map[string]string
+}
+
+func (p *fakePublisher) Publish(_ context.Context, _ string, msg messaging.Message) error {
+	if p.failing[string(msg.Data)] {
+		return errors.New("broker unavailable")
+	}
+	p.published = append(p.published, string(msg.Data))
+	p.keys = append(p.keys, msg.OrderingKey)
+	p.attributes = append(p.attributes, msg.Attributes)
+	return nil
+}
+
+func (p *fakePublisher) Close() error { return nil }
+
+func TestRelay_FailureHoldsBackOnlyItsOrderingKey(t *testing.T) {
+	store := &fakeStore{records: []Record{
+		{ID: 1, OrderingKey: "user-1", Payload: []byte("a1"), Due: true},
+		{ID: 2, OrderingKey: "user-2", Payload: []byte("b1"), Due: true},
+		{ID: 3, OrderingKey: "user-1", Payload: []byte("a2"), Due: true},
+		{ID: 4, OrderingKey: "user-2", Payload: []byte("b2"), Due: true},
+	}}
+	publisher := &fakePublisher{failing: map[string]bool{"a1": true}}
+	relay := NewRelay(store, publisher)
+
+	delivered, err := relay.RelayOnce(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if delivered != 2 || len(publisher.published) != 2 || publisher.published[0] != "b1" || publisher.published[1] != "b2" {
+		t.Fatalf("expected only user-2's events, got %v", publisher.published)
+	}
+	if store.records[0].Attempts != 1 {
+		t.Fatalf("expected the failed attempt to be recorded, got %d", store.records[0].Attempts)
+	}
+
+	publisher.failing = nil
+	if _, err := relay.RelayOnce(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"b1", "b2", "a1", "a2"}
+	for i, payload := range want {
+		if publisher.published[i] != payload {
+			t.Fatalf("expected %v, got %v", want, publisher.published)
+		}
+	}
+	if keys := []string{"user-2", "user-2", "user-1", "user-1"}; !slices.Equal(publisher.keys, keys) {
+		t.Fatalf("expected the rows' ordering keys %v, got %v", keys, publisher.keys)
+	}
+}
+
+func TestRelay_WaitsForBackoffBeforeLaterEventsOfTheSameKey(t *testing.T) {
+	store := &fakeStore{records: []Record{
+		{ID: 1, OrderingKey: "user-1", Payload: []byte("a1"), Attempts: 1, Due: false},
+		{ID: 2, OrderingKey: "user-1", Payload: []byte("a2"), Due: true},
+	}}
+	publisher := &fakePublisher{}
+
+	delivered, err := NewRelay(store, publisher).RelayOnce(context.Background())
+	if err != nil || delivered != 0 {
+		t.Fatalf("expected nothing to be delivered, got %d, %v", delivered, err)
+	}
+}
+
+func TestRelay_DeadLettersAfterMaxAttempts(t *testing.T) {
+	store := &fakeStore{records: []Record{
+		{ID: 1, OrderingKey: "user-1", Payload: []byte("poison"), Attempts: 2, Due: true},
+		{ID: 2, OrderingKey: "user-1", Payload: []byte("a2"), Due: true},
+	}}
+	publisher := &fakePublisher{failing: map[string]bool{"poison": true}}
+	relay := NewRelay(store, publisher)
+	relay.MaxAttempts = 3
+
+	if _, err := relay.RelayOnce(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(store.deadLettered) != 1 || store.deadLettered[0] != 1 {
+		t.Fatalf("expected the poison row to be dead-lettered, got %v", store.deadLettered)
+	}
+	if _, err := relay.RelayOnce(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(publisher.published) != 1 || publisher.published[0] != "a2" {
+		t.Fatalf("expected the key to move on past the dead letter, got %v", publisher.published)
+	}
+}
+
+func TestRelay_PublishesTheEnvelopeAttributes(t *testing.T) {
+	env, err := events.New("food_entry.deleted", 1, 4, map[string]int{"entry_id": 9})
+	if err != nil {
+		t.Fatalf("failed to build event: %v", err)
+	}
+	payload, _ := json.Marshal(env)
+	store := &fakeStore{records: []Record{{ID: 5, Type: env.Type, OrderingKey: "user-4", Payload: payload, Due: true}}}
+	publisher := &fakePublisher{}
+
+	if _, err := NewRelay(store, publisher).RelayOnce(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := events.Attributes(env)
+	want["outbox_id"] = "5"
+	if len(publisher.attributes) != 1 || !maps.Equal(publisher.attributes[0], want) {
+		t.Fatalf("expected attributes %v, got %v", want, publisher.attributes)
+	}
+}
+
+func TestRelay_Backoff(t *testing.T) {
+	relay := NewRelay(nil, nil)
+	relay.MinBackoff = time.Second
+	relay.MaxBackoff = 10 * time.Second
+
+	cases := map[int]time.Duration{0: time.Second, 1: 2 * time.Second, 3: 8 * time.Second, 4: 10 * time.Second, 50: 10 * time.Second}
+	for attempts, want := range cases {
+		if got := relay.backoff(attempts); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package profile
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/coloradocollective/go-capstone-starter/internal/energy"
+)
+
+// Body is the body data of a user's profile. Every field is optional.
+type Body struct {
+	Sex            string   `json:"sex,omitempty"`
+	BirthDate      string   `json:"birth_date,omitempty"`
+	HeightCm       *float64 `json:"height_cm,omitempty"`
+	WeightKg       *float64 `json:"weight_kg,omitempty"`
+	BodyFatPercent *float64 `json:"body_fat_percent,omitempty"`
+	ActivityLevel  string   `json:"activity_level,omitempty"`
+}
+
+// Validate checks each field that is set against plausible human ranges.
+func (b Body) Validate(today time.Time) error {
+	if b.Sex != "" && b.Sex != energy.Female && b.Sex != energy.Male {
+		return errors.New("sex must be female or male")
+	}
+	if b.BirthDate != "" {
+		birth, err := time.Parse(time.DateOnly, b.BirthDate)
+		if err != nil {
+			return errors.New("birth_date must be a date such as 1990-05-01")
+		}
+		if age := age(birth, today); age < 13 || age > 120 {
+			return errors.New("age must be between 13 and 120")
+		}
+	}
+	if b.HeightCm != nil && (*b.HeightCm < 50 || *b.HeightCm > 275) {
+		return errors.New("height_cm must be between 50 and 275")
+	}
+	if b.WeightKg != nil && (*b.WeightKg < 20 || *b.WeightKg > 500) {
+		return errors.New("weight_kg must be between 20 and 500")
+	}
+	if b.BodyFatPercent != nil && (*b.BodyFatPercent < 2 || *b.BodyFatPercent > 70) {
+		return errors.New("body_fat_percent must be between 2 and 70")
+	}
+	if _, ok := energy.ActivityFactors[b.ActivityLevel]; b.ActivityLevel != "" && !ok {
+		return errors.New("activity_level must be sedentary, light, moderate, active or very_active")
+	}
+	return nil
+}
+
+// age is the number of birthdays between birth and today.
+func age(birth, today time.Time) int {
+	years := today.Year() - birth.Year()
+	if today.Month() < birth.Month() || (today.Month() == birth.Month() && today.Day() < birth.Day()) {
+		years--
+	}
+	return years
+}
+
+// ErrIncompleteBody is returned when the profile lacks data the equations need.
+var ErrIncompleteBody = errors.New("profile is incomplete")
+
+// Person converts the profile for the energy equations, listing any missing fields.
+func (b Body) Person(today time.Time) (energy.Person, error) {
+	var missing []string
+	if b.Sex == "" {
+		missing = append(missing, "sex")
+	}
+	if b.BirthDate == "" {
+		missing = append(missing, "birth_date")
+	}
+	if b.HeightCm == nil {
+		missing = append(missing, "height_cm")
+	}
+	if b.WeightKg == nil {
+		missing = append(missing, "weight_kg")
+	}
+	if b.ActivityLevel == "" {
+		missing = append(missing, "activity_level")
+	}
+	if len(missing) > 0 {
+		return energy.Person{}, fmt.Errorf("%w: set %s", ErrIncompleteBody, strings.Join(missing, ", "))
+	}
+	birth, err := time.Parse(time.DateOnly, b.BirthDate)
+	if err != nil {
+		return energy.Person{}, err
+	}
+	return energy.Person{
+		Sex:            b.Sex,
+		Age:            age(birth, today),
+		HeightCm:       *b.HeightCm,
+		WeightKg:       *b.WeightKg,
+		BodyFatPercent: b.BodyFatPercent,
+		ActivityLevel:  b.ActivityLevel,
+	}, nil
+}
+
+// Body returns sql.ErrNoRows for unknown users.
+func (s *PostgresStore) Body(ctx context.Context, userID int) (Body, error) {
+	var b Body
+	var sex, birthDate, activity sql.NullString
+	var height, weight, bodyFat sql.NullFloat64
+	err := s.db.QueryRowContext(ctx, `
+	SELECT sex, to_char(birth_date, 'YYYY-MM-DD'), height_cm, weight_kg, body_fat_percent, activity_level
+	FROM users WHERE id = $1;
+	`, userID).Scan(&sex, &birthDate, &height, &weight, &bodyFat, &activity)
+	if err != nil {
+		return Body{}, err
+	}
+	b.Sex, b.BirthDate, b.ActivityLevel = sex.String, birthDate.String, activity.String
+	b.HeightCm, b.WeightKg, b.BodyFatPercent = floatOrNil(height), floatOrNil(weight), floatOrNil(bodyFat)
+	return b, nil
+}
+
+func floatOrNil(v sql.NullFloat64) *float64 {
+	if !v.Valid {
+		return nil
+	}
+	return &v.Float64
+}
+
+func nullIfEmpty(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// SetBody replaces the body data; fields left empty are cleared.
+func (s *PostgresStore) SetBody(ctx context.Context, userID int, b Body) error {
+	result, err := s.db.ExecContext(ctx, `
+	UPDATE users SET sex = $2, birth_date = $3, height_cm = $4, weight_kg = $5, body_fat_percent = $6, activity_level = $7
+	WHERE id = $1;
+	`, userID, nullIfEmpty(b.Sex), nullIfEmpty(b.BirthDate), b.HeightCm, b.WeightKg, b.BodyFatPercent, nullIfEmpty(b.ActivityLevel))
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("user %d: %w", userID, sql.ErrNoRows)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package profile
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/coloradocollective/go-capstone-starter/internal/auth"
+)
+
+type handler struct {
+	store Store
+}
+
+// Handlers registers the profile endpoints.
+func Handlers(db *sql.DB, authenticator auth.Authenticator) func(mux *http.ServeMux) {
+	return routes(NewPostgresStore(db), authenticator)
+}
+
+func routes(store Store, authenticator auth.Authenticator) func(mux *http.ServeMux) {
+	h := &handler{store: store}
+	return func(mux *http.ServeMux) {
+		mux.HandleFunc("GET /api/profile/time-zone", authenticator.Require(h.getTimeZone))
+		mux.HandleFunc("PUT /api/profile/time-zone", authenticator.Require(h.setTimeZone))
+		mux.HandleFunc("GET /api/profile/body", authenticator.Require(h.getBody))
+		mux.HandleFunc("PUT /api/profile/body", authenticator.Require(h.setBody))
+	}
+}
+
+type timeZoneBody struct {
+	TimeZone string `json:"time_zone"`
+}
+
+func (h *handler) getTimeZone(w http.ResponseWriter, r *http.Request, userID int) {
+	loc, err := h.store.Location(r.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to load time zone for user %d: %v", userID, err)
+		http.Error(w, "Failed to load time zone", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, timeZoneBody{TimeZone: loc.String()})
+}
+
+func (h *handler) setTimeZone(w http.ResponseWriter, r *http.Request, userID int) {
+	var body timeZoneBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	err := h.store.SetTimeZone(r.Context(), userID, body.TimeZone)
+	if errors.Is(err, ErrInvalidTimeZone) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to set time zone for user %d: %v", userID, err)
+		http.Error(w, "Failed to set time zone", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, body)
+}
+
+func (h *handler) getBody(w http.ResponseWriter, r *http.Request, userID int) {
+	body, err := h.store.Body(r.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to load profile for user %d: %v", userID, err)
+		http.Error(w, "Failed to load profile", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, body)
+}
+
+func (h *handler) setBody(w http.ResponseWriter, r *http.Request, userID int) {
+	var body Body
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := body.Validate(time.Now()); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err := h.store.SetBody(r.Context(), userID, body)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to save profile for user %d: %v", userID, err)
+		http.Error(w, "Failed to save profile", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, body)
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
@@ -0,0 +1,126 @@
+package profile
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coloradocollective/go-capstone-starter/internal/auth/authtest"
+)
+
+type memoryStore struct {
+	zones  map[int]string
+	bodies map[int]Body
+}
+
+func (s *memoryStore) Location(_ context.Context, userID int) (*time.Location, error) {
+	name, ok := s.zones[userID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return LoadTimeZone(name)
+}
+
+func (s *memoryStore) SetTimeZone(_ context.Context, userID int, name string) error {
+	if _, err := LoadTimeZone(name); err != nil {
+		return err
+	}
+	if _, ok := s.zones[userID]; !ok {
+		return sql.ErrNoRows
+	}
+	s.zones[userID] = name
+	return nil
+}
+
+func (s *memoryStore) Body(_ context.Context, userID int) (Body, error) {
+	if _, ok := s.zones[userID]; !ok {
+		return Body{}, sql.ErrNoRows
+	}
+	return s.bodies[userID], nil
+}
+
+func (s *memoryStore) SetBody(_ context.Context, userID int, body Body) error {
+	if _, ok := s.zones[userID]; !ok {
+		return sql.ErrNoRows
+	}
+	s.bodies[userID] = body
+	return nil
+}
+
+func TestTimeZone(t *testing.T) {
+	store := &memoryStore{zones: map[int]string{4: DefaultTimeZone}}
+	mux := http.NewServeMux()
+	routes(store, authtest.Auth)(mux)
+
+	rec := authtest.Request(t, mux, http.MethodPut, "/api/profile/time-zone", 4, `{"time_zone": "America/Denver"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = authtest.Request(t, mux, http.MethodGet, "/api/profile/time-zone", 4, "")
+	var body timeZoneBody
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if body.TimeZone != "America/Denver" {
+		t.Errorf("expected America/Denver, got %q", body.TimeZone)
+	}
+
+	for _, name := range []string{"", "Local", "Mars/Olympus_Mons", "MST7MDT/../UTC"} {
+		rec := authtest.Request(t, mux, http.MethodPut, "/api/profile/time-zone", 4, `{"time_zone": "`+name+`"}`)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%q: expected 400, got %d", name, rec.Code)
+		}
+	}
+	if rec := authtest.Request(t, mux, http.MethodGet, "/api/profile/time-zone", 9, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown user, got %d", rec.Code)
+	}
+}
+
+func TestBody(t *testing.T) {
+	store := &memoryStore{zones: map[int]string{4: DefaultTimeZone}, bodies: map[int]Body{}}
+	mux := http.NewServeMux()
+	routes(store, authtest.Auth)(mux)
+
+	body := `{"sex": "female", "birth_date": "1990-05-01", "height_cm": 165, "weight_kg": 60.5, "activity_level": "light"}`
+	if rec := authtest.Request(t, mux, http.MethodPut, "/api/profile/body", 4, body); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := store.bodies[4]; got.WeightKg == nil || *got.WeightKg != 60.5 || got.BodyFatPercent != nil {
+		t.Errorf("unexpected body %+v", got)
+	}
+
+	invalid := map[string]string{
+		"sex":      `{"sex": "other"}`,
+		"birth":    `{"birth_date": "2024-01-01"}`,
+		"height":   `{"height_cm": 1.65}`,
+		"activity": `{"activity_level": "couch"}`,
+	}
+	for name, body := range invalid {
+		if rec := authtest.Request(t, mux, http.MethodPut, "/api/profile/body", 4, body); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", name, rec.Code)
+		}
+	}
+}
+
+func TestBody_Person(t *testing.T) {
+	today := time.Date(2024, 4, 30, 0, 0, 0, 0, time.UTC)
+	height, weight := 165.0, 60.0
+	body := Body{Sex: "female", BirthDate: "1990-05-01", HeightCm: &height, ActivityLevel: "light"}
+
+	if _, err := body.Person(today); !errors.Is(err, ErrIncompleteBody) || !strings.Contains(err.Error(), "weight_kg") {
+		t.Errorf("expected weight_kg to be reported missing, got %v", err)
+	}
+	body.WeightKg = &weight
+	person, err := body.Person(today)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if person.Age != 33 {
+		t.Errorf("expected age 33 the day before the 34th birthday, got %d", person.Age)
+	}
+}
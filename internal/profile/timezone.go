@@ -0,0 +1,86 @@
+package profile
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// DefaultTimeZone is used for users who have not chosen one.
+const DefaultTimeZone = "UTC"
+
+// ErrInvalidTimeZone is returned for names that are not IANA time zones.
+var ErrInvalidTimeZone = errors.New("time_zone must be an IANA time zone such as America/Denver")
+
+// LoadTimeZone resolves an IANA time zone name. "Local" and the empty name are
+// rejected, as they would mean the server's zone rather than the user's.
+func LoadTimeZone(name string) (*time.Location, error) {
+	if name == "" || name == "Local" {
+		return nil, ErrInvalidTimeZone
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, ErrInvalidTimeZone
+	}
+	return loc, nil
+}
+
+// Locations looks up the time zone in which a user's days start and end.
+type Locations interface {
+	Location(ctx context.Context, userID int) (*time.Location, error)
+}
+
+// Store reads and writes profile settings.
+type Store interface {
+	Locations
+	SetTimeZone(ctx context.Context, userID int, name string) error
+	Body(ctx context.Context, userID int) (Body, error)
+	SetBody(ctx context.Context, userID int, body Body) error
+}
+
+type PostgresStore struct {
+	db *sql.DB
+}
+
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// Location returns sql.ErrNoRows for unknown users. SetTimeZone only stores names
+// that resolve, so a name that does not was written around it or dropped from the
+// time zone database; it is logged and falls back to UTC rather than failing every
+// day-based query.
+func (s *PostgresStore) Location(ctx context.Context, userID int) (*time.Location, error) {
+	var name string
+	err := s.db.QueryRowContext(ctx, `SELECT time_zone FROM users WHERE id = $1;`, userID).Scan(&name)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := LoadTimeZone(name)
+	if err != nil {
+		log.Printf("User %d has an unknown time zone %q; using %s", userID, name, DefaultTimeZone)
+		return time.UTC, nil
+	}
+	return loc, nil
+}
+
+func (s *PostgresStore) SetTimeZone(ctx context.Context, userID int, name string) error {
+	if _, err := LoadTimeZone(name); err != nil {
+		return err
+	}
+	result, err := s.db.ExecContext(ctx, `UPDATE users SET time_zone = $2 WHERE id = $1;`, userID, name)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("user %d: %w", userID, sql.ErrNoRows)
+	}
+	return nil
+}
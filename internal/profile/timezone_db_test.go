@@ -0,0 +1,43 @@
+package profile
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/coloradocollective/go-capstone-starter/internal/dbtest"
+)
+
+func TestPostgresStore_TimeZone(t *testing.T) {
+	db := dbtest.Open(t)
+	userID := dbtest.UserID(t, db)
+	store := NewPostgresStore(db)
+	ctx := context.Background()
+
+	if loc, err := store.Location(ctx, userID); err != nil || loc != time.UTC {
+		t.Fatalf("expected new users to default to UTC, got %v %v", loc, err)
+	}
+	if err := store.SetTimeZone(ctx, userID, "America/Denver"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if loc, err := store.Location(ctx, userID); err != nil || loc.String() != "America/Denver" {
+		t.Fatalf("expected America/Denver, got %v %v", loc, err)
+	}
+	if err := store.SetTimeZone(ctx, userID, "Mars/Olympus_Mons"); !errors.Is(err, ErrInvalidTimeZone) {
+		t.Fatalf("expected ErrInvalidTimeZone, got %v", err)
+	}
+
+	// A name written around SetTimeZone falls back instead of failing.
+	if _, err := db.Exec(`UPDATE users SET time_zone = 'Mars/Olympus_Mons' WHERE id = $1`, userID); err != nil {
+		t.Fatal(err)
+	}
+	if loc, err := store.Location(ctx, userID); err != nil || loc != time.UTC {
+		t.Fatalf("expected the fallback to UTC, got %v %v", loc, err)
+	}
+
+	if _, err := store.Location(ctx, -1); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("expected sql.ErrNoRows for an unknown user, got %v", err)
+	}
+}
@@ -0,0 +1,174 @@
+package recipes
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/coloradocollective/go-capstone-starter/internal/auth"
+	"github.com/coloradocollective/go-capstone-starter/internal/embeddings"
+)
+
+const (
+	defaultSimilarLimit = 10
+	maxSimilarLimit     = 50
+	// maxQueryLength keeps pasted text from being sent to the embeddings API.
+	maxQueryLength = 200
+	maxDietTags    = 10
+)
+
+type handler struct {
+	store    Store
+	embedder embeddings.Embedder
+	index    *similarIndex
+}
+
+// Handlers registers the recipe endpoints backed by Postgres. Recipes are
+// reference data and need no sign-in; free-text search embeds the query with a
+// metered API, so it does.
+func Handlers(db *sql.DB, embedder embeddings.Embedder, authenticator auth.Authenticator) func(mux *http.ServeMux) {
+	return routes(NewPostgresStore(db), embedder, authenticator)
+}
+
+func routes(store Store, embedder embeddings.Embedder, authenticator auth.Authenticator) func(mux *http.ServeMux) {
+	h := &handler{store: store, embedder: embedder, index: newSimilarIndex(store)}
+	return func(mux *http.ServeMux) {
+		mux.Handle("GET /api/recipes/similar", authenticator.Require(h.searchSimilar))
+		mux.HandleFunc("GET /api/recipes/{id}", h.getRecipe)
+		mux.HandleFunc("GET /api/recipes/{id}/similar", h.similarToRecipe)
+	}
+}
+
+// getRecipe returns a recipe with its nutrition and the provenance of that nutrition.
+func (h *handler) getRecipe(w http.ResponseWriter, r *http.Request) {
+	recipeID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid recipe id", http.StatusBadRequest)
+		return
+	}
+
+	recipe, err := h.store.Recipe(r.Context(), recipeID)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Recipe not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to load recipe %d: %v", recipeID, err)
+		http.Error(w, "Failed to load recipe", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, recipe)
+}
+
+// similarToRecipe returns the recipes closest to an existing recipe's stored
+// embedding, limited to those with every tag in diet.
+func (h *handler) similarToRecipe(w http.ResponseWriter, r *http.Request) {
+	recipeID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid recipe id", http.StatusBadRequest)
+		return
+	}
+	limit, err := parseLimit(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	diet, err := parseDiet(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	target, err := h.store.RecipeEmbedding(r.Context(), recipeID)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Recipe has no embedding yet", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to load embedding for recipe %d: %v", recipeID, err)
+		http.Error(w, "Failed to load recipe", http.StatusInternalServerError)
+		return
+	}
+
+	candidates, err := h.index.candidates(r.Context(), target.Model)
+	if err != nil {
+		log.Printf("Failed to load embeddings: %v", err)
+		http.Error(w, "Failed to load recipes", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, RankSimilar(target.Vector, WithDietTags(candidates, diet), recipeID, limit))
+}
+
+// searchSimilar embeds the free-text query in q and returns the closest recipes,
+// limited to those with every tag in diet.
+func (h *handler) searchSimilar(w http.ResponseWriter, r *http.Request, _ int) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" || len(query) > maxQueryLength {
+		http.Error(w, "q is required and must be at most 200 characters", http.StatusBadRequest)
+		return
+	}
+	limit, err := parseLimit(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	diet, err := parseDiet(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.embedder.Embed(r.Context(), query)
+	if err != nil {
+		log.Printf("Failed to embed query: %v", err)
+		http.Error(w, "Failed to embed query", http.StatusBadGateway)
+		return
+	}
+
+	candidates, err := h.index.candidates(r.Context(), result.Model)
+	if err != nil {
+		log.Printf("Failed to load embeddings: %v", err)
+		http.Error(w, "Failed to load recipes", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, RankSimilar(result.Vector, WithDietTags(candidates, diet), 0, limit))
+}
+
+func parseLimit(r *http.Request) (int, error) {
+	raw := r.URL.Query().Get("limit")
+	if raw == "" {
+		return defaultSimilarLimit, nil
+	}
+	limit, err := strconv.Atoi(raw)
+	if err != nil || limit < 1 || limit > maxSimilarLimit {
+		return 0, errors.New("limit must be between 1 and 50")
+	}
+	return limit, nil
+}
+
+// parseDiet reads diet, a comma-separated list of diet tags such as
+// "vegan,gluten-free" that every result must have.
+func parseDiet(r *http.Request) ([]string, error) {
+	var diet []string
+	for _, tag := range strings.Split(r.URL.Query().Get("diet"), ",") {
+		if tag = strings.ToLower(strings.TrimSpace(tag)); tag != "" && !slices.Contains(diet, tag) {
+			diet = append(diet, tag)
+		}
+	}
+	if len(diet) > maxDietTags {
+		return nil, errors.New("diet may name at most 10 tags")
+	}
+	return diet, nil
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
@@ -0,0 +1,247 @@
+package recipes
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/coloradocollective/go-capstone-starter/internal/auth/authtest"
+	"github.com/coloradocollective/go-capstone-starter/internal/embeddings"
+)
+
+type memoryStore struct {
+	recipes    map[int]Recipe
+	embeddings []StoredEmbedding
+	loads      int
+}
+
+func (s *memoryStore) Recipe(_ context.Context, recipeID int) (Recipe, error) {
+	recipe, ok := s.recipes[recipeID]
+	if !ok {
+		return Recipe{}, sql.ErrNoRows
+	}
+	return recipe, nil
+}
+
+func (s *memoryStore) RecipeEmbedding(_ context.Context, recipeID int) (StoredEmbedding, error) {
+	for _, e := range s.embeddings {
+		if e.RecipeID == recipeID {
+			return e, nil
+		}
+	}
+	return StoredEmbedding{}, sql.ErrNoRows
+}
+
+func (s *memoryStore) Embeddings(_ context.Context, model string) ([]StoredEmbedding, error) {
+	s.loads++
+	var matching []StoredEmbedding
+	for _, e := range s.embeddings {
+		if e.Model == model {
+			matching = append(matching, e)
+		}
+	}
+	return matching, nil
+}
+
+// EmbeddingsVersion counts the stored embeddings, which is enough for tests that
+// only ever add them.
+func (s *memoryStore) EmbeddingsVersion(_ context.Context, model string) (string, error) {
+	return fmt.Sprint(len(s.embeddings)), nil
+}
+
+func newTestServer(t *testing.T) *http.ServeMux {
+	t.Helper()
+	embedder := embeddings.NewHashEmbedder(embeddings.DefaultHashDimensions)
+	store := &memoryStore{recipes: map[int]Recipe{
+		1: {ID: 1, Name: "Turkey Chili", DietTags: []string{"high-protein"}, Nutrition: &Nutrition{
+			Calories:   320,
+			Provenance: Provenance{Source: "ensemble", Estimator: "gpt-4o-mini,llama3", PromptVersion: "nutrition-v1"},
+		}},
+	}}
+	tags := map[int][]string{1: {"high-protein"}, 2: {"high-protein", "low-carb"}, 3: {"vegetarian"}}
+	for id, text := range map[int]string{
+		1: "Turkey Chili\nground turkey\nkidney beans\ndiced tomatoes\nchili powder",
+		2: "Beef Chili\nground beef\nkidney beans\ndiced tomatoes\nchili powder",
+		3: "Chocolate Cake\nflour\nsugar\ncocoa powder\neggs",
+	} {
+		result, err := embedder.Embed(context.Background(), text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		store.embeddings = append(store.embeddings, StoredEmbedding{RecipeID: id, Name: text, DietTags: tags[id], Model: result.Model, Vector: result.Vector})
+	}
+
+	mux := http.NewServeMux()
+	routes(store, embedder, authtest.Auth)(mux)
+	return mux
+}
+
+func TestSimilarToRecipe(t *testing.T) {
+	mux := newTestServer(t)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/recipes/1/similar?limit=2", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var results []SimilarRecipe
+	if err := json.Unmarshal(rec.Body.Bytes(), &results); err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].ID != 2 {
+		t.Fatalf("expected beef chili first and the recipe itself excluded, got %+v", results)
+	}
+}
+
+func TestSimilarToRecipe_FiltersByDiet(t *testing.T) {
+	mux := newTestServer(t)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/recipes/1/similar?diet=vegetarian", nil))
+	var results []SimilarRecipe
+	if err := json.Unmarshal(rec.Body.Bytes(), &results); err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].ID != 3 || !slices.Equal(results[0].DietTags, []string{"vegetarian"}) {
+		t.Fatalf("expected only the vegetarian cake with its tags, got %+v", results)
+	}
+
+	rec = authtest.Request(t, mux, http.MethodGet, "/api/recipes/similar?q=chili&diet=Low-Carb,+high-protein", 4, "")
+	results = nil
+	if err := json.Unmarshal(rec.Body.Bytes(), &results); err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].ID != 2 {
+		t.Fatalf("expected only the recipe with both tags, got %+v", results)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/recipes/1/similar?diet=a,b,c,d,e,f,g,h,i,j,k", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for too many diet tags, got %d", rec.Code)
+	}
+}
+
+func TestSimilarToRecipe_NoEmbedding(t *testing.T) {
+	mux := newTestServer(t)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/recipes/99/similar", nil))
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rec.Code)
+	}
+}
+
+func TestSearchSimilar(t *testing.T) {
+	mux := newTestServer(t)
+
+	rec := authtest.Request(t, mux, http.MethodGet, "/api/recipes/similar?q=chocolate+cocoa+cake", 4, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var results []SimilarRecipe
+	if err := json.Unmarshal(rec.Body.Bytes(), &results); err != nil {
+		t.Fatal(err)
+	}
+	if len(results) == 0 || results[0].ID != 3 {
+		t.Fatalf("expected chocolate cake first, got %+v", results)
+	}
+
+	cases := map[string]struct {
+		query  string
+		userID int
+		code   int
+	}{
+		"signed out": {"chocolate", 0, http.StatusUnauthorized},
+		"missing":    {"", 4, http.StatusBadRequest},
+		"too long":   {strings.Repeat("cake", 51), 4, http.StatusBadRequest},
+	}
+	for name, c := range cases {
+		if rec := authtest.Request(t, mux, http.MethodGet, "/api/recipes/similar?q="+c.query, c.userID, ""); rec.Code != c.code {
+			t.Errorf("%s: expected %d, got %d", name, c.code, rec.Code)
+		}
+	}
+}
+
+func TestGetRecipe_IncludesProvenance(t *testing.T) {
+	mux := newTestServer(t)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/recipes/1", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var recipe Recipe
+	if err := json.Unmarshal(rec.Body.Bytes(), &recipe); err != nil {
+		t.Fatal(err)
+	}
+	if recipe.Nutrition == nil || recipe.Nutrition.Provenance.Source != "ensemble" || recipe.Nutrition.Provenance.PromptVersion != "nutrition-v1" {
+		t.Fatalf("expected provenance in response, got %+v", recipe.Nutrition)
+	}
+	if !slices.Equal(recipe.DietTags, []string{"high-protein"}) {
+		t.Fatalf("expected the diet tags in response, got %v", recipe.DietTags)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/recipes/42", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown recipe, got %d", rec.Code)
+	}
+}
+
+func TestSimilarIndex_ReloadsOnlyWhenEmbeddingsChange(t *testing.T) {
+	embedder := embeddings.NewHashEmbedder(embeddings.DefaultHashDimensions)
+	store := &memoryStore{}
+	add := func(id int, text string) {
+		result, err := embedder.Embed(context.Background(), text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		store.embeddings = append(store.embeddings, StoredEmbedding{RecipeID: id, Name: text, Model: result.Model, Vector: result.Vector})
+	}
+	add(1, "Turkey Chili")
+	add(2, "Beef Chili")
+	mux := http.NewServeMux()
+	routes(store, embedder, authtest.Auth)(mux)
+
+	search := func() []SimilarRecipe {
+		rec := authtest.Request(t, mux, http.MethodGet, "/api/recipes/similar?q=chili", 4, "")
+		var results []SimilarRecipe
+		if err := json.Unmarshal(rec.Body.Bytes(), &results); err != nil {
+			t.Fatal(err)
+		}
+		return results
+	}
+	search()
+	search()
+	if store.loads != 1 {
+		t.Fatalf("expected the embeddings to be loaded once, got %d loads", store.loads)
+	}
+	add(3, "Chicken Chili")
+	if results := search(); len(results) != 3 || store.loads != 2 {
+		t.Fatalf("expected a reload that finds the new recipe, got %d loads and %+v", store.loads, results)
+	}
+}
+
+func TestRankSimilar_KeepsBestLimit(t *testing.T) {
+	candidates := []StoredEmbedding{
+		{RecipeID: 1, Vector: []float64{1, 0}},
+		{RecipeID: 2, Vector: []float64{0, 1}},
+		{RecipeID: 3, Vector: []float64{3, 1}},
+		{RecipeID: 4, Vector: []float64{1, 1}},
+		{RecipeID: 5, Vector: []float64{1, 0, 0}},
+	}
+	results := RankSimilar([]float64{2, 0}, candidates, 1, 2)
+	if len(results) != 2 || results[0].ID != 3 || results[1].ID != 4 {
+		t.Fatalf("expected recipes 3 and 4, got %+v", results)
+	}
+}
@@ -0,0 +1,376 @@
+package recipes
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/coloradocollective/go-capstone-starter/internal/foodentries"
+	"github.com/lib/pq"
+)
+
+// Review statuses for recipe_nutrition_flags.
+const (
+	ReviewOpen     = "open"
+	ReviewApproved = "approved"
+	ReviewEdited   = "edited"
+	ReviewRejected = "rejected"
+)
+
+var (
+	// ErrReviewClosed is returned when acting on a review that is no longer open.
+	ErrReviewClosed = errors.New("review is not open")
+	// ErrNoNutrition is returned when approving a review whose recipe has no
+	// stored values left to approve.
+	ErrNoNutrition = errors.New("recipe has no stored nutrition to approve")
+)
+
+// Review is a flagged nutrition estimate waiting for a human decision.
+type Review struct {
+	ID         int              `json:"id"`
+	RecipeID   int              `json:"recipe_id"`
+	RecipeName string           `json:"recipe_name"`
+	Reason     string           `json:"reason"`
+	Status     string           `json:"status"`
+	Nutrition  *Nutrition       `json:"nutrition"`
+	Estimates  []ReviewEstimate `json:"estimates"`
+	CreatedAt  time.Time        `json:"created_at"`
+	ReviewedBy string           `json:"reviewed_by,omitempty"`
+	ReviewNote string           `json:"review_note,omitempty"`
+}
+
+// ReviewEstimate is one backend's answer from the run that raised the flag.
+type ReviewEstimate struct {
+	Backend       string `json:"backend"`
+	Calories      *int   `json:"calories"`
+	Protein       *int   `json:"protein"`
+	Fat           *int   `json:"fat"`
+	Carbohydrates *int   `json:"carbohydrates"`
+	Error         string `json:"error,omitempty"`
+}
+
+// ReviewDecision is the body accepted by the approve, edit and reject endpoints.
+type ReviewDecision struct {
+	Reviewer      string `json:"reviewer"`
+	Note          string `json:"note"`
+	Calories      *int   `json:"calories"`
+	Protein       *int   `json:"protein"`
+	Fat           *int   `json:"fat"`
+	Carbohydrates *int   `json:"carbohydrates"`
+	// Propagate, on edit, also corrects the food entries logged from the recipe.
+	Propagate bool `json:"propagate"`
+}
+
+// ReviewStore persists the nutrition review queue.
+type ReviewStore interface {
+	Reviews(ctx context.Context, status string) ([]Review, error)
+	ApproveReview(ctx context.Context, reviewID int, decision ReviewDecision) error
+	EditReview(ctx context.Context, reviewID int, decision ReviewDecision) error
+	RejectReview(ctx context.Context, reviewID int, decision ReviewDecision) error
+}
+
+type reviewHandler struct {
+	store ReviewStore
+}
+
+// AdminHandlers registers the nutrition review queue endpoints. Every request must
+// carry the admin token in the X-Admin-Token header; with an empty token the
+// endpoints refuse all requests.
+func AdminHandlers(db *sql.DB, adminToken string) func(mux *http.ServeMux) {
+	return adminRoutes(NewPostgresStore(db), adminToken)
+}
+
+func adminRoutes(store ReviewStore, adminToken string) func(mux *http.ServeMux) {
+	h := &reviewHandler{store: store}
+	admin := func(next http.HandlerFunc) http.Handler {
+		return requireAdmin(adminToken, next)
+	}
+	return func(mux *http.ServeMux) {
+		mux.Handle("GET /api/admin/nutrition-reviews", admin(h.list))
+		mux.Handle("POST /api/admin/nutrition-reviews/{id}/approve", admin(h.decide(store.ApproveReview, nil)))
+		mux.Handle("POST /api/admin/nutrition-reviews/{id}/edit", admin(h.decide(store.EditReview, ReviewDecision.validateValues)))
+		mux.Handle("POST /api/admin/nutrition-reviews/{id}/reject", admin(h.decide(store.RejectReview, nil)))
+	}
+}
+
+func requireAdmin(adminToken string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		given := r.Header.Get("X-Admin-Token")
+		if adminToken == "" || subtle.ConstantTimeCompare([]byte(given), []byte(adminToken)) != 1 {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (h *reviewHandler) list(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		status = ReviewOpen
+	}
+
+	reviews, err := h.store.Reviews(r.Context(), status)
+	if err != nil {
+		log.Printf("Failed to list nutrition reviews: %v", err)
+		http.Error(w, "Failed to list reviews", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, reviews)
+}
+
+// decide runs action on the review, after validate when it is set.
+func (h *reviewHandler) decide(action func(context.Context, int, ReviewDecision) error, validate func(ReviewDecision) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		reviewID, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			http.Error(w, "Invalid review id", http.StatusBadRequest)
+			return
+		}
+
+		var decision ReviewDecision
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&decision); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+		}
+		if decision.Reviewer == "" {
+			decision.Reviewer = "admin"
+		}
+		if validate != nil {
+			if err := validate(decision); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		err = action(r.Context(), reviewID, decision)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			http.Error(w, "Review not found", http.StatusNotFound)
+		case errors.Is(err, ErrReviewClosed):
+			http.Error(w, err.Error(), http.StatusConflict)
+		case errors.Is(err, ErrNoNutrition):
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		case err != nil:
+			log.Printf("Failed to update nutrition review %d: %v", reviewID, err)
+			http.Error(w, "Failed to update review", http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}
+}
+
+var (
+	errMissingValues  = errors.New("calories, protein, fat and carbohydrates are required")
+	errNegativeValues = errors.New("calories, protein, fat and carbohydrates must not be negative")
+)
+
+// validateValues checks the replacement values of an edit.
+func (d ReviewDecision) validateValues() error {
+	if d.Calories == nil || d.Protein == nil || d.Fat == nil || d.Carbohydrates == nil {
+		return errMissingValues
+	}
+	if *d.Calories < 0 || *d.Protein < 0 || *d.Fat < 0 || *d.Carbohydrates < 0 {
+		return errNegativeValues
+	}
+	return nil
+}
+
+func (s *PostgresStore) Reviews(ctx context.Context, status string) ([]Review, error) {
+	rows, err := s.db.QueryContext(ctx, `
+	SELECT f.id, f.recipe_id, r.name, f.reason, f.status, f.created_at,
+		COALESCE(f.reviewed_by, ''), COALESCE(f.review_note, ''), f.run_id,
+		n.calories, n.protein, n.fat, n.carbohydrates, n.source, n.estimator
+	FROM recipe_nutrition_flags f
+	JOIN recipes r ON r.id = f.recipe_id
+	LEFT JOIN recipe_nutrition n ON n.recipe_id = f.recipe_id
+	WHERE f.status = $1
+	ORDER BY f.created_at;
+	`, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reviews := []Review{}
+	var runIDs []sql.NullInt64
+	for rows.Next() {
+		var review Review
+		var runID sql.NullInt64
+		var calories, protein, fat, carbohydrates sql.NullInt64
+		var source, estimator sql.NullString
+		err := rows.Scan(&review.ID, &review.RecipeID, &review.RecipeName, &review.Reason, &review.Status, &review.CreatedAt,
+			&review.ReviewedBy, &review.ReviewNote, &runID,
+			&calories, &protein, &fat, &carbohydrates, &source, &estimator)
+		if err != nil {
+			return nil, err
+		}
+		if source.Valid {
+			review.Nutrition = &Nutrition{
+				Calories:      int(calories.Int64),
+				Protein:       int(protein.Int64),
+				Fat:           int(fat.Int64),
+				Carbohydrates: int(carbohydrates.Int64),
+				Provenance:    Provenance{Source: source.String, Estimator: estimator.String},
+			}
+		}
+		reviews = append(reviews, review)
+		runIDs = append(runIDs, runID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var recipeIDs, flagRunIDs []int64
+	for i, runID := range runIDs {
+		if runID.Valid {
+			recipeIDs = append(recipeIDs, int64(reviews[i].RecipeID))
+			flagRunIDs = append(flagRunIDs, runID.Int64)
+		}
+	}
+	if len(recipeIDs) == 0 {
+		return reviews, nil
+	}
+	estimates, err := s.reviewEstimates(ctx, recipeIDs, flagRunIDs)
+	if err != nil {
+		return nil, err
+	}
+	for i, runID := range runIDs {
+		if runID.Valid {
+			reviews[i].Estimates = estimates[estimateKey{int64(reviews[i].RecipeID), runID.Int64}]
+		}
+	}
+	return reviews, nil
+}
+
+type estimateKey struct{ recipeID, runID int64 }
+
+// reviewEstimates loads, in one query, the estimates of every run that raised
+// one of the flags, keyed by recipe and run.
+func (s *PostgresStore) reviewEstimates(ctx context.Context, recipeIDs, runIDs []int64) (map[estimateKey][]ReviewEstimate, error) {
+	rows, err := s.db.QueryContext(ctx, `
+	SELECT e.recipe_id, e.run_id, e.backend, e.calories, e.protein, e.fat, e.carbohydrates, COALESCE(e.error, '')
+	FROM recipe_nutrition_estimates e
+	JOIN unnest($1::int[], $2::int[]) AS f(recipe_id, run_id)
+		ON f.recipe_id = e.recipe_id AND f.run_id = e.run_id
+	ORDER BY e.id;
+	`, pq.Array(recipeIDs), pq.Array(runIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	estimates := make(map[estimateKey][]ReviewEstimate)
+	for rows.Next() {
+		var key estimateKey
+		var e ReviewEstimate
+		if err := rows.Scan(&key.recipeID, &key.runID, &e.Backend, &e.Calories, &e.Protein, &e.Fat, &e.Carbohydrates, &e.Error); err != nil {
+			return nil, err
+		}
+		estimates[key] = append(estimates[key], e)
+	}
+	return estimates, rows.Err()
+}
+
+// ApproveReview accepts the stored values as they are and locks them. It returns
+// ErrNoNutrition, and leaves the review open, when the values are gone.
+func (s *PostgresStore) ApproveReview(ctx context.Context, reviewID int, decision ReviewDecision) error {
+	return s.closeReview(ctx, reviewID, ReviewApproved, decision, func(tx *sql.Tx, recipeID int) error {
+		result, err := tx.ExecContext(ctx, `UPDATE recipe_nutrition SET locked = TRUE WHERE recipe_id = $1`, recipeID)
+		if err != nil {
+			return err
+		}
+		if n, err := result.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			return ErrNoNutrition
+		}
+		return nil
+	})
+}
+
+// EditReview replaces the stored values with the reviewer's and locks them. With
+// Propagate, entries logged from the recipe are recomputed in the same transaction.
+func (s *PostgresStore) EditReview(ctx context.Context, reviewID int, decision ReviewDecision) error {
+	if err := decision.validateValues(); err != nil {
+		return err
+	}
+	return s.closeReview(ctx, reviewID, ReviewEdited, decision, func(tx *sql.Tx, recipeID int) error {
+		_, err := tx.ExecContext(ctx, `
+		INSERT INTO recipe_nutrition (recipe_id, calories, protein, fat, carbohydrates, source, estimator, prompt_version, input_fingerprint, estimated_at, locked)
+		VALUES ($1, $2, $3, $4, $5, 'manual', $6, NULL, NULL, NOW(), TRUE)
+		ON CONFLICT (recipe_id) DO UPDATE SET
+			calories = EXCLUDED.calories,
+			protein = EXCLUDED.protein,
+			fat = EXCLUDED.fat,
+			carbohydrates = EXCLUDED.carbohydrates,
+			source = EXCLUDED.source,
+			estimator = EXCLUDED.estimator,
+			prompt_version = NULL,
+			input_fingerprint = NULL,
+			estimated_at = EXCLUDED.estimated_at,
+			locked = TRUE;
+		`, recipeID, *decision.Calories, *decision.Protein, *decision.Fat, *decision.Carbohydrates, decision.Reviewer)
+		if err != nil || !decision.Propagate {
+			return err
+		}
+		updated, err := foodentries.ApplyRecipeNutrition(ctx, tx, foodentries.EventsTopic(), recipeID, foodentries.RecipeTotals{
+			Calories:      *decision.Calories,
+			Protein:       *decision.Protein,
+			Fat:           *decision.Fat,
+			Carbohydrates: *decision.Carbohydrates,
+		})
+		if err != nil {
+			return err
+		}
+		log.Printf("Corrected %d food entries logged from recipe %d", updated, recipeID)
+		return nil
+	})
+}
+
+// RejectReview discards the stored values so the next analyzer run estimates the recipe again.
+func (s *PostgresStore) RejectReview(ctx context.Context, reviewID int, decision ReviewDecision) error {
+	return s.closeReview(ctx, reviewID, ReviewRejected, decision, func(tx *sql.Tx, recipeID int) error {
+		_, err := tx.ExecContext(ctx, `DELETE FROM recipe_nutrition WHERE recipe_id = $1 AND NOT locked`, recipeID)
+		return err
+	})
+}
+
+func (s *PostgresStore) closeReview(ctx context.Context, reviewID int, status string, decision ReviewDecision, apply func(tx *sql.Tx, recipeID int) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var recipeID int
+	var current string
+	err = tx.QueryRowContext(ctx, `SELECT recipe_id, status FROM recipe_nutrition_flags WHERE id = $1 FOR UPDATE`, reviewID).Scan(&recipeID, &current)
+	if err != nil {
+		return err
+	}
+	if current != ReviewOpen {
+		return ErrReviewClosed
+	}
+
+	if err := apply(tx, recipeID); err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `
+	UPDATE recipe_nutrition_flags
+	SET status = $2, reviewed_by = $3, review_note = NULLIF($4, ''), reviewed_at = NOW()
+	WHERE id = $1;
+	`, reviewID, status, decision.Reviewer, decision.Note)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
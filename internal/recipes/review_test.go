@@ -0,0 +1,158 @@
+package recipes
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type fakeReviewStore struct {
+	reviews   []Review
+	decisions map[int]string
+	// withoutNutrition lists recipes whose stored values are gone.
+	withoutNutrition map[int]bool
+}
+
+func (s *fakeReviewStore) Reviews(_ context.Context, status string) ([]Review, error) {
+	var matching []Review
+	for _, r := range s.reviews {
+		if r.Status == status {
+			matching = append(matching, r)
+		}
+	}
+	return matching, nil
+}
+
+func (s *fakeReviewStore) decide(reviewID int, status string, decision ReviewDecision) error {
+	for i, r := range s.reviews {
+		if r.ID != reviewID {
+			continue
+		}
+		if r.Status != ReviewOpen {
+			return ErrReviewClosed
+		}
+		s.reviews[i].Status = status
+		s.reviews[i].ReviewedBy = decision.Reviewer
+		return nil
+	}
+	return sql.ErrNoRows
+}
+
+func (s *fakeReviewStore) ApproveReview(_ context.Context, id int, d ReviewDecision) error {
+	for _, r := range s.reviews {
+		if r.ID == id && s.withoutNutrition[r.RecipeID] {
+			return ErrNoNutrition
+		}
+	}
+	return s.decide(id, ReviewApproved, d)
+}
+
+func (s *fakeReviewStore) EditReview(_ context.Context, id int, d ReviewDecision) error {
+	return s.decide(id, ReviewEdited, d)
+}
+
+func (s *fakeReviewStore) RejectReview(_ context.Context, id int, d ReviewDecision) error {
+	return s.decide(id, ReviewRejected, d)
+}
+
+func newAdminServer() (*http.ServeMux, *fakeReviewStore) {
+	store := &fakeReviewStore{reviews: []Review{
+		{ID: 1, RecipeID: 10, Status: ReviewOpen, Reason: "backends disagree on calories"},
+		{ID: 2, RecipeID: 11, Status: ReviewOpen, Reason: "implausible calories (12000 kcal)"},
+	}}
+	mux := http.NewServeMux()
+	adminRoutes(store, "secret")(mux)
+	return mux, store
+}
+
+func adminRequest(method, target, body string) *http.Request {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("X-Admin-Token", "secret")
+	return req
+}
+
+func TestAdminRoutes_RequireToken(t *testing.T) {
+	mux, _ := newAdminServer()
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/admin/nutrition-reviews", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 without token, got %d", rec.Code)
+	}
+
+	disabled := http.NewServeMux()
+	adminRoutes(&fakeReviewStore{}, "")(disabled)
+	rec = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/admin/nutrition-reviews", nil)
+	req.Header.Set("X-Admin-Token", "")
+	disabled.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 when no admin token is configured, got %d", rec.Code)
+	}
+}
+
+func TestAdminRoutes_ApproveAndConflict(t *testing.T) {
+	mux, store := newAdminServer()
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, adminRequest(http.MethodPost, "/api/admin/nutrition-reviews/1/approve", `{"reviewer":"dana"}`))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if store.reviews[0].Status != ReviewApproved || store.reviews[0].ReviewedBy != "dana" {
+		t.Fatalf("expected review approved by dana, got %+v", store.reviews[0])
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, adminRequest(http.MethodPost, "/api/admin/nutrition-reviews/1/reject", ""))
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409 for a closed review, got %d", rec.Code)
+	}
+}
+
+func TestAdminRoutes_EditRequiresValues(t *testing.T) {
+	mux, store := newAdminServer()
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, adminRequest(http.MethodPost, "/api/admin/nutrition-reviews/2/edit", `{"calories": 900}`))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for incomplete values, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, adminRequest(http.MethodPost, "/api/admin/nutrition-reviews/2/edit",
+		`{"calories": 900, "protein": -40, "fat": 30, "carbohydrates": 110}`))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for negative values, got %d", rec.Code)
+	}
+	if store.reviews[1].Status != ReviewOpen {
+		t.Fatalf("expected invalid edits to leave the review open, got %+v", store.reviews[1])
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, adminRequest(http.MethodPost, "/api/admin/nutrition-reviews/2/edit",
+		`{"calories": 900, "protein": 40, "fat": 30, "carbohydrates": 110}`))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if store.reviews[1].Status != ReviewEdited || store.reviews[1].ReviewedBy != "admin" {
+		t.Fatalf("expected review edited by default reviewer, got %+v", store.reviews[1])
+	}
+}
+
+func TestAdminRoutes_ApproveWithoutNutrition(t *testing.T) {
+	mux, store := newAdminServer()
+	store.withoutNutrition = map[int]bool{11: true}
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, adminRequest(http.MethodPost, "/api/admin/nutrition-reviews/2/approve", ""))
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422 when there is nothing to approve, got %d", rec.Code)
+	}
+	if store.reviews[1].Status != ReviewOpen {
+		t.Fatalf("expected the review to stay open, got %+v", store.reviews[1])
+	}
+}
@@ -0,0 +1,157 @@
+package recipes
+
+import (
+	"container/heap"
+	"context"
+	"math"
+	"slices"
+	"sort"
+	"sync"
+)
+
+// SimilarRecipe is a search hit ranked by cosine similarity.
+type SimilarRecipe struct {
+	ID         int      `json:"id"`
+	Name       string   `json:"name"`
+	ImageURL   string   `json:"image_url"`
+	DietTags   []string `json:"diet_tags"`
+	Similarity float64  `json:"similarity"`
+}
+
+// RankSimilar returns the limit candidates closest to target, skipping excludeID.
+// It keeps only the best limit hits while scanning, so memory does not grow with
+// the catalogue.
+func RankSimilar(target []float64, candidates []StoredEmbedding, excludeID, limit int) []SimilarRecipe {
+	target = unit(target)
+	best := &topHits{}
+	for _, c := range candidates {
+		if c.RecipeID == excludeID || len(c.Vector) != len(target) {
+			continue
+		}
+		hit := SimilarRecipe{ID: c.RecipeID, Name: c.Name, ImageURL: c.ImageURL, DietTags: c.DietTags, Similarity: dot(target, c.Vector)}
+		if best.Len() < limit {
+			heap.Push(best, hit)
+		} else if limit > 0 && hit.Similarity > (*best)[0].Similarity {
+			(*best)[0] = hit
+			heap.Fix(best, 0)
+		}
+	}
+	results := []SimilarRecipe(*best)
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Similarity != results[j].Similarity {
+			return results[i].Similarity > results[j].Similarity
+		}
+		return results[i].ID < results[j].ID
+	})
+	return results
+}
+
+// WithDietTags returns the candidates tagged with every one of diet, in a new
+// slice. An empty diet returns candidates itself.
+func WithDietTags(candidates []StoredEmbedding, diet []string) []StoredEmbedding {
+	if len(diet) == 0 {
+		return candidates
+	}
+	var matching []StoredEmbedding
+	for _, c := range candidates {
+		if hasAll(c.DietTags, diet) {
+			matching = append(matching, c)
+		}
+	}
+	return matching
+}
+
+func hasAll(tags, want []string) bool {
+	for _, w := range want {
+		if !slices.Contains(tags, w) {
+			return false
+		}
+	}
+	return true
+}
+
+// topHits is a min-heap on similarity, so the weakest kept hit is at the root.
+type topHits []SimilarRecipe
+
+func (h topHits) Len() int           { return len(h) }
+func (h topHits) Less(i, j int) bool { return h[i].Similarity < h[j].Similarity }
+func (h topHits) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *topHits) Push(x any)        { *h = append(*h, x.(SimilarRecipe)) }
+func (h *topHits) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// unit returns a copy of v scaled to length 1, so that cosine similarity is a
+// dot product. A zero vector stays zero.
+func unit(v []float64) []float64 {
+	var norm float64
+	for _, x := range v {
+		norm += x * x
+	}
+	out := make([]float64, len(v))
+	if norm == 0 {
+		return out
+	}
+	norm = math.Sqrt(norm)
+	for i, x := range v {
+		out[i] = x / norm
+	}
+	return out
+}
+
+func dot(a, b []float64) float64 {
+	var sum float64
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
+
+// similarIndex keeps each model's embeddings in memory, normalized, and reloads
+// them only when the store reports that they changed. Similar-recipe requests
+// then cost one small version query instead of reading every vector.
+type similarIndex struct {
+	store Store
+
+	mu      sync.Mutex
+	byModel map[string]indexedModel
+}
+
+type indexedModel struct {
+	version    string
+	candidates []StoredEmbedding
+}
+
+func newSimilarIndex(store Store) *similarIndex {
+	return &similarIndex{store: store, byModel: make(map[string]indexedModel)}
+}
+
+// candidates returns the current embeddings of model, normalized to unit length.
+// The returned slice is shared and must not be modified.
+func (x *similarIndex) candidates(ctx context.Context, model string) ([]StoredEmbedding, error) {
+	version, err := x.store.EmbeddingsVersion(ctx, model)
+	if err != nil {
+		return nil, err
+	}
+	x.mu.Lock()
+	cached, ok := x.byModel[model]
+	x.mu.Unlock()
+	if ok && cached.version == version {
+		return cached.candidates, nil
+	}
+
+	loaded, err := x.store.Embeddings(ctx, model)
+	if err != nil {
+		return nil, err
+	}
+	for i := range loaded {
+		loaded[i].Vector = unit(loaded[i].Vector)
+	}
+	x.mu.Lock()
+	x.byModel[model] = indexedModel{version: version, candidates: loaded}
+	x.mu.Unlock()
+	return loaded, nil
+}
@@ -0,0 +1,178 @@
+package recipes
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// StoredEmbedding is a recipe's embedding as persisted by the analyzer.
+type StoredEmbedding struct {
+	RecipeID int
+	Name     string
+	ImageURL string
+	DietTags []string
+	Model    string
+	Vector   []float64
+}
+
+// Recipe is a recipe with its estimated nutrition, if any.
+type Recipe struct {
+	ID        int        `json:"id"`
+	Slug      string     `json:"slug"`
+	Name      string     `json:"name"`
+	ImageURL  string     `json:"image_url"`
+	DietTags  []string   `json:"diet_tags"`
+	Nutrition *Nutrition `json:"nutrition"`
+}
+
+// dietTags selects the diet tags of recipe r in name order. Tags the model check
+// rejected are left out, so a restriction is only trusted when nothing disputes it.
+const dietTags = `COALESCE((
+		SELECT array_agg(t.tag ORDER BY t.tag) FROM recipe_diet_tags t
+		WHERE t.recipe_id = r.id AND t.model_verified IS NOT FALSE
+	), '{}')`
+
+// Nutrition is a recipe's stored nutrition together with where it came from.
+type Nutrition struct {
+	Calories      int        `json:"calories"`
+	Protein       int        `json:"protein"`
+	Fat           int        `json:"fat"`
+	Carbohydrates int        `json:"carbohydrates"`
+	Provenance    Provenance `json:"provenance"`
+}
+
+// Provenance records the source, estimator and input behind a nutrition value.
+type Provenance struct {
+	Source           string     `json:"source"`
+	Estimator        string     `json:"estimator,omitempty"`
+	PromptVersion    string     `json:"prompt_version,omitempty"`
+	InputFingerprint string     `json:"input_fingerprint,omitempty"`
+	EstimatedAt      *time.Time `json:"estimated_at,omitempty"`
+}
+
+// Store loads recipes and their embeddings.
+type Store interface {
+	Recipe(ctx context.Context, recipeID int) (Recipe, error)
+	RecipeEmbedding(ctx context.Context, recipeID int) (StoredEmbedding, error)
+	Embeddings(ctx context.Context, model string) ([]StoredEmbedding, error)
+	// EmbeddingsVersion changes whenever an embedding of model is added, replaced
+	// or removed.
+	EmbeddingsVersion(ctx context.Context, model string) (string, error)
+}
+
+// PostgresStore reads recipes and their embeddings from Postgres.
+type PostgresStore struct {
+	db *sql.DB
+}
+
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+func (s *PostgresStore) Recipe(ctx context.Context, recipeID int) (Recipe, error) {
+	var r Recipe
+	var imageURL, estimator, promptVersion, fingerprint, source sql.NullString
+	var calories, protein, fat, carbohydrates sql.NullInt64
+	var estimatedAt sql.NullTime
+	var tags pq.StringArray
+	err := s.db.QueryRowContext(ctx, `
+	SELECT r.id, r.slug, r.name, r.image_url, `+dietTags+`,
+		n.calories, n.protein, n.fat, n.carbohydrates,
+		n.source, n.estimator, n.prompt_version, n.input_fingerprint, n.estimated_at
+	FROM recipes r
+	LEFT JOIN recipe_nutrition n ON n.recipe_id = r.id
+	WHERE r.id = $1;
+	`, recipeID).Scan(&r.ID, &r.Slug, &r.Name, &imageURL, &tags,
+		&calories, &protein, &fat, &carbohydrates,
+		&source, &estimator, &promptVersion, &fingerprint, &estimatedAt)
+	if err != nil {
+		return Recipe{}, err
+	}
+
+	r.ImageURL = imageURL.String
+	r.DietTags = tags
+	if source.Valid {
+		r.Nutrition = &Nutrition{
+			Calories:      int(calories.Int64),
+			Protein:       int(protein.Int64),
+			Fat:           int(fat.Int64),
+			Carbohydrates: int(carbohydrates.Int64),
+			Provenance: Provenance{
+				Source:           source.String,
+				Estimator:        estimator.String,
+				PromptVersion:    promptVersion.String,
+				InputFingerprint: fingerprint.String,
+			},
+		}
+		if estimatedAt.Valid {
+			r.Nutrition.Provenance.EstimatedAt = &estimatedAt.Time
+		}
+	}
+	return r, nil
+}
+
+func (s *PostgresStore) RecipeEmbedding(ctx context.Context, recipeID int) (StoredEmbedding, error) {
+	var e StoredEmbedding
+	var imageURL sql.NullString
+	var vector pq.Float64Array
+	err := s.db.QueryRowContext(ctx, `
+	SELECT r.id, r.name, r.image_url, e.model, e.embedding
+	FROM recipe_embeddings e
+	JOIN recipes r ON r.id = e.recipe_id
+	WHERE e.recipe_id = $1;
+	`, recipeID).Scan(&e.RecipeID, &e.Name, &imageURL, &e.Model, &vector)
+	e.ImageURL = imageURL.String
+	e.Vector = vector
+	return e, err
+}
+
+func (s *PostgresStore) EmbeddingsVersion(ctx context.Context, model string) (string, error) {
+	// The embeddings carry their recipes' diet tags, so reclassifying changes the
+	// version too.
+	var count, tagCount int
+	var latest, tagged sql.NullTime
+	err := s.db.QueryRowContext(ctx, `
+	SELECT
+		(SELECT COUNT(*) FROM recipe_embeddings WHERE model = $1),
+		(SELECT MAX(updated_at) FROM recipe_embeddings WHERE model = $1),
+		(SELECT COUNT(*) FROM recipe_diet_tags),
+		(SELECT MAX(classified_at) FROM recipe_diet_tags);
+	`, model).Scan(&count, &latest, &tagCount, &tagged)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d:%d:%d:%d", count, latest.Time.UnixNano(), tagCount, tagged.Time.UnixNano()), nil
+}
+
+func (s *PostgresStore) Embeddings(ctx context.Context, model string) ([]StoredEmbedding, error) {
+	rows, err := s.db.QueryContext(ctx, `
+	SELECT r.id, r.name, r.image_url, `+dietTags+`, e.model, e.embedding
+	FROM recipe_embeddings e
+	JOIN recipes r ON r.id = e.recipe_id
+	WHERE e.model = $1;
+	`, model)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var embeddings []StoredEmbedding
+	for rows.Next() {
+		var e StoredEmbedding
+		var imageURL sql.NullString
+		var tags pq.StringArray
+		var vector pq.Float64Array
+		if err := rows.Scan(&e.RecipeID, &e.Name, &imageURL, &tags, &e.Model, &vector); err != nil {
+			return nil, err
+		}
+		e.ImageURL = imageURL.String
+		e.DietTags = tags
+		e.Vector = vector
+		embeddings = append(embeddings, e)
+	}
+	return embeddings, rows.Err()
+}